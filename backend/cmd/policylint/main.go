@@ -0,0 +1,97 @@
+// policylint statically analyzes a policy document, as the admin API does
+// before a version can be activated:
+//
+//	policylint [-db] [-fail-on error|warning|info] [-json] policies.yaml
+//
+// With -db the roles, resources and attribute keys are read from the
+// database configured by the DB_* variables, which enables the unknown
+// attribute/value and unmentioned role/resource checks.
+//
+// It exits 1 if any finding is at least as severe as -fail-on (default
+// error) and 2 if the document cannot be read or compiled.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"zero-trust-access-platform/backend/internal/config"
+	"zero-trust-access-platform/backend/internal/db"
+	"zero-trust-access-platform/backend/internal/policy"
+)
+
+func main() {
+	useDB := flag.Bool("db", false, "load the inventory from the database (DB_* variables)")
+	failOn := flag.String("fail-on", string(policy.SeverityError), "lowest severity that fails: error, warning or info")
+	asJSON := flag.Bool("json", false, "print findings as JSON")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: policylint [-db] [-fail-on error|warning|info] [-json] policies.yaml")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	threshold := policy.Severity(*failOn).Rank()
+	if flag.NArg() != 1 || threshold == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	src, err := os.ReadFile(flag.Arg(0))
+	if err != nil {
+		fatal(err)
+	}
+	doc, err := policy.ParseDocument(src)
+	if err != nil {
+		fatal(err)
+	}
+
+	var inv *policy.Inventory
+	if *useDB {
+		database, err := db.Open(config.Load())
+		if err != nil {
+			fatal(err)
+		}
+		inv, err = policy.NewRepository(database).Inventory(context.Background())
+		database.Close()
+		if err != nil {
+			fatal(fmt.Errorf("load inventory: %w", err))
+		}
+	}
+
+	findings, err := policy.Analyze(doc, inv)
+	if err != nil {
+		fatal(err)
+	}
+
+	failed := false
+	for _, f := range findings {
+		if f.Severity.Rank() >= threshold {
+			failed = true
+		}
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(findings)
+	} else {
+		for _, f := range findings {
+			fmt.Printf("%-7s  %-20s  %s\n", f.Severity, f.Kind, f.Message)
+		}
+		if len(findings) == 0 {
+			fmt.Println("no findings")
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "policylint:", err)
+	os.Exit(2)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// JUnit XML as understood by common CI systems: one suite per test file.
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Errors   int          `xml:"errors,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Errors   int         `xml:"errors,attr"`
+	Time     string      `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Body    string `xml:",chardata"`
+}
+
+func writeJUnit(path string, results []result) error {
+	var out junitSuites
+	index := map[string]int{}
+	var elapsed []time.Duration // per suite
+	for _, r := range results {
+		i, ok := index[r.File]
+		if !ok {
+			i = len(out.Suites)
+			index[r.File] = i
+			out.Suites = append(out.Suites, junitSuite{Name: r.File})
+			elapsed = append(elapsed, 0)
+		}
+		elapsed[i] += r.Duration
+		s := &out.Suites[i]
+
+		c := junitCase{
+			Name:      r.Name,
+			Classname: r.File,
+			Time:      fmt.Sprintf("%.6f", r.Duration.Seconds()),
+		}
+		switch {
+		case r.Err != nil:
+			c.Error = &junitMessage{Message: r.Err.Error(), Body: r.Err.Error()}
+			s.Errors++
+			out.Errors++
+		case len(r.Diffs) > 0:
+			c.Failure = &junitMessage{Message: r.Diffs[0], Body: strings.Join(r.Diffs, "\n")}
+			s.Failures++
+			out.Failures++
+		}
+		s.Tests++
+		out.Tests++
+		s.Cases = append(s.Cases, c)
+	}
+	for i := range out.Suites {
+		out.Suites[i].Time = fmt.Sprintf("%.6f", elapsed[i].Seconds())
+	}
+
+	b, err := xml.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append([]byte(xml.Header), append(b, '\n')...), 0o644)
+}
@@ -0,0 +1,342 @@
+// policytest runs policy test cases through policy.Evaluate, so a policy
+// set can be tested like code:
+//
+//	policytest [-policy policies.yaml] [-junit report.xml] [-v] tests.yaml...
+//
+// A test file names the policy set it tests (relative to the file; the
+// built-in defaults if omitted, and -policy overrides it), optional
+// network zones, an optional role hierarchy (the built-in user, devops
+// and admin roles if omitted) and the cases:
+//
+//	policy: ../policies.yaml
+//	zones:
+//	  corp-vpn: [10.8.0.0/16]
+//	roles:
+//	  - {name: contractor, inherits: [user]}
+//	  - {name: user}
+//	cases:
+//	  - name: users cannot write
+//	    context: {user_role: user, action: write, resource_name: wiki, sensitivity: low}
+//	    expect:
+//	      decision: deny
+//	      policy: user-read-only
+//	      obligations: []
+//
+// The context uses the AccessContext JSON field names; a missing time is
+// the time of the run. Under expect, decision (allow/deny), policy, reason
+// (a substring) and obligations (type plus optional duration, to and
+// advice) are checked when given.
+//
+// It exits 1 if any case fails and 2 if the files cannot be loaded.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"zero-trust-access-platform/backend/internal/policy"
+)
+
+type testFile struct {
+	Policy string              `yaml:"policy"`
+	Zones  map[string][]string `yaml:"zones"`
+	Roles  []testRole          `yaml:"roles"`
+	Cases  []testCase          `yaml:"cases"`
+}
+
+type testRole struct {
+	Name        string   `yaml:"name"`
+	Inherits    []string `yaml:"inherits"`
+	Permissions []string `yaml:"permissions"`
+}
+
+type testCase struct {
+	Name    string         `yaml:"name"`
+	Context map[string]any `yaml:"context"`
+	Expect  expectation    `yaml:"expect"`
+}
+
+type expectation struct {
+	Decision    string                `yaml:"decision"` // allow or deny
+	Policy      string                `yaml:"policy"`
+	Reason      string                `yaml:"reason"`
+	Obligations *[]expectedObligation `yaml:"obligations"` // nil: not checked
+}
+
+type expectedObligation struct {
+	Type     policy.ObligationType `yaml:"type"`
+	Duration string                `yaml:"duration"`
+	To       string                `yaml:"to"`
+	Advice   bool                  `yaml:"advice"`
+}
+
+// result is the outcome of one case; Diffs is empty when it passed.
+type result struct {
+	File     string
+	Name     string
+	Diffs    []string
+	Err      error
+	Duration time.Duration
+}
+
+func (r result) passed() bool {
+	return r.Err == nil && len(r.Diffs) == 0
+}
+
+func main() {
+	policyPath := flag.String("policy", "", "policy set to test, overriding the files' policy")
+	junitPath := flag.String("junit", "", "write a JUnit XML report to this file")
+	verbose := flag.Bool("v", false, "list passing cases too")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: policytest [-policy policies.yaml] [-junit report.xml] [-v] tests.yaml...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var results []result
+	for _, path := range flag.Args() {
+		rs, err := runFile(path, *policyPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "policytest: %s: %v\n", path, err)
+			os.Exit(2)
+		}
+		results = append(results, rs...)
+	}
+
+	failed := report(results, *verbose)
+
+	if *junitPath != "" {
+		if err := writeJUnit(*junitPath, results); err != nil {
+			fmt.Fprintln(os.Stderr, "policytest:", err)
+			os.Exit(2)
+		}
+	}
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// runFile installs the file's policy set, zones and roles and runs its
+// cases.
+func runFile(path, policyOverride string) ([]result, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var tf testFile
+	if err := yaml.Unmarshal(raw, &tf); err != nil {
+		return nil, err
+	}
+	if len(tf.Cases) == 0 {
+		return nil, errors.New("no cases")
+	}
+
+	src := policy.DefaultSource
+	switch {
+	case policyOverride != "":
+		src, err = os.ReadFile(policyOverride)
+	case tf.Policy != "":
+		src, err = os.ReadFile(filepath.Join(filepath.Dir(path), tf.Policy))
+	}
+	if err != nil {
+		return nil, err
+	}
+	set, err := policy.CompileSource(src)
+	if err != nil {
+		return nil, err
+	}
+	policy.SetActive(set)
+
+	zones := map[string][]netip.Prefix{}
+	for name, cidrs := range tf.Zones {
+		for _, c := range cidrs {
+			p, err := netip.ParsePrefix(c)
+			if err != nil {
+				return nil, fmt.Errorf("zone %q: %w", name, err)
+			}
+			zones[name] = append(zones[name], p)
+		}
+	}
+	policy.SetZones(zones)
+
+	hierarchy := policy.BuiltinRoles
+	if len(tf.Roles) > 0 {
+		hierarchy = make([]policy.Role, 0, len(tf.Roles))
+		for _, r := range tf.Roles {
+			if r.Name == "" {
+				return nil, errors.New("role without a name")
+			}
+			hierarchy = append(hierarchy, policy.Role{Name: r.Name, Inherits: r.Inherits, Permissions: r.Permissions})
+		}
+	}
+	policy.SetRoles(hierarchy)
+
+	now := time.Now()
+	out := make([]result, 0, len(tf.Cases))
+	for i, tc := range tf.Cases {
+		name := tc.Name
+		if name == "" {
+			name = fmt.Sprintf("cases[%d]", i)
+		}
+		res := result{File: path, Name: name}
+
+		start := time.Now()
+		ac, err := accessContext(tc.Context, now)
+		if err != nil {
+			res.Err = err
+		} else {
+			res.Diffs = compare(tc.Expect, policy.Evaluate(ac))
+		}
+		res.Duration = time.Since(start)
+		out = append(out, res)
+	}
+	return out, nil
+}
+
+// accessContext decodes a case's context through JSON, so the same field
+// names as in access_logs.context and the simulate API apply.
+func accessContext(m map[string]any, now time.Time) (policy.AccessContext, error) {
+	var ac policy.AccessContext
+	b, err := json.Marshal(m)
+	if err != nil {
+		return ac, fmt.Errorf("context: %w", err)
+	}
+	dec := json.NewDecoder(strings.NewReader(string(b)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&ac); err != nil {
+		return ac, fmt.Errorf("context: %w", err)
+	}
+	if ac.Time.IsZero() {
+		ac.Time = now
+	}
+	return ac, nil
+}
+
+// compare lists the differences between what a case expects and d.
+func compare(want expectation, d policy.Decision) []string {
+	var diffs []string
+	got := "deny"
+	if d.Allowed {
+		got = "allow"
+	}
+	if want.Decision != "" && want.Decision != got {
+		diffs = append(diffs, fmt.Sprintf("decision: want %s, got %s", want.Decision, got))
+	}
+	if want.Policy != "" && want.Policy != d.Policy {
+		diffs = append(diffs, fmt.Sprintf("policy: want %q, got %q", want.Policy, d.Policy))
+	}
+	if want.Reason != "" && !strings.Contains(d.Reason, want.Reason) {
+		diffs = append(diffs, fmt.Sprintf("reason: want it to contain %q, got %q", want.Reason, d.Reason))
+	}
+	if want.Obligations != nil {
+		diffs = append(diffs, compareObligations(*want.Obligations, d.Obligations)...)
+	}
+	if len(diffs) > 0 && (want.Decision == "" || want.Policy == "") {
+		// Give the full picture when the expectation was only partial.
+		diffs = append(diffs, fmt.Sprintf("decided by %q: %s", d.Policy, d.Reason))
+	}
+	return diffs
+}
+
+func compareObligations(want []expectedObligation, got []policy.Obligation) []string {
+	var diffs []string
+	used := make([]bool, len(got))
+	for _, w := range want {
+		found := false
+		for i, g := range got {
+			if used[i] || !obligationMatches(w, g) {
+				continue
+			}
+			used[i], found = true, true
+			break
+		}
+		if !found {
+			diffs = append(diffs, "obligations: missing "+describeExpected(w))
+		}
+	}
+	for i, g := range got {
+		if !used[i] {
+			diffs = append(diffs, "obligations: unexpected "+describeObligation(g))
+		}
+	}
+	return diffs
+}
+
+func obligationMatches(w expectedObligation, g policy.Obligation) bool {
+	if w.Type != g.Type || w.Advice != g.Advice {
+		return false
+	}
+	if w.To != "" && w.To != g.To {
+		return false
+	}
+	if w.Duration != "" {
+		d, err := time.ParseDuration(w.Duration)
+		if err != nil || d != g.Duration {
+			return false
+		}
+	}
+	return true
+}
+
+func describeExpected(w expectedObligation) string {
+	s := string(w.Type)
+	if w.Duration != "" {
+		s += " " + w.Duration
+	}
+	if w.To != "" {
+		s += " to " + w.To
+	}
+	if w.Advice {
+		s += " (advice)"
+	}
+	return s
+}
+
+func describeObligation(o policy.Obligation) string {
+	s := string(o.Type)
+	if o.Duration > 0 {
+		s += " " + o.Duration.String()
+	}
+	if o.To != "" {
+		s += " to " + o.To
+	}
+	if o.Advice {
+		s += " (advice)"
+	}
+	return s
+}
+
+// report prints the results and returns the number of failed cases.
+func report(results []result, verbose bool) int {
+	failed := 0
+	for _, r := range results {
+		switch {
+		case r.Err != nil:
+			failed++
+			fmt.Printf("FAIL  %s: %s\n      %v\n", r.File, r.Name, r.Err)
+		case len(r.Diffs) > 0:
+			failed++
+			fmt.Printf("FAIL  %s: %s\n", r.File, r.Name)
+			for _, d := range r.Diffs {
+				fmt.Printf("      %s\n", d)
+			}
+		case verbose:
+			fmt.Printf("ok    %s: %s\n", r.File, r.Name)
+		}
+	}
+	fmt.Printf("\n%d passed, %d failed\n", len(results)-failed, failed)
+	return failed
+}
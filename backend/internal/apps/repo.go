@@ -0,0 +1,223 @@
+package apps
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// App maps a host and path prefix of an internal web app to the registered
+// resource that policies decide on, e.g. grafana.internal.example.com/ ->
+// "grafana".
+type App struct {
+	ID           int64     `json:"id"`
+	Host         string    `json:"host"`
+	PathPrefix   string    `json:"path_prefix"`
+	ResourceID   int64     `json:"resource_id"`
+	ResourceName string    `json:"resource_name"`
+	Upstream     string    `json:"upstream,omitempty"` // proxied to by PROXY_ADDR
+	UpdatedBy    *int64    `json:"updated_by"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Validate normalises Host and PathPrefix and checks them.
+func (a *App) Validate() error {
+	a.Host = NormalizeHost(a.Host)
+	if a.Host == "" {
+		return errors.New("host is required")
+	}
+	if a.PathPrefix == "" {
+		a.PathPrefix = "/"
+	}
+	if !strings.HasPrefix(a.PathPrefix, "/") {
+		return errors.New("path_prefix must start with /")
+	}
+	// Stored as CleanPath leaves request paths, or it could never match.
+	a.PathPrefix = cleanSlash(a.PathPrefix)
+	if a.ResourceID == 0 {
+		return errors.New("resource_id is required")
+	}
+	if a.Upstream != "" {
+		u, err := url.Parse(a.Upstream)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New("upstream must be an http(s) URL")
+		}
+	}
+	return nil
+}
+
+// NormalizeHost lower-cases a Host header value and drops any port.
+func NormalizeHost(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(host, ".")
+}
+
+// Repository provides DB access for protected_apps.
+type Repository struct {
+	DB *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{DB: db}
+}
+
+// List returns every app with its resource name, ordered by host and path.
+func (r *Repository) List(ctx context.Context) ([]App, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+SELECT a.id, a.host, a.path_prefix, a.resource_id, res.name, COALESCE(a.upstream, ''),
+       a.updated_by, a.updated_at
+FROM protected_apps a
+JOIN resources res ON res.id = a.resource_id
+ORDER BY a.host, a.path_prefix;
+`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []App{}
+	for rows.Next() {
+		var a App
+		if err := rows.Scan(
+			&a.ID,
+			&a.Host,
+			&a.PathPrefix,
+			&a.ResourceID,
+			&a.ResourceName,
+			&a.Upstream,
+			&a.UpdatedBy,
+			&a.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Create stores a new app; a.ID and a.UpdatedAt are filled in.
+func (r *Repository) Create(ctx context.Context, a *App, userID int64) error {
+	return r.DB.QueryRowContext(ctx, `
+INSERT INTO protected_apps (host, path_prefix, resource_id, upstream, updated_by)
+VALUES ($1, $2, $3, NULLIF($4, ''), $5)
+RETURNING id, updated_at;
+`, a.Host, a.PathPrefix, a.ResourceID, a.Upstream, userID).Scan(&a.ID, &a.UpdatedAt)
+}
+
+// Update replaces an app. It returns sql.ErrNoRows if there is none.
+func (r *Repository) Update(ctx context.Context, a *App, userID int64) error {
+	return r.DB.QueryRowContext(ctx, `
+UPDATE protected_apps
+SET host = $2, path_prefix = $3, resource_id = $4, upstream = NULLIF($5, ''),
+    updated_by = $6, updated_at = NOW()
+WHERE id = $1
+RETURNING updated_at;
+`, a.ID, a.Host, a.PathPrefix, a.ResourceID, a.Upstream, userID).Scan(&a.UpdatedAt)
+}
+
+// Delete removes an app. It returns sql.ErrNoRows if there is none.
+func (r *Repository) Delete(ctx context.Context, id int64) error {
+	res, err := r.DB.ExecContext(ctx, `DELETE FROM protected_apps WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Table looks up the app serving a host and path.
+type Table struct {
+	byHost map[string][]App // longest prefix first
+}
+
+func NewTable(list []App) *Table {
+	t := &Table{byHost: map[string][]App{}}
+	for _, a := range list {
+		host := NormalizeHost(a.Host)
+		t.byHost[host] = append(t.byHost[host], a)
+	}
+	for _, l := range t.byHost {
+		sort.SliceStable(l, func(i, j int) bool {
+			return len(l[i].PathPrefix) > len(l[j].PathPrefix)
+		})
+	}
+	return t
+}
+
+// ErrInvalidPath is returned by CleanPath for paths that still have a ".."
+// segment after cleaning, e.g. double-encoded dot segments.
+var ErrInvalidPath = errors.New("invalid request path")
+
+// CleanPath normalizes a raw request path (as in X-Original-URI or Envoy's
+// :path) the way the upstream will resolve it: the query is dropped, the
+// path percent-decoded and cleaned of dot segments, keeping a trailing
+// slash. Without this, /public/../admin or /public/%2e%2e/admin would be
+// matched against the /public app while the upstream serves /admin. The
+// result is for matching and policy decisions; what is forwarded keeps
+// its original encoding.
+func CleanPath(raw string) (string, error) {
+	if i := strings.IndexAny(raw, "?#"); i >= 0 {
+		raw = raw[:i]
+	}
+	p, err := url.PathUnescape(raw)
+	if err != nil {
+		return "", ErrInvalidPath
+	}
+	p = cleanSlash(p)
+	// Some upstreams decode twice.
+	if again, err := url.PathUnescape(p); err == nil && hasDotDot(again) {
+		return "", ErrInvalidPath
+	}
+	return p, nil
+}
+
+// cleanSlash is path.Clean for a rooted path that keeps a trailing slash.
+func cleanSlash(p string) string {
+	trailing := strings.HasSuffix(p, "/")
+	p = path.Clean("/" + p)
+	if trailing && p != "/" {
+		p += "/"
+	}
+	return p
+}
+
+// hasDotDot reports whether p has a ".." segment. Names merely containing
+// two dots, such as v1..2, are fine.
+func hasDotDot(p string) bool {
+	for _, seg := range strings.Split(p, "/") {
+		if seg == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+// Match returns the app with the longest path prefix matching the request.
+// Prefixes match whole path segments: /admin matches /admin and /admin/x
+// but not /administrator. path must come from CleanPath.
+func (t *Table) Match(host, path string) (App, bool) {
+	if t == nil {
+		return App{}, false
+	}
+	for _, a := range t.byHost[NormalizeHost(host)] {
+		p := a.PathPrefix
+		if path == p || (strings.HasPrefix(path, p) && (strings.HasSuffix(p, "/") || path[len(p)] == '/')) {
+			return a, true
+		}
+	}
+	return App{}, false
+}
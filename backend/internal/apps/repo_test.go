@@ -0,0 +1,91 @@
+package apps
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCleanPath(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{raw: "/", want: "/"},
+		{raw: "/grafana/d/abc?orgId=1", want: "/grafana/d/abc"},
+		{raw: "/public/", want: "/public/"},
+		{raw: "/public/../admin", want: "/admin"},
+		{raw: "/public/%2e%2e/admin", want: "/admin"},
+		{raw: "/public/..%2Fadmin", want: "/admin"},
+		{raw: "//admin//x/", want: "/admin/x/"},
+		{raw: "/api/repos/a%2Fb", want: "/api/repos/a/b"},
+		{raw: "/releases/v1..2/notes", want: "/releases/v1..2/notes"},
+		{raw: "/files/a..", want: "/files/a.."},
+		{raw: "/public/%252e%252e/admin", wantErr: true},
+		{raw: "/public/%zz", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, err := CleanPath(tt.raw)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidPath) {
+					t.Fatalf("got %q, %v; want ErrInvalidPath", got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("got %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateCleansPathPrefix(t *testing.T) {
+	tests := []struct {
+		prefix string
+		want   string
+	}{
+		{prefix: "", want: "/"},
+		{prefix: "/grafana", want: "/grafana"},
+		{prefix: "/grafana/", want: "/grafana/"},
+		{prefix: "/a/../b", want: "/b"},
+		{prefix: "/a//b/./c/", want: "/a/b/c/"},
+	}
+	for _, tt := range tests {
+		a := App{Host: "Grafana.Internal:443", PathPrefix: tt.prefix, ResourceID: 1}
+		if err := a.Validate(); err != nil {
+			t.Fatalf("%q: %v", tt.prefix, err)
+		}
+		if a.PathPrefix != tt.want || a.Host != "grafana.internal" {
+			t.Errorf("%q: got %s%s, want grafana.internal%s", tt.prefix, a.Host, a.PathPrefix, tt.want)
+		}
+	}
+}
+
+func TestTableMatch(t *testing.T) {
+	table := NewTable([]App{
+		{Host: "apps.internal", PathPrefix: "/", ResourceName: "portal"},
+		{Host: "apps.internal", PathPrefix: "/admin", ResourceName: "admin"},
+		{Host: "apps.internal", PathPrefix: "/public/", ResourceName: "public"},
+	})
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "/", want: "portal"},
+		{path: "/admin", want: "admin"},
+		{path: "/admin/users", want: "admin"},
+		{path: "/administrator", want: "portal"},
+		{path: "/public/x", want: "public"},
+		{path: "/public", want: "portal"},
+	}
+	for _, tt := range tests {
+		a, ok := table.Match("APPS.internal:8443", tt.path)
+		if !ok || a.ResourceName != tt.want {
+			t.Errorf("%s: got %q, %v; want %q", tt.path, a.ResourceName, ok, tt.want)
+		}
+	}
+	if _, ok := table.Match("other.internal", "/"); ok {
+		t.Error("unknown host matched")
+	}
+}
@@ -0,0 +1,110 @@
+package attributes
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// Kind selects which entity an attribute belongs to.
+type Kind int
+
+const (
+	User Kind = iota
+	Resource
+)
+
+func (k Kind) table() (table, idColumn string) {
+	if k == Resource {
+		return "resource_attributes", "resource_id"
+	}
+	return "user_attributes", "user_id"
+}
+
+// Repository provides DB access for user_attributes and resource_attributes.
+type Repository struct {
+	DB *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{DB: db}
+}
+
+// Get returns the attributes of a single user or resource.
+func (r *Repository) Get(ctx context.Context, kind Kind, id int64) (map[string]string, error) {
+	all, err := r.GetMany(ctx, kind, []int64{id})
+	if err != nil {
+		return nil, err
+	}
+	if all[id] == nil {
+		return map[string]string{}, nil
+	}
+	return all[id], nil
+}
+
+// GetMany returns attributes for several ids in one query. Ids without
+// attributes are absent from the result.
+func (r *Repository) GetMany(ctx context.Context, kind Kind, ids []int64) (map[int64]map[string]string, error) {
+	table, idCol := kind.table()
+
+	rows, err := r.DB.QueryContext(ctx, fmt.Sprintf(`
+SELECT %[2]s, key, value
+FROM %[1]s
+WHERE %[2]s = ANY($1);
+`, table, idCol), pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := map[int64]map[string]string{}
+	for rows.Next() {
+		var (
+			id         int64
+			key, value string
+		)
+		if err := rows.Scan(&id, &key, &value); err != nil {
+			return nil, err
+		}
+		if out[id] == nil {
+			out[id] = map[string]string{}
+		}
+		out[id][key] = value
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Replace sets the full attribute map of a user or resource. Keys must
+// already be validated.
+func (r *Repository) Replace(ctx context.Context, kind Kind, id int64, attrs map[string]string) error {
+	table, idCol := kind.table()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		fmt.Sprintf(`DELETE FROM %s WHERE %s = $1;`, table, idCol),
+		id,
+	); err != nil {
+		return err
+	}
+
+	for k, v := range attrs {
+		if _, err := tx.ExecContext(ctx,
+			fmt.Sprintf(`INSERT INTO %s (%s, key, value) VALUES ($1, $2, $3);`, table, idCol),
+			id, k, v,
+		); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,154 @@
+package audit
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"github.com/lib/pq"
+
+	"zero-trust-access-platform/backend/internal/middleware"
+	"zero-trust-access-platform/backend/internal/policy"
+)
+
+// Origin describes where a decision was requested: the HTTP request, or
+// the request an external proxy is asking about.
+type Origin struct {
+	Path          string
+	Method        string
+	IP            string
+	ServiceClient string // set for service clients and proxies
+}
+
+// OriginOf describes an HTTP request handled by the platform itself.
+func OriginOf(r *http.Request) Origin {
+	o := Origin{Path: r.URL.Path, Method: r.Method, IP: middleware.ClientIP(r)}
+	o.ServiceClient, _ = middleware.ServiceClient(r)
+	return o
+}
+
+// Record writes a policy decision to access_logs (best-effort). Call it
+// with the decision after its obligations have been fulfilled, so the
+// fulfilled ones are recorded with it.
+func Record(
+	db *sql.DB,
+	r *http.Request,
+	ac policy.AccessContext,
+	d policy.Decision,
+) {
+	Write(db, OriginOf(r), ac, d)
+}
+
+// Write is Record for decisions not made on behalf of an *http.Request,
+// e.g. Envoy ext_authz checks.
+func Write(
+	db *sql.DB,
+	o Origin,
+	ac policy.AccessContext,
+	d policy.Decision,
+) {
+	decision := "deny"
+	if d.Allowed {
+		decision = "allow"
+	}
+
+	// Stored so the decision can be replayed against other policy sets.
+	var acJSON any
+	if b, err := json.Marshal(ac); err == nil {
+		acJSON = string(b)
+	}
+
+	var obligationsJSON any
+	if fulfilled := d.FulfilledObligations(); len(fulfilled) > 0 {
+		if b, err := json.Marshal(fulfilled); err == nil {
+			obligationsJSON = string(b)
+		}
+	}
+
+	var serviceClient any
+	if o.ServiceClient != "" {
+		serviceClient = o.ServiceClient
+	}
+
+	var threatFeed any
+	if ac.ThreatIntel != nil {
+		threatFeed = ac.ThreatIntel.Feed
+	}
+
+	var shadowDecision, shadowPolicy, shadowReason any
+	if d.Shadow != nil {
+		shadowDecision = "deny"
+		if d.Shadow.Allowed {
+			shadowDecision = "allow"
+		}
+		shadowPolicy = d.Shadow.Policy
+		shadowReason = d.Shadow.Reason
+	}
+
+	_, _ = db.Exec(
+		`INSERT INTO access_logs
+         (user_id, role, resource_name, action, decision,
+          policy_name, contributing_policies, decision_reason, policy_version_id, context,
+          obligations, shadow_decision, shadow_policy_name, shadow_reason,
+          service_client, path, method, ip, risk_score, threat_feed)
+         VALUES ($1,$2,$3,$4,$5,$6,$7,$8,NULLIF($9, 0),$10,$11,$12,$13,$14,$15,$16,$17,$18,$19,$20)`,
+		ac.UserID,
+		ac.UserRole,
+		ac.ResourceName,
+		ac.Action,
+		decision,
+		d.Policy,
+		pq.Array(d.Policies),
+		d.Reason,
+		d.Version,
+		acJSON,
+		obligationsJSON,
+		shadowDecision,
+		shadowPolicy,
+		shadowReason,
+		serviceClient,
+		o.Path,
+		o.Method,
+		o.IP,
+		ac.RiskScore,
+		threatFeed,
+	)
+}
+
+// Stream writes decisions in the background, for callers such as the
+// proxy that must not wait on the database for every request. When the
+// buffer is full the caller writes the decision itself, so decisions are
+// slowed down under load but never dropped.
+type Stream struct {
+	db *sql.DB
+	ch chan streamEntry
+}
+
+type streamEntry struct {
+	o  Origin
+	ac policy.AccessContext
+	d  policy.Decision
+}
+
+// NewStream starts a writer with room for size pending decisions.
+func NewStream(db *sql.DB, size int) *Stream {
+	s := &Stream{db: db, ch: make(chan streamEntry, size)}
+	go s.run()
+	return s
+}
+
+// Write queues a decision for Write, or writes it synchronously when the
+// buffer is full.
+func (s *Stream) Write(o Origin, ac policy.AccessContext, d policy.Decision) {
+	select {
+	case s.ch <- streamEntry{o, ac, d}:
+	default:
+		Write(s.db, o, ac, d)
+	}
+}
+
+func (s *Stream) run() {
+	for e := range s.ch {
+		Write(s.db, e.o, e.ac, e.d)
+	}
+}
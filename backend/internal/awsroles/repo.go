@@ -3,8 +3,6 @@ package awsroles
 import (
 	"context"
 	"database/sql"
-	"fmt"
-	"strings"
 )
 
 // Repository provides DB access for AWS roles.
@@ -16,39 +14,41 @@ func NewRepository(db *sql.DB) *Repository {
 	return &Repository{DB: db}
 }
 
+// grantedNames selects the names of the AWS roles granted to application
+// role $1: the aws_role:<name> permissions of the role and of every role
+// it inherits.
+const grantedNames = `
+WITH RECURSIVE effective(name) AS (
+    SELECT name FROM roles WHERE name = $1
+    UNION
+    SELECT parent
+    FROM effective e
+    JOIN roles r ON r.name = e.name
+    CROSS JOIN LATERAL unnest(r.inherits) AS parent
+)
+SELECT substr(p, length('aws_role:') + 1)
+FROM effective e
+JOIN roles r ON r.name = e.name
+CROSS JOIN LATERAL unnest(r.permissions) AS p
+WHERE p LIKE 'aws_role:%'
+`
+
 // ListForAppRole returns all AWS roles that should be available to users
-// with the given application role (e.g. "admin", "developer").
-//
-// It uses defaultRoleMap (roleNamesForAppRole) and selects from aws_roles
-// by name. You can extend this to also filter by env or risk_level.
+// with the given application role (e.g. "admin", "devops"), including
+// those granted to roles it inherits.
 func (r *Repository) ListForAppRole(ctx context.Context, appRole string) ([]AwsRole, error) {
-	names := roleNamesForAppRole(appRole)
-	if len(names) == 0 {
-		return []AwsRole{}, nil
-	}
-
-	// Build simple IN clause with $1, $2, ...
-	placeholders := make([]string, len(names))
-	args := make([]any, len(names))
-	for i, n := range names {
-		placeholders[i] = fmt.Sprintf("$%d", i+1)
-		args[i] = n
-	}
-
-	q := fmt.Sprintf(`
+	rows, err := r.DB.QueryContext(ctx, `
 SELECT id, name, arn, description, env, risk_level, created_at, updated_at
 FROM aws_roles
-WHERE name IN (%s)
+WHERE name IN (`+grantedNames+`)
 ORDER BY env, name;
-`, strings.Join(placeholders, ","))
-
-	rows, err := r.DB.QueryContext(ctx, q, args...)
+`, appRole)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var roles []AwsRole
+	roles := []AwsRole{}
 	for rows.Next() {
 		var role AwsRole
 		if err := rows.Scan(
@@ -71,32 +71,14 @@ ORDER BY env, name;
 	return roles, nil
 }
 
-// GetForAppRole returns a single AWS role by id, but only if that role
-// is allowed for the given application role. This prevents a user from
-// requesting an arbitrary role id they are not mapped to.
-func (r *Repository) GetForAppRole(ctx context.Context, appRole string, roleID int64) (*AwsRole, error) {
-	names := roleNamesForAppRole(appRole)
-	if len(names) == 0 {
-		return nil, sql.ErrNoRows
-	}
-
-	placeholders := make([]string, len(names))
-	args := make([]any, 0, len(names)+1)
-	for i, n := range names {
-		placeholders[i] = fmt.Sprintf("$%d", i+1)
-		args = append(args, n)
-	}
-	// last arg is roleID
-	args = append(args, roleID)
-
-	q := fmt.Sprintf(`
+// Get returns a single AWS role by id, or sql.ErrNoRows. It does not
+// check that the role is granted to anyone; see IsGranted.
+func (r *Repository) Get(ctx context.Context, roleID int64) (*AwsRole, error) {
+	row := r.DB.QueryRowContext(ctx, `
 SELECT id, name, arn, description, env, risk_level, created_at, updated_at
 FROM aws_roles
-WHERE id = $%d AND name IN (%s)
-LIMIT 1;
-`, len(args), strings.Join(placeholders, ","))
-
-	row := r.DB.QueryRowContext(ctx, q, args...)
+WHERE id = $1;
+`, roleID)
 
 	var role AwsRole
 	if err := row.Scan(
@@ -113,3 +95,11 @@ LIMIT 1;
 	}
 	return &role, nil
 }
+
+// IsGranted reports whether the AWS role named name is granted to the
+// given application role, directly or through a role it inherits.
+func (r *Repository) IsGranted(ctx context.Context, appRole, name string) (bool, error) {
+	var ok bool
+	err := r.DB.QueryRowContext(ctx, `SELECT $2 IN (`+grantedNames+`);`, appRole, name).Scan(&ok)
+	return ok, err
+}
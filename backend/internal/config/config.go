@@ -1,6 +1,12 @@
 package config
 
-import "os"
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
 
 type Config struct {
 	AppEnv     string
@@ -12,6 +18,66 @@ type Config struct {
 	DBName     string
 	JWTSecret  string
 	AWSRegion  string
+
+	// Session controls, enforced by the sessions repository.
+	SessionIdleTimeout      time.Duration  // 0 disables the idle timeout
+	SessionMaxLifetime      time.Duration  // absolute lifetime for regular roles
+	AdminSessionMaxLifetime time.Duration  // absolute lifetime for admins
+	SessionMaxConcurrent    map[string]int // per role; missing or 0 means unlimited
+
+	// CIDRs of reverse proxies whose X-Forwarded-For header is trusted.
+	TrustedProxies []string
+
+	// Where notify_owner obligations are POSTed; empty logs them instead.
+	// Owners hear about one user's access to one resource at most once per
+	// NotifyDedupeWindow.
+	NotifyWebhookURL   string
+	NotifyDedupeWindow time.Duration
+
+	// Policy engine: "builtin" or "rego". Rego bundles come from
+	// RegoBundleDir if set, otherwise from the rego_modules table, and are
+	// re-read every RegoReloadInterval. With PolicyEngineCompare the
+	// built-in engine also runs on every request for comparison.
+	PolicyEngine        string
+	PolicyEngineCompare bool
+	RegoBundleDir       string
+	RegoQuery           string
+	RegoReloadInterval  time.Duration
+
+	// Listen address of the Envoy ext_authz gRPC service, e.g. ":9191";
+	// empty disables it.
+	ExtAuthzGRPCAddr string
+
+	// Identity-aware reverse proxy. ProxyAddr empty disables it;
+	// ProxyLoginURL is the frontend login page, also used to send browsers
+	// behind forward auth to log in, and ProxyHeaderSecret signs the
+	// X-ZT-Assertion header for upstreams.
+	ProxyAddr         string
+	ProxyLoginURL     string
+	ProxyHeaderSecret string
+
+	// Decision cache; a size of 0 disables it.
+	DecisionCacheSize int
+	DecisionCacheTTL  time.Duration
+
+	// Risk engine: points each signal adds to risk.score at full
+	// severity. Signals left out (or set to 0) are not computed.
+	RiskWeights map[string]int
+
+	// Signal providers run before every evaluation. SignalWebhooks maps
+	// provider names to URLs; SignalTimeouts (milliseconds) and
+	// SignalFailClosed apply to any provider by name.
+	SignalWebhooks   map[string]string
+	SignalTimeouts   map[string]int
+	SignalFailClosed []string
+
+	// Threat-intelligence blocklists (CIDR lists, or CSV with categories),
+	// re-read when they change. ThreatIntelLogin is what happens to logins
+	// from a listed IP: "deny", "step_up" (only users with MFA already
+	// enrolled may continue) or "off".
+	ThreatIntelFiles          []string
+	ThreatIntelReloadInterval time.Duration
+	ThreatIntelLogin          string
 }
 
 func Load() *Config {
@@ -25,12 +91,138 @@ func Load() *Config {
 		DBName:     getEnv("DB_NAME", "zero_trust"),
 		JWTSecret:  getEnv("JWT_SECRET", "change-me-in-env"),
 		AWSRegion:  getEnv("AWS_REGION", "us-east-1"),
+
+		SessionIdleTimeout:      getDuration("SESSION_IDLE_TIMEOUT", 30*time.Minute),
+		SessionMaxLifetime:      getDuration("SESSION_MAX_LIFETIME", 24*time.Hour),
+		AdminSessionMaxLifetime: getDuration("ADMIN_SESSION_MAX_LIFETIME", 8*time.Hour),
+		SessionMaxConcurrent:    getIntMap("SESSION_MAX_CONCURRENT", "admin:2,user:5"),
+
+		TrustedProxies: getList("TRUSTED_PROXIES", ""),
+
+		NotifyWebhookURL:   getEnv("NOTIFY_WEBHOOK_URL", ""),
+		NotifyDedupeWindow: getDuration("NOTIFY_DEDUPE_WINDOW", time.Hour),
+
+		PolicyEngine:        getEnv("POLICY_ENGINE", "builtin"),
+		PolicyEngineCompare: getBool("POLICY_ENGINE_COMPARE", false),
+		RegoBundleDir:       getEnv("REGO_BUNDLE_DIR", ""),
+		RegoQuery:           getEnv("REGO_QUERY", "data.zerotrust.authz.decision"),
+		RegoReloadInterval:  getDuration("REGO_RELOAD_INTERVAL", 30*time.Second),
+
+		ExtAuthzGRPCAddr: getEnv("EXT_AUTHZ_GRPC_ADDR", ""),
+
+		ProxyAddr:         getEnv("PROXY_ADDR", ""),
+		ProxyLoginURL:     getEnv("PROXY_LOGIN_URL", ""),
+		ProxyHeaderSecret: getEnv("PROXY_HEADER_SECRET", ""),
+
+		DecisionCacheSize: getInt("DECISION_CACHE_SIZE", 10000),
+		DecisionCacheTTL:  getDuration("DECISION_CACHE_TTL", 30*time.Second),
+
+		RiskWeights: getIntMap("RISK_WEIGHTS", "failed_logins:40,new_device:20,unusual_hour:15,ip_reputation:30,deny_burst:30"),
+
+		SignalWebhooks:   getStringMap("SIGNAL_WEBHOOKS", ""),
+		SignalTimeouts:   getIntMap("SIGNAL_TIMEOUTS", ""),
+		SignalFailClosed: getList("SIGNAL_FAIL_CLOSED", ""),
+
+		ThreatIntelFiles:          getList("THREAT_INTEL_FILES", ""),
+		ThreatIntelReloadInterval: getDuration("THREAT_INTEL_RELOAD_INTERVAL", 30*time.Second),
+		ThreatIntelLogin:          getEnv("THREAT_INTEL_LOGIN", "deny"),
 	}
 }
 
+// Development reports whether APP_ENV is "development", which relaxes
+// checks that need TLS in production, such as https-only login callbacks.
+func (c *Config) Development() bool {
+	return c.AppEnv == "development"
+}
+
 func getEnv(key, def string) string {
 	if v, ok := os.LookupEnv(key); ok {
 		return v
 	}
 	return def
 }
+
+func getDuration(key string, def time.Duration) time.Duration {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Printf("config: invalid %s=%q, using %s", key, v, def)
+		return def
+	}
+	return d
+}
+
+func getInt(key string, def int) int {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		log.Printf("config: invalid %s=%q, using %d", key, v, def)
+		return def
+	}
+	return n
+}
+
+func getBool(key string, def bool) bool {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		log.Printf("config: invalid %s=%q, using %t", key, v, def)
+		return def
+	}
+	return b
+}
+
+// getIntMap parses "key:n,key:n" lists such as "admin:2,user:5".
+func getIntMap(key, def string) map[string]int {
+	raw := getEnv(key, def)
+	out := map[string]int{}
+	for _, pair := range strings.Split(raw, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		k, v, found := strings.Cut(pair, ":")
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if !found || err != nil || n < 0 {
+			log.Printf("config: ignoring invalid %s entry %q", key, pair)
+			continue
+		}
+		out[strings.TrimSpace(k)] = n
+	}
+	return out
+}
+
+// getStringMap parses "key=value,key=value" lists; values may contain ":"
+// (e.g. URLs) but not ",".
+func getStringMap(key, def string) map[string]string {
+	out := map[string]string{}
+	for _, pair := range getList(key, def) {
+		k, v, found := strings.Cut(pair, "=")
+		if !found || strings.TrimSpace(k) == "" || strings.TrimSpace(v) == "" {
+			log.Printf("config: ignoring invalid %s entry %q", key, pair)
+			continue
+		}
+		out[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return out
+}
+
+// getList parses a comma-separated list, dropping empty entries.
+func getList(key, def string) []string {
+	var out []string
+	for _, v := range strings.Split(getEnv(key, def), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -9,8 +9,10 @@ import (
 	_ "github.com/lib/pq"
 )
 
-func Open(cfg *config.Config) (*sql.DB, error) {
-	dsn := fmt.Sprintf(
+// DSN is the lib/pq connection string for cfg, also used for LISTEN
+// connections.
+func DSN(cfg *config.Config) string {
+	return fmt.Sprintf(
 		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
 		cfg.DBHost,
 		cfg.DBPort,
@@ -18,14 +20,8 @@ func Open(cfg *config.Config) (*sql.DB, error) {
 		cfg.DBPassword,
 		cfg.DBName,
 	)
+}
 
-	// DEBUG: print DSN parts (no password)
-	fmt.Println("DB DSN DEBUG:",
-		"host="+cfg.DBHost,
-		"port="+cfg.DBPort,
-		"user="+cfg.DBUser,
-		"dbname="+cfg.DBName,
-	)
-
-	return sql.Open("postgres", dsn)
+func Open(cfg *config.Config) (*sql.DB, error) {
+	return sql.Open("postgres", DSN(cfg))
 }
@@ -3,32 +3,63 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
+	"zero-trust-access-platform/backend/internal/attributes"
+	"zero-trust-access-platform/backend/internal/audit"
 	"zero-trust-access-platform/backend/internal/awsroles"
 	"zero-trust-access-platform/backend/internal/awssts"
 	"zero-trust-access-platform/backend/internal/middleware"
+	"zero-trust-access-platform/backend/internal/notify"
+	"zero-trust-access-platform/backend/internal/obligations"
+	"zero-trust-access-platform/backend/internal/policy"
+	"zero-trust-access-platform/backend/internal/signals"
+)
+
+// AWS console sessions last an hour unless a policy obligation caps them;
+// STS will not issue anything shorter than 15 minutes.
+const (
+	awsSessionDuration    = time.Hour
+	awsMinSessionDuration = 15 * time.Minute
 )
 
 // AwsRolesHandler exposes APIs for listing AWS roles available
 // to the current user and creating console sessions for them.
 type AwsRolesHandler struct {
-	Repo *awsroles.Repository
-	STS  *awssts.Service
-	DB   *sql.DB
+	Repo     *awsroles.Repository
+	STS      *awssts.Service
+	DB       *sql.DB
+	Notifier notify.Notifier
+	Signals  *signals.Registry
 }
 
-func NewAwsRolesHandler(repo *awsroles.Repository, stsSvc *awssts.Service, db *sql.DB) *AwsRolesHandler {
+func NewAwsRolesHandler(repo *awsroles.Repository, stsSvc *awssts.Service, db *sql.DB, notifier notify.Notifier, signalRegistry *signals.Registry) *AwsRolesHandler {
 	return &AwsRolesHandler{
-		Repo: repo,
-		STS:  stsSvc,
-		DB:   db,
+		Repo:     repo,
+		STS:      stsSvc,
+		DB:       db,
+		Notifier: notifier,
+		Signals:  signalRegistry,
 	}
 }
 
+type createAwsSessionRequest struct {
+	Justification string `json:"justification"`
+	MFACode       string `json:"mfa_code"` // for step_up_mfa
+}
+
+type accessDeniedResponse struct {
+	Error  string `json:"error"`
+	Policy string `json:"policy"`
+	Reason string `json:"reason"`
+}
+
 type AwsRoleResponse struct {
 	ID          int64  `json:"id"`
 	Name        string `json:"name"`
@@ -68,9 +99,10 @@ func (h *AwsRolesHandler) ListMyAwsRoles(w http.ResponseWriter, r *http.Request)
 	_ = json.NewEncoder(w).Encode(out)
 }
 
-// POST /me/aws/roles/{id}/session
-// Creates a federated AWS console URL for the specified role,
-// only if that role is allowed for the current user's app role.
+// POST /me/aws/roles/{id}/session  body (optional): {"justification": "..."}
+// Creates a federated AWS console URL for the specified role, only if
+// the policy allows it, the role is granted to the user's app role and
+// the policy's obligations can be fulfilled. Every decision is logged.
 func (h *AwsRolesHandler) CreateAwsSession(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.UserID(r)
 	if !ok {
@@ -97,45 +129,132 @@ func (h *AwsRolesHandler) CreateAwsSession(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	// Ensure this role is actually allowed for this app role.
-	role, err := h.Repo.GetForAppRole(r.Context(), appRole, roleID)
-	if err != nil {
-		// Could be sql.ErrNoRows or other error; treat as not found for security.
+	role, err := h.Repo.Get(r.Context(), roleID)
+	if errors.Is(err, sql.ErrNoRows) {
 		http.Error(w, "role not found", http.StatusNotFound)
 		return
 	}
+	if err != nil {
+		http.Error(w, "failed to load role", http.StatusInternalServerError)
+		return
+	}
+	granted, err := h.Repo.IsGranted(r.Context(), appRole, role.Name)
+	if err != nil {
+		http.Error(w, "failed to check role grant", http.StatusInternalServerError)
+		return
+	}
+
+	var req createAwsSessionRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, 4096)).Decode(&req); err != nil && err != io.EOF {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	// 🔐 Zero Trust policy evaluation. The role's risk level is its
+	// sensitivity, so the high-sensitivity policies apply to high-risk roles.
+	ac := policy.AccessContext{
+		UserID:             userID,
+		UserRole:           appRole,
+		MFAEnabled:         true, // enforced during login
+		ResourceName:       role.Name,
+		ResourceType:       "aws_role",
+		Sensitivity:        string(role.RiskLevel),
+		Action:             "assume",
+		ClientIP:           middleware.ClientIP(r),
+		Time:               time.Now(),
+		ResourceAttributes: map[string]string{"env": role.Env},
+	}
+	if h.DB != nil {
+		ac.UserAttributes, err = attributes.NewRepository(h.DB).Get(r.Context(), attributes.User, userID)
+		if err != nil {
+			http.Error(w, "failed to load user attributes", http.StatusInternalServerError)
+			return
+		}
+	}
+	if err := h.Signals.Enrich(r.Context(), &ac, signals.Request{UserAgent: r.UserAgent()}); err != nil {
+		h.deny(w, r, ac, signals.Denied(err), granted)
+		return
+	}
+
+	var verifyMFA func(string) error
+	if h.DB != nil {
+		verifyMFA = obligations.VerifyTOTP(r.Context(), h.DB, userID)
+	}
+
+	// The policy decides first, so e.g. regular users are denied by
+	// aws-non-privileged-deny; an allow still needs the role's grant.
+	decision := policy.Evaluate(ac)
+	if decision.Allowed && !granted {
+		decision = policy.Decision{
+			Allowed:  false,
+			Policy:   "aws-role-not-granted",
+			Policies: []string{},
+			Reason:   fmt.Sprintf("%s is not granted to the %s role", role.Name, appRole),
+		}
+	}
+
+	duration := awsSessionDuration
+	decision = obligations.Fulfil(r.Context(), h.Notifier, ac, decision, obligations.Request{
+		Justification:      req.Justification,
+		SessionDuration:    &duration,
+		MinSessionDuration: awsMinSessionDuration,
+		MFACode:            req.MFACode,
+		VerifyMFA:          verifyMFA,
+	})
+
+	if !decision.Allowed {
+		h.deny(w, r, ac, decision, granted)
+		return
+	}
 
 	// Create a federated console URL via STS.
 	consoleURL, err := h.STS.AssumeRoleAndConsoleURL(
 		r.Context(),
 		role.ARN,
 		fmt.Sprintf("zt-%d-%d", userID, role.ID),
-		int32(3600),
+		int32(duration.Seconds()),
 	)
 	if err != nil {
+		// The policy allowed it, but no session exists: log it as such.
+		h.record(r, ac, policy.Decision{
+			Allowed:  false,
+			Policy:   "aws-sts-failed",
+			Policies: []string{},
+			Reason:   "policy " + decision.Policy + " allowed the session but STS failed: " + err.Error(),
+		})
 		http.Error(w, "failed to create aws session", http.StatusInternalServerError)
 		return
 	}
+	h.record(r, ac, decision)
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"url":              consoleURL,
+		"duration_seconds": int(duration.Seconds()),
+	})
+}
 
-	// Log activity into access_logs (best-effort; ignore errors).
+// record logs a decision into access_logs (best-effort; ignore errors).
+func (h *AwsRolesHandler) record(r *http.Request, ac policy.AccessContext, d policy.Decision) {
 	if h.DB != nil {
-		_, _ = h.DB.Exec(`
-			INSERT INTO access_logs (user_id, role, resource_name, action, decision, path, method, ip)
-			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-		`,
-			userID,
-			appRole,
-			role.Name,        // resource_name
-			"create_session", // action
-			"allow",          // decision
-			r.URL.Path,
-			r.Method,
-			r.RemoteAddr,
-		)
+		audit.Record(h.DB, r, ac, d)
 	}
+}
 
+// deny logs d and answers 403 with the deciding policy and reason. For a
+// role not granted to the caller it answers 404 as for an unknown id, so
+// the response reveals neither the role's name nor that it exists.
+func (h *AwsRolesHandler) deny(w http.ResponseWriter, r *http.Request, ac policy.AccessContext, d policy.Decision, granted bool) {
+	h.record(r, ac, d)
+	if !granted {
+		http.Error(w, "role not found", http.StatusNotFound)
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(map[string]string{
-		"url": consoleURL,
+	w.WriteHeader(http.StatusForbidden)
+	_ = json.NewEncoder(w).Encode(accessDeniedResponse{
+		Error:  "access denied",
+		Policy: d.Policy,
+		Reason: d.Reason,
 	})
 }
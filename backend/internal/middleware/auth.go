@@ -2,6 +2,9 @@ package middleware
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
 	"strings"
 
@@ -11,11 +14,100 @@ import (
 type ctxKey string
 
 const (
-	ctxUserID   ctxKey = "userID"
-	ctxUserRole ctxKey = "userRole"
+	ctxUserID    ctxKey = "userID"
+	ctxUserRole  ctxKey = "userRole"
+	ctxSessionID ctxKey = "sessionID"
 )
 
-func Auth(jwtSecret []byte, next http.HandlerFunc) http.HandlerFunc {
+// SessionStore is consulted on every authenticated request so that a
+// revoked session is rejected even though its JWT is still valid. Touch
+// returns ErrSessionInvalid for an unknown, revoked or expired session;
+// any other error means the session could not be checked.
+type SessionStore interface {
+	Touch(ctx context.Context, id string) error
+}
+
+// ErrSessionInvalid means the token's session is unknown, revoked or
+// expired: the caller is not authenticated.
+var ErrSessionInvalid = errors.New("session revoked or expired")
+
+// ErrSessionUnavailable is returned by ParseToken when the session store
+// failed, e.g. during a database outage. Callers answer 503 rather than
+// logging the user out.
+var ErrSessionUnavailable = errors.New("session store unavailable")
+
+// SessionCookie is the cookie that carries the platform JWT for browsers
+// reaching apps behind the forward-auth endpoints and the proxy.
+const SessionCookie = "zt_session"
+
+// ErrNoToken is returned by SessionToken when the request carries no token.
+var ErrNoToken = errors.New("no session token")
+
+// Identity is the authenticated user behind a platform session.
+type Identity struct {
+	UserID    int64
+	Role      string
+	SessionID string
+	MFA       bool   // false for the temporary token issued before MFA
+	Host      string // set for a session scoped to one app host ("aud")
+}
+
+// SessionToken returns the JWT from the Authorization header or, failing
+// that, the session cookie.
+func SessionToken(r *http.Request) (string, error) {
+	if h := r.Header.Get("Authorization"); h != "" {
+		tok, ok := strings.CutPrefix(h, "Bearer ")
+		if !ok {
+			return "", errors.New("invalid token format")
+		}
+		return tok, nil
+	}
+	if c, err := r.Cookie(SessionCookie); err == nil && c.Value != "" {
+		return c.Value, nil
+	}
+	return "", ErrNoToken
+}
+
+// ParseToken validates a platform JWT and its backing session.
+func ParseToken(ctx context.Context, jwtSecret []byte, sessions SessionStore, tokenStr string) (*Identity, error) {
+	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
+		return jwtSecret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil || !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, errors.New("invalid claims")
+	}
+
+	sub, okSub := claims["sub"].(float64)
+	role, okRole := claims["role"].(string)
+	jti, okJti := claims["jti"].(string)
+	if !okSub || !okRole || !okJti || jti == "" {
+		return nil, errors.New("invalid token claims")
+	}
+	mfa, _ := claims["mfa"].(bool)
+	var host string
+	if aud, err := claims.GetAudience(); err == nil && len(aud) > 0 {
+		if len(aud) != 1 || aud[0] == "" {
+			return nil, errors.New("invalid token claims")
+		}
+		host = aud[0]
+	}
+
+	if err := sessions.Touch(ctx, jti); err != nil {
+		if errors.Is(err, ErrSessionInvalid) {
+			return nil, ErrSessionInvalid
+		}
+		return nil, fmt.Errorf("%w: %v", ErrSessionUnavailable, err)
+	}
+
+	return &Identity{UserID: int64(sub), Role: role, SessionID: jti, MFA: mfa, Host: host}, nil
+}
+
+func Auth(jwtSecret []byte, sessions SessionStore, next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
@@ -29,29 +121,26 @@ func Auth(jwtSecret []byte, next http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 
-		token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-			return jwtSecret, nil
-		})
-		if err != nil || !token.Valid {
-			http.Error(w, "invalid token", http.StatusUnauthorized)
+		id, err := ParseToken(r.Context(), jwtSecret, sessions, tokenStr)
+		if errors.Is(err, ErrSessionUnavailable) {
+			log.Printf("auth: %v", err)
+			http.Error(w, "session store unavailable", http.StatusServiceUnavailable)
 			return
 		}
-
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			http.Error(w, "invalid claims", http.StatusUnauthorized)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
 			return
 		}
-
-		sub, okSub := claims["sub"].(float64)
-		role, okRole := claims["role"].(string)
-		if !okSub || !okRole {
-			http.Error(w, "invalid token claims", http.StatusUnauthorized)
+		// App host sessions live in a cookie the app can see; they are
+		// not API tokens.
+		if id.Host != "" {
+			http.Error(w, "token is scoped to an app host", http.StatusUnauthorized)
 			return
 		}
 
-		ctx := context.WithValue(r.Context(), ctxUserID, int64(sub))
-		ctx = context.WithValue(ctx, ctxUserRole, role)
+		ctx := context.WithValue(r.Context(), ctxUserID, id.UserID)
+		ctx = context.WithValue(ctx, ctxUserRole, id.Role)
+		ctx = context.WithValue(ctx, ctxSessionID, id.SessionID)
 
 		next.ServeHTTP(w, r.WithContext(ctx))
 	}
@@ -66,3 +155,8 @@ func UserRole(r *http.Request) (string, bool) {
 	v, ok := r.Context().Value(ctxUserRole).(string)
 	return v, ok
 }
+
+func SessionID(r *http.Request) (string, bool) {
+	v, ok := r.Context().Value(ctxSessionID).(string)
+	return v, ok
+}
@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var testSecret = []byte("test-secret")
+
+// fakeSessions knows the sessions in ok; fail makes every lookup error.
+type fakeSessions struct {
+	ok   map[string]bool
+	fail bool
+}
+
+func (f fakeSessions) Touch(_ context.Context, id string) error {
+	if f.fail {
+		return errors.New("connection refused")
+	}
+	if !f.ok[id] {
+		return ErrSessionInvalid
+	}
+	return nil
+}
+
+func sign(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	base := jwt.MapClaims{
+		"sub":  float64(7),
+		"role": "user",
+		"jti":  "s1",
+		"exp":  time.Now().Add(time.Hour).Unix(),
+		"mfa":  true,
+	}
+	for k, v := range claims {
+		if v == nil {
+			delete(base, k)
+			continue
+		}
+		base[k] = v
+	}
+	tok, err := jwt.NewWithClaims(jwt.SigningMethodHS256, base).SignedString(testSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tok
+}
+
+func TestParseToken(t *testing.T) {
+	store := fakeSessions{ok: map[string]bool{"s1": true}}
+	tests := []struct {
+		name    string
+		claims  jwt.MapClaims
+		store   SessionStore
+		wantErr bool
+		errIs   error // if set, the error must wrap it
+		want    Identity
+	}{
+		{name: "api token", want: Identity{UserID: 7, Role: "user", SessionID: "s1", MFA: true}},
+		{name: "pre-MFA token", claims: jwt.MapClaims{"mfa": false}, want: Identity{UserID: 7, Role: "user", SessionID: "s1"}},
+		{name: "host token", claims: jwt.MapClaims{"aud": "grafana.internal"}, want: Identity{UserID: 7, Role: "user", SessionID: "s1", MFA: true, Host: "grafana.internal"}},
+		{name: "several audiences", claims: jwt.MapClaims{"aud": []string{"a", "b"}}, wantErr: true},
+		{name: "missing jti", claims: jwt.MapClaims{"jti": nil}, wantErr: true},
+		{name: "expired", claims: jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()}, wantErr: true},
+		{name: "revoked session", claims: jwt.MapClaims{"jti": "gone"}, wantErr: true, errIs: ErrSessionInvalid},
+		{name: "store down", store: fakeSessions{fail: true}, wantErr: true, errIs: ErrSessionUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := tt.store
+			if s == nil {
+				s = store
+			}
+			id, err := ParseToken(context.Background(), testSecret, s, sign(t, tt.claims))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %+v, want an error", *id)
+				}
+				if tt.errIs != nil && !errors.Is(err, tt.errIs) {
+					t.Fatalf("error = %v, want %v", err, tt.errIs)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if *id != tt.want {
+				t.Fatalf("identity = %+v, want %+v", *id, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuth(t *testing.T) {
+	store := fakeSessions{ok: map[string]bool{"s1": true}}
+	tests := []struct {
+		name   string
+		header string
+		want   int
+	}{
+		{name: "no token", want: http.StatusUnauthorized},
+		{name: "not bearer", header: "Basic abc", want: http.StatusUnauthorized},
+		{name: "api token", header: "Bearer " + sign(t, nil), want: http.StatusOK},
+		{name: "host token", header: "Bearer " + sign(t, jwt.MapClaims{"aud": "grafana.internal"}), want: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := Auth(testSecret, store, func(w http.ResponseWriter, r *http.Request) {
+				if id, _ := UserID(r); id != 7 {
+					t.Errorf("UserID = %d, want 7", id)
+				}
+			})
+			r := httptest.NewRequest(http.MethodGet, "/resources", nil)
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			h(w, r)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+
+	t.Run("store down", func(t *testing.T) {
+		h := Auth(testSecret, fakeSessions{fail: true}, func(http.ResponseWriter, *http.Request) {})
+		r := httptest.NewRequest(http.MethodGet, "/resources", nil)
+		r.Header.Set("Authorization", "Bearer "+sign(t, nil))
+		w := httptest.NewRecorder()
+		h(w, r)
+		if w.Code != http.StatusServiceUnavailable {
+			t.Fatalf("status = %d, want 503", w.Code)
+		}
+	})
+}
@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+const (
+	ctxClientIP ctxKey = "clientIP"
+	ctxScheme   ctxKey = "scheme"
+)
+
+// RealIP resolves the client address and scheme once per request and
+// stores them for ClientIP and Scheme. X-Forwarded-For and
+// X-Forwarded-Proto are only honoured when the direct peer is one of the
+// trusted proxies; X-Forwarded-For is then walked from the right, skipping
+// further trusted hops, so a client cannot spoof its address by sending
+// the header itself.
+func RealIP(trustedProxies []netip.Prefix, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip, trusted := resolveClientIP(r, trustedProxies)
+		scheme := "http"
+		if r.TLS != nil || (trusted && forwardedProto(r) == "https") {
+			scheme = "https"
+		}
+		ctx := context.WithValue(r.Context(), ctxClientIP, ip)
+		ctx = context.WithValue(ctx, ctxScheme, scheme)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Scheme returns the scheme the client used, "http" or "https", as
+// resolved by RealIP. Without RealIP only the connection itself counts.
+func Scheme(r *http.Request) string {
+	if v, ok := r.Context().Value(ctxScheme).(string); ok {
+		return v
+	}
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// forwardedProto is the scheme the first proxy saw.
+func forwardedProto(r *http.Request) string {
+	proto, _, _ := strings.Cut(r.Header.Get("X-Forwarded-Proto"), ",")
+	return strings.ToLower(strings.TrimSpace(proto))
+}
+
+// ClientIP returns the address resolved by RealIP, falling back to the
+// direct peer when RealIP is not installed.
+func ClientIP(r *http.Request) string {
+	if v, ok := r.Context().Value(ctxClientIP).(string); ok {
+		return v
+	}
+	host, _, _ := net.SplitHostPort(r.RemoteAddr)
+	return host
+}
+
+// ParsePrefixes parses CIDRs or bare addresses (treated as /32 or /128).
+func ParsePrefixes(list []string) ([]netip.Prefix, error) {
+	out := make([]netip.Prefix, 0, len(list))
+	for _, s := range list {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		if !strings.Contains(s, "/") {
+			addr, err := netip.ParseAddr(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid address %q", s)
+			}
+			out = append(out, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q", s)
+		}
+		out = append(out, p.Masked())
+	}
+	return out, nil
+}
+
+// resolveClientIP returns the client address and whether the direct peer
+// is a trusted proxy.
+func resolveClientIP(r *http.Request, trusted []netip.Prefix) (string, bool) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	peer, err := netip.ParseAddr(host)
+	if err != nil || !isTrusted(peer, trusted) {
+		return host, false
+	}
+
+	hops := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		if !isTrusted(addr, trusted) {
+			return addr.Unmap().String(), true
+		}
+		host = addr.Unmap().String()
+	}
+	return host, true
+}
+
+func isTrusted(addr netip.Addr, trusted []netip.Prefix) bool {
+	addr = addr.Unmap()
+	for _, p := range trusted {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
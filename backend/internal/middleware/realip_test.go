@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestRealIP(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+	tests := []struct {
+		name       string
+		peer       string
+		xff        string
+		proto      string
+		tls        bool
+		wantIP     string
+		wantScheme string
+	}{
+		{name: "direct", peer: "198.51.100.7:1234", wantIP: "198.51.100.7", wantScheme: "http"},
+		{name: "direct TLS", peer: "198.51.100.7:1234", tls: true, wantIP: "198.51.100.7", wantScheme: "https"},
+		{name: "untrusted peer spoofing", peer: "198.51.100.7:1234", xff: "203.0.113.1", proto: "https", wantIP: "198.51.100.7", wantScheme: "http"},
+		{name: "trusted proxy", peer: "10.0.0.2:1234", xff: "203.0.113.1", proto: "https", wantIP: "203.0.113.1", wantScheme: "https"},
+		{name: "trusted proxy chain", peer: "10.0.0.2:1234", xff: "192.0.2.9, 203.0.113.1, 10.0.0.3", proto: "HTTPS, http", wantIP: "203.0.113.1", wantScheme: "https"},
+		{name: "trusted proxy over http", peer: "10.0.0.2:1234", xff: "203.0.113.1", proto: "http", wantIP: "203.0.113.1", wantScheme: "http"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotIP, gotScheme string
+			h := RealIP(trusted, http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				gotIP, gotScheme = ClientIP(r), Scheme(r)
+			}))
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.peer
+			if tt.xff != "" {
+				r.Header.Set("X-Forwarded-For", tt.xff)
+			}
+			if tt.proto != "" {
+				r.Header.Set("X-Forwarded-Proto", tt.proto)
+			}
+			if tt.tls {
+				r.TLS = &tls.ConnectionState{}
+			} else {
+				r.TLS = nil
+			}
+			h.ServeHTTP(httptest.NewRecorder(), r)
+			if gotIP != tt.wantIP || gotScheme != tt.wantScheme {
+				t.Fatalf("got %s over %s, want %s over %s", gotIP, gotScheme, tt.wantIP, tt.wantScheme)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+)
+
+const ctxServiceClient ctxKey = "serviceClient"
+
+// ServiceAuthenticator checks service client credentials and returns the
+// client's name. Authenticate returns ErrServiceCredentialsInvalid for
+// wrong credentials; any other error means they could not be checked.
+type ServiceAuthenticator interface {
+	Authenticate(ctx context.Context, id, secret string) (string, error)
+}
+
+// ErrServiceCredentialsInvalid means the client is unknown or revoked, or
+// the secret is wrong.
+var ErrServiceCredentialsInvalid = errors.New("invalid service credentials")
+
+// ServiceAuth admits requests from registered service clients, which
+// authenticate with HTTP Basic auth (client ID and secret). It is used
+// instead of Auth on endpoints meant for other services, not users.
+func ServiceAuth(clients ServiceAuthenticator, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, secret, ok := r.BasicAuth()
+		if !ok || id == "" || secret == "" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="service"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		name, err := clients.Authenticate(r.Context(), id, secret)
+		if errors.Is(err, ErrServiceCredentialsInvalid) {
+			http.Error(w, "invalid service credentials", http.StatusUnauthorized)
+			return
+		}
+		// Valid credentials must not look revoked during an outage.
+		if err != nil {
+			log.Printf("service auth: %v", err)
+			http.Error(w, "service client store unavailable", http.StatusServiceUnavailable)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), ctxServiceClient, name)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	}
+}
+
+// ServiceClient is the name of the service client that made the request,
+// if it came through ServiceAuth.
+func ServiceClient(r *http.Request) (string, bool) {
+	v, ok := r.Context().Value(ctxServiceClient).(string)
+	return v, ok
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// fakeClients knows one client, "svc" with secret "s3cret"; fail makes
+// every lookup error.
+type fakeClients struct {
+	fail bool
+}
+
+func (f fakeClients) Authenticate(_ context.Context, id, secret string) (string, error) {
+	if f.fail {
+		return "", errors.New("connection refused")
+	}
+	if id != "svc" || secret != "s3cret" {
+		return "", ErrServiceCredentialsInvalid
+	}
+	return "billing", nil
+}
+
+func TestServiceAuth(t *testing.T) {
+	tests := []struct {
+		name    string
+		clients fakeClients
+		id      string
+		secret  string
+		want    int
+	}{
+		{name: "no credentials", want: http.StatusUnauthorized},
+		{name: "valid", id: "svc", secret: "s3cret", want: http.StatusOK},
+		{name: "wrong secret", id: "svc", secret: "nope", want: http.StatusUnauthorized},
+		{name: "store down", clients: fakeClients{fail: true}, id: "svc", secret: "s3cret", want: http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := ServiceAuth(tt.clients, func(w http.ResponseWriter, r *http.Request) {
+				if name, _ := ServiceClient(r); name != "billing" {
+					t.Errorf("ServiceClient = %q, want billing", name)
+				}
+			})
+			r := httptest.NewRequest(http.MethodPost, "/access/v1/evaluation", nil)
+			if tt.id != "" {
+				r.SetBasicAuth(tt.id, tt.secret)
+			}
+			w := httptest.NewRecorder()
+			h(w, r)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
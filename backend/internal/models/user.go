@@ -12,4 +12,8 @@ type User struct {
 
 	MFAEnabled bool   `json:"mfa_enabled"`
 	MFASecret  string `json:"-"`
+
+	// Roles are the effective roles (Role and the roles it inherits), set
+	// in login responses so the UI can tell who counts as an admin.
+	Roles []string `json:"roles,omitempty"`
 }
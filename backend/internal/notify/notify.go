@@ -0,0 +1,160 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Message is a notification to a person or team, e.g. a resource owner.
+type Message struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+
+	// Key identifies repeats of the same event, e.g. one user's access to
+	// one resource, for Background. Empty never counts as a repeat.
+	Key string `json:"-"`
+}
+
+// Notifier delivers messages.
+type Notifier interface {
+	Notify(ctx context.Context, m Message) error
+}
+
+// New returns a Webhook notifier if url is set, and a Log notifier
+// otherwise.
+func New(url string) Notifier {
+	if url == "" {
+		return Log{}
+	}
+	return &Webhook{URL: url, Client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+// Log writes messages to the server log. It is the fallback when no
+// delivery channel is configured.
+type Log struct{}
+
+func (Log) Notify(_ context.Context, m Message) error {
+	log.Printf("notify %s: %s: %s", m.To, m.Subject, m.Body)
+	return nil
+}
+
+// Webhook POSTs each message as JSON, e.g. to a chat or mail relay.
+type Webhook struct {
+	URL    string
+	Client *http.Client
+}
+
+func (h *Webhook) Notify(ctx context.Context, m Message) error {
+	body, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := h.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("notify webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// backgroundQueue bounds the messages waiting for delivery; more are
+// dropped and logged.
+const backgroundQueue = 256
+
+// Background delivers messages from a queue so that callers never wait
+// on the delivery channel, and drops repeats of a Key within Window.
+// Delivery failures are logged.
+type Background struct {
+	Next   Notifier
+	Window time.Duration
+
+	queue chan Message
+
+	mu   sync.Mutex
+	sent map[string]time.Time
+}
+
+// NewBackground starts delivering through next.
+func NewBackground(next Notifier, window time.Duration) *Background {
+	b := &Background{
+		Next:   next,
+		Window: window,
+		queue:  make(chan Message, backgroundQueue),
+		sent:   map[string]time.Time{},
+	}
+	go b.run()
+	if window > 0 {
+		go b.expire()
+	}
+	return b
+}
+
+// Notify queues m and returns at once; it only fails when the queue is
+// full. A message is remembered as sent once it is queued, so a dropped
+// one is not suppressed as a repeat.
+func (b *Background) Notify(_ context.Context, m Message) error {
+	dedupe := m.Key != "" && b.Window > 0
+	now := time.Now()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if dedupe {
+		if t, ok := b.sent[m.Key]; ok && now.Sub(t) < b.Window {
+			return nil
+		}
+	}
+	select {
+	case b.queue <- m:
+		if dedupe {
+			b.sent[m.Key] = now
+		}
+		return nil
+	default:
+		return fmt.Errorf("notification queue is full")
+	}
+}
+
+// expire forgets keys older than Window, once per Window.
+func (b *Background) expire() {
+	t := time.NewTicker(b.Window)
+	defer t.Stop()
+	for now := range t.C {
+		b.forget(now)
+	}
+}
+
+func (b *Background) forget(now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for k, t := range b.sent {
+		if now.Sub(t) >= b.Window {
+			delete(b.sent, k)
+		}
+	}
+}
+
+func (b *Background) run() {
+	for m := range b.queue {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if err := b.Next.Notify(ctx, m); err != nil {
+			log.Printf("notify %s: %v", m.To, err)
+		}
+		cancel()
+	}
+}
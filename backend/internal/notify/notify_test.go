@@ -0,0 +1,66 @@
+package notify
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBackgroundDedupe(t *testing.T) {
+	// No delivery goroutine: the queue only holds one message, so the
+	// test controls when it is full.
+	b := &Background{
+		Window: time.Hour,
+		queue:  make(chan Message, 1),
+		sent:   map[string]time.Time{},
+	}
+	ctx := context.Background()
+
+	if err := b.Notify(ctx, Message{Key: "a"}); err != nil {
+		t.Fatalf("first message: %v", err)
+	}
+	if err := b.Notify(ctx, Message{Key: "a"}); err != nil {
+		t.Fatalf("repeat: %v", err)
+	}
+	if len(b.queue) != 1 {
+		t.Fatalf("queued %d messages, want the repeat dropped", len(b.queue))
+	}
+
+	if err := b.Notify(ctx, Message{Key: "b"}); err == nil {
+		t.Fatal("full queue: want an error")
+	}
+	<-b.queue
+	if err := b.Notify(ctx, Message{Key: "b"}); err != nil {
+		t.Fatalf("retry after a full queue: %v", err)
+	}
+	if len(b.queue) != 1 {
+		t.Fatal("a message dropped on a full queue must not count as sent")
+	}
+
+	<-b.queue
+	if err := b.Notify(ctx, Message{}); err != nil {
+		t.Fatal(err)
+	}
+	<-b.queue
+	if err := b.Notify(ctx, Message{}); err != nil || len(b.queue) != 1 {
+		t.Fatal("messages without a key are never repeats")
+	}
+}
+
+func TestBackgroundForget(t *testing.T) {
+	now := time.Now()
+	b := &Background{
+		Window: time.Hour,
+		sent: map[string]time.Time{
+			"old":   now.Add(-2 * time.Hour),
+			"fresh": now.Add(-time.Minute),
+		},
+	}
+	b.forget(now)
+	if _, ok := b.sent["old"]; ok {
+		t.Error("old key was kept")
+	}
+	if _, ok := b.sent["fresh"]; !ok {
+		t.Error("fresh key was forgotten")
+	}
+}
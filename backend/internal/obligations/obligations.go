@@ -0,0 +1,126 @@
+package obligations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pquerna/otp/totp"
+
+	"zero-trust-access-platform/backend/internal/notify"
+	"zero-trust-access-platform/backend/internal/policy"
+)
+
+// MaxJustificationLength bounds the justification stored in the audit log.
+const MaxJustificationLength = 500
+
+// Request is what the calling endpoint can offer towards a decision's
+// obligations.
+type Request struct {
+	// Justification as typed by the user, if the endpoint accepts one.
+	Justification string
+
+	// SessionDuration is the lifetime of the session the endpoint is about
+	// to create; max_session_duration lowers it. Nil means the endpoint
+	// creates no session and cannot honour a cap.
+	SessionDuration *time.Duration
+
+	// MinSessionDuration is the shortest session the endpoint can create
+	// (AWS STS will not go below 15 minutes); a lower cap is unfulfillable.
+	MinSessionDuration time.Duration
+
+	// MFACode is a one-time code the user sent for a step-up, checked by
+	// VerifyMFA. Nil VerifyMFA means the endpoint cannot ask for a code,
+	// so step_up_mfa cannot be fulfilled.
+	MFACode   string
+	VerifyMFA func(code string) error
+}
+
+// Fulfil carries out the obligations of d for a request described by ac.
+// The returned decision is a deny if any obligation could not be met.
+func Fulfil(ctx context.Context, n notify.Notifier, ac policy.AccessContext, d policy.Decision, req Request) policy.Decision {
+	handlers := map[policy.ObligationType]policy.ObligationHandler{
+		policy.ObligationRequireJustification: func(policy.Obligation) (string, error) {
+			j := strings.TrimSpace(req.Justification)
+			if j == "" {
+				return "", errors.New("a justification is required")
+			}
+			if len(j) > MaxJustificationLength {
+				return "", fmt.Errorf("justification is longer than %d characters", MaxJustificationLength)
+			}
+			return j, nil
+		},
+
+		policy.ObligationNotifyOwner: func(o policy.Obligation) (string, error) {
+			to := o.To
+			if to == "" {
+				to = ac.ResourceAttributes["owner"]
+			}
+			if to == "" {
+				return "", fmt.Errorf("resource %q has no owner attribute", ac.ResourceName)
+			}
+			msg := notify.Message{
+				To:      to,
+				Subject: fmt.Sprintf("Access to %s", ac.ResourceName),
+				Body:    fmt.Sprintf("User %d (%s) was granted %s on %s.", ac.UserID, ac.UserRole, ac.Action, ac.ResourceName),
+				Key:     fmt.Sprintf("%s|%d|%s|%s", to, ac.UserID, ac.ResourceName, ac.Action),
+			}
+			if j := strings.TrimSpace(req.Justification); j != "" {
+				msg.Body += " Justification: " + j
+			}
+			if err := n.Notify(ctx, msg); err != nil {
+				return "", err
+			}
+			return "notified " + to, nil
+		},
+	}
+
+	if req.SessionDuration != nil {
+		handlers[policy.ObligationMaxSessionDuration] = func(o policy.Obligation) (string, error) {
+			if o.Duration < req.MinSessionDuration {
+				return "", fmt.Errorf("sessions cannot be shorter than %s", req.MinSessionDuration)
+			}
+			if o.Duration < *req.SessionDuration {
+				*req.SessionDuration = o.Duration
+			}
+			return "session capped at " + req.SessionDuration.String(), nil
+		}
+	}
+
+	if req.VerifyMFA != nil {
+		handlers[policy.ObligationStepUpMFA] = func(policy.Obligation) (string, error) {
+			code := strings.TrimSpace(req.MFACode)
+			if code == "" {
+				return "", errors.New("an MFA code is required")
+			}
+			if err := req.VerifyMFA(code); err != nil {
+				return "", err
+			}
+			return "MFA code verified", nil
+		}
+	}
+
+	return d.Fulfil(handlers)
+}
+
+// VerifyTOTP returns a Request.VerifyMFA that checks codes against the
+// user's enrolled MFA secret.
+func VerifyTOTP(ctx context.Context, db *sql.DB, userID int64) func(string) error {
+	return func(code string) error {
+		var secret sql.NullString
+		err := db.QueryRowContext(ctx, `SELECT mfa_secret FROM users WHERE id = $1`, userID).Scan(&secret)
+		if err != nil {
+			return err
+		}
+		if !secret.Valid || secret.String == "" {
+			return errors.New("MFA is not enrolled")
+		}
+		if !totp.Validate(code, secret.String) {
+			return errors.New("invalid MFA code")
+		}
+		return nil
+	}
+}
@@ -0,0 +1,516 @@
+package policy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Severity ranks analyzer findings. Findings of SeverityError block the
+// activation of a policy version.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityInfo    Severity = "info"
+)
+
+// Rank orders severities from info (1) to error (3); 0 for unknown ones.
+func (s Severity) Rank() int {
+	switch s {
+	case SeverityError:
+		return 3
+	case SeverityWarning:
+		return 2
+	case SeverityInfo:
+		return 1
+	}
+	return 0
+}
+
+// Finding kinds reported by Analyze.
+const (
+	FindingDefaultAllow        = "default_allow"        // allow without conditions
+	FindingUnreachable         = "unreachable"          // conditions can never all hold
+	FindingShadowed            = "shadowed"             // never decides; an earlier rule always does
+	FindingRedundant           = "redundant"            // shadowed by a rule with the same effect
+	FindingContradiction       = "contradiction"        // allow and deny over exactly the same requests
+	FindingConflict            = "conflict"             // allow and deny overlap at the same priority
+	FindingUnknownAttribute    = "unknown_attribute"    // no user or resource has the attribute
+	FindingUnknownValue        = "unknown_value"        // role, resource or type that does not exist
+	FindingUnmentionedRole     = "unmentioned_role"     // no rule names the role
+	FindingUnmentionedResource = "unmentioned_resource" // no rule names the resource
+)
+
+// Finding is one problem found by Analyze.
+type Finding struct {
+	Severity Severity `json:"severity"`
+	Kind     string   `json:"kind"`
+	Policy   string   `json:"policy,omitempty"`
+	Related  string   `json:"related,omitempty"` // the other policy, for pairwise findings
+	Message  string   `json:"message"`
+}
+
+// Inventory is what exists in the platform, for the checks that need more
+// than the policy document. A nil Inventory skips them.
+type Inventory struct {
+	Roles              []string `json:"roles"`
+	Resources          []string `json:"resources"`
+	ResourceTypes      []string `json:"resource_types"`
+	UserAttributes     []string `json:"user_attributes"`
+	ResourceAttributes []string `json:"resource_attributes"`
+}
+
+// HasErrors reports whether any finding is an error.
+func HasErrors(findings []Finding) bool {
+	for _, f := range findings {
+		if f.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Analyze compiles doc and checks it for rules that can never decide a
+// request, allow/deny pairs that contradict each other and references to
+// things that do not exist. Compile errors are returned as the error.
+//
+// Overlap is decided from eq/ne/in/not_in conditions; other conditions
+// (time windows, networks, contains, value_from) only count as covering
+// another rule when it has the identical condition. Shadowing is checked
+// pairwise, so a rule covered only by several earlier rules together is
+// not reported.
+func Analyze(doc *Document, inv *Inventory) ([]Finding, error) {
+	set, err := Compile(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	a := analysis{set: set}
+	for _, p := range set.policies {
+		a.spaces = append(a.spaces, spaceOf(p))
+	}
+	a.reachability()
+	a.pairs()
+	if inv != nil {
+		a.references(inv)
+	}
+
+	sort.SliceStable(a.findings, func(i, j int) bool {
+		return a.findings[i].Severity.Rank() > a.findings[j].Severity.Rank()
+	})
+	if a.findings == nil {
+		a.findings = []Finding{}
+	}
+	return a.findings, nil
+}
+
+type analysis struct {
+	set      *PolicySet
+	spaces   []space // parallel to set.policies
+	findings []Finding
+}
+
+func (a *analysis) add(sev Severity, kind, policy, related, format string, args ...any) {
+	f := Finding{
+		Severity: sev,
+		Kind:     kind,
+		Policy:   policy,
+		Related:  related,
+		Message:  fmt.Sprintf(format, args...),
+	}
+	for _, seen := range a.findings {
+		if seen == f {
+			return
+		}
+	}
+	a.findings = append(a.findings, f)
+}
+
+func (a *analysis) reachability() {
+	for i, p := range a.set.policies {
+		if p.unconditionalAllow() && !p.spec.Shadow {
+			a.add(SeverityWarning, FindingDefaultAllow, p.spec.Name, "",
+				"%q allows every request no other rule denies; set deny_by_default and replace it with explicit allow policies (see GET /admin/policy/default-allow)", p.spec.Name)
+		}
+		if a.spaces[i].empty() {
+			a.add(SeverityError, FindingUnreachable, p.spec.Name, "",
+				"the conditions of %q can never all hold, so it never matches", p.spec.Name)
+		}
+	}
+}
+
+// pairs looks for shadowed rules and allow/deny contradictions. Shadow
+// policies are never enforced, so they neither shadow nor are shadowed.
+func (a *analysis) pairs() {
+	ps := a.set.policies
+	for j := range ps {
+		b := ps[j]
+		if b.spec.Shadow || a.spaces[j].empty() {
+			continue
+		}
+		for i := range ps {
+			p := ps[i]
+			if i == j || p.spec.Shadow || a.spaces[i].empty() {
+				continue
+			}
+			opposite := p.spec.Effect != b.spec.Effect
+			covers := a.spaces[i].covers(a.spaces[j])
+			covered := a.spaces[j].covers(a.spaces[i])
+
+			if opposite && covers && covered {
+				if i < j {
+					a.add(SeverityError, FindingContradiction, b.spec.Name, p.spec.Name,
+						"%q (%s) and %q (%s) match exactly the same requests",
+						p.spec.Name, p.spec.Effect, b.spec.Name, b.spec.Effect)
+				}
+				continue
+			}
+			if i < j && opposite && !covers && !covered &&
+				p.spec.Priority == b.spec.Priority && a.spaces[i].overlaps(a.spaces[j]) {
+				a.add(SeverityWarning, FindingConflict, b.spec.Name, p.spec.Name,
+					"%q (%s) and %q (%s) have the same priority and overlap; %s",
+					p.spec.Name, p.spec.Effect, b.spec.Name, b.spec.Effect, a.tieBreak())
+			}
+
+			if covers && a.shadows(i, j) {
+				if opposite {
+					a.add(SeverityError, FindingShadowed, b.spec.Name, p.spec.Name,
+						"%q (%s) never decides a request: every request it matches is decided by %q (%s)",
+						b.spec.Name, b.spec.Effect, p.spec.Name, p.spec.Effect)
+				} else {
+					a.add(SeverityWarning, FindingRedundant, b.spec.Name, p.spec.Name,
+						"%q never decides a request: %q matches everything it does with the same effect",
+						b.spec.Name, p.spec.Name)
+				}
+				break
+			}
+		}
+	}
+}
+
+// shadows reports whether policy i, when it matches whatever j matches,
+// keeps j from deciding under the set's algorithm.
+func (a *analysis) shadows(i, j int) bool {
+	p, b := a.set.policies[i].spec, a.set.policies[j].spec
+	switch a.set.algorithm {
+	case DenyOverrides:
+		return p.Effect == EffectDeny && b.Effect == EffectAllow
+	case PermitOverrides:
+		return p.Effect == EffectAllow && b.Effect == EffectDeny
+	default:
+		return i < j // evaluation order: priority, then document order
+	}
+}
+
+func (a *analysis) tieBreak() string {
+	switch a.set.algorithm {
+	case DenyOverrides:
+		return "deny-overrides resolves it, but the intent is unclear"
+	case PermitOverrides:
+		return "permit-overrides resolves it, but the intent is unclear"
+	default:
+		return "only their order in the document decides"
+	}
+}
+
+// references checks conditions against the inventory and reports roles
+// and resources that no rule names.
+func (a *analysis) references(inv *Inventory) {
+	known := map[string]map[string]bool{
+		"user.role":     setOf(inv.Roles),
+		"resource.name": setOf(inv.Resources),
+		"resource.type": setOf(inv.ResourceTypes),
+	}
+	userAttrs, resourceAttrs := setOf(inv.UserAttributes), setOf(inv.ResourceAttributes)
+	mentioned := map[string]map[string]bool{"user.role": {}, "resource.name": {}}
+
+	for _, p := range a.set.policies {
+		name := p.spec.Name
+		for _, c := range p.conds {
+			for _, f := range []string{c.spec.Field, c.spec.ValueFrom} {
+				if attr, ok := strings.CutPrefix(f, "user."); ok && !isBuiltinField(f) && !userAttrs[attr] {
+					a.add(SeverityWarning, FindingUnknownAttribute, name, "",
+						"%q refers to user attribute %q, which no user has", name, attr)
+				}
+				if attr, ok := strings.CutPrefix(f, "resource."); ok && !isBuiltinField(f) && !resourceAttrs[attr] {
+					a.add(SeverityWarning, FindingUnknownAttribute, name, "",
+						"%q refers to resource attribute %q, which no resource has", name, attr)
+				}
+			}
+
+			field := c.spec.Field
+			vals, ok := literalValues(c.spec)
+			if field == "user.roles" {
+				// {field: user.roles, op: contains, value: devops} names a role.
+				field = "user.role"
+				v, isStr := c.spec.Value.(string)
+				vals, ok = []string{v}, isStr && c.spec.ValueFrom == ""
+			}
+			if !ok {
+				continue
+			}
+			if m := mentioned[field]; m != nil {
+				for _, v := range vals {
+					m[v] = true
+				}
+			}
+			if k := known[field]; k != nil {
+				for _, v := range vals {
+					if !k[v] {
+						a.add(SeverityWarning, FindingUnknownValue, name, "",
+							"%q compares %s with %q, which does not exist", name, c.spec.Field, v)
+					}
+				}
+			}
+		}
+	}
+
+	for _, r := range inv.Roles {
+		if !mentioned["user.role"][r] {
+			a.add(SeverityInfo, FindingUnmentionedRole, "", "",
+				"no rule mentions role %q; it is only covered by rules that apply to everyone", r)
+		}
+	}
+	for _, r := range inv.Resources {
+		if !mentioned["resource.name"][r] {
+			a.add(SeverityInfo, FindingUnmentionedResource, "", "",
+				"no rule mentions resource %q; it is only covered by rules that apply to everyone", r)
+		}
+	}
+}
+
+func isBuiltinField(name string) bool {
+	_, ok := fields[name]
+	return ok
+}
+
+// literalValues returns the values an eq/ne/in/not_in condition names.
+func literalValues(c Condition) ([]string, bool) {
+	if c.ValueFrom != "" {
+		return nil, false
+	}
+	switch c.Op {
+	case "", "eq", "ne":
+		s, ok := c.Value.(string)
+		return []string{s}, ok
+	case "in", "not_in":
+		list, _ := c.Value.([]any)
+		var out []string
+		for _, v := range list {
+			if s, ok := v.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out, true
+	}
+	return nil, false
+}
+
+func setOf(list []string) map[string]bool {
+	m := make(map[string]bool, len(list))
+	for _, v := range list {
+		m[v] = true
+	}
+	return m
+}
+
+// valueSet is the set of values a field may take: exactly values when
+// include is set, anything but values otherwise.
+type valueSet struct {
+	include bool
+	values  map[string]bool
+}
+
+// finiteDomains lists the fields whose every possible value is known, so
+// that "anything but" can be turned into a finite set.
+var finiteDomains = map[string][]string{
+	"user.mfa_enabled": {"true", "false"},
+}
+
+func (v valueSet) normalize(field string) valueSet {
+	dom, ok := finiteDomains[field]
+	if !ok || v.include {
+		return v
+	}
+	out := valueSet{include: true, values: map[string]bool{}}
+	for _, d := range dom {
+		if !v.values[d] {
+			out.values[d] = true
+		}
+	}
+	return out
+}
+
+func (v valueSet) intersect(o valueSet) valueSet {
+	out := valueSet{values: map[string]bool{}}
+	switch {
+	case v.include && o.include:
+		out.include = true
+		for k := range v.values {
+			if o.values[k] {
+				out.values[k] = true
+			}
+		}
+	case v.include || o.include:
+		in, ex := v, o
+		if !in.include {
+			in, ex = o, v
+		}
+		out.include = true
+		for k := range in.values {
+			if !ex.values[k] {
+				out.values[k] = true
+			}
+		}
+	default:
+		for k := range v.values {
+			out.values[k] = true
+		}
+		for k := range o.values {
+			out.values[k] = true
+		}
+	}
+	return out
+}
+
+func (v valueSet) empty() bool {
+	return v.include && len(v.values) == 0
+}
+
+// subsetOf reports whether every value in v is also in o.
+func (v valueSet) subsetOf(o valueSet) bool {
+	switch {
+	case v.include && o.include:
+		for k := range v.values {
+			if !o.values[k] {
+				return false
+			}
+		}
+		return true
+	case v.include:
+		for k := range v.values {
+			if o.values[k] {
+				return false
+			}
+		}
+		return true
+	case o.include:
+		return false // v is infinite
+	default:
+		for k := range o.values {
+			if !v.values[k] {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// space approximates the requests a policy matches: a value set per field
+// for the conditions the analyzer understands, plus the others verbatim.
+type space struct {
+	fields map[string]valueSet
+	opaque map[string]bool
+}
+
+func spaceOf(p compiledPolicy) space {
+	s := space{fields: map[string]valueSet{}, opaque: map[string]bool{}}
+	for _, c := range p.conds {
+		vs, ok := conditionSet(c.spec)
+		if !ok {
+			s.opaque[conditionKey(c.spec)] = true
+			continue
+		}
+		if cur, seen := s.fields[c.spec.Field]; seen {
+			vs = cur.intersect(vs)
+		}
+		s.fields[c.spec.Field] = vs.normalize(c.spec.Field)
+	}
+	return s
+}
+
+func conditionSet(c Condition) (valueSet, bool) {
+	if c.ValueFrom != "" {
+		return valueSet{}, false
+	}
+	var vals []string
+	switch c.Op {
+	case "", "eq", "ne":
+		s, err := scalarValue(fieldKindOf(c.Field), c.Value)
+		if err != nil {
+			return valueSet{}, false
+		}
+		vals = []string{s}
+	case "in", "not_in":
+		list, _ := c.Value.([]any)
+		for _, v := range list {
+			s, err := scalarValue(fieldKindOf(c.Field), v)
+			if err != nil {
+				return valueSet{}, false
+			}
+			vals = append(vals, s)
+		}
+	default:
+		return valueSet{}, false
+	}
+	include := c.Op == "" || c.Op == "eq" || c.Op == "in"
+	return valueSet{include: include, values: setOf(vals)}, true
+}
+
+func fieldKindOf(name string) fieldKind {
+	f, _ := lookupField(name)
+	return f.kind
+}
+
+func conditionKey(c Condition) string {
+	return fmt.Sprintf("%s %s %v %s", c.Field, c.Op, c.Value, c.ValueFrom)
+}
+
+func (s space) empty() bool {
+	for _, v := range s.fields {
+		if v.empty() {
+			return true
+		}
+	}
+	return false
+}
+
+// covers reports whether s certainly matches every request o matches.
+func (s space) covers(o space) bool {
+	for f, v := range s.fields {
+		ov, ok := o.fields[f]
+		if !ok {
+			ov = valueSet{values: map[string]bool{}}.normalize(f) // any value
+		}
+		if !ov.subsetOf(v) {
+			return false
+		}
+	}
+	for k := range s.opaque {
+		if !o.opaque[k] {
+			return false
+		}
+	}
+	return true
+}
+
+// overlaps reports whether s and o certainly match some request in common.
+// Differing opaque conditions make the answer unknown, which counts as no.
+func (s space) overlaps(o space) bool {
+	if len(s.opaque) != len(o.opaque) {
+		return false
+	}
+	for k := range s.opaque {
+		if !o.opaque[k] {
+			return false
+		}
+	}
+	for f, v := range s.fields {
+		if ov, ok := o.fields[f]; ok && v.intersect(ov).normalize(f).empty() {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,188 @@
+package policy
+
+import "testing"
+
+func TestAnalyze(t *testing.T) {
+	type want struct {
+		severity Severity
+		kind     string
+		policy   string
+		related  string
+	}
+	tests := []struct {
+		name string
+		src  string
+		want []want
+	}{
+		{
+			name: "deny shadowed by a broader allow",
+			src: `
+policies:
+  - name: allow-admins
+    priority: 50
+    effect: allow
+    reason: admins
+    when:
+      - {field: user.role, op: eq, value: admin}
+  - name: deny-admin-writes
+    priority: 40
+    effect: deny
+    reason: no admin writes
+    when:
+      - {field: user.role, op: eq, value: admin}
+      - {field: action, op: eq, value: write}
+`,
+			want: []want{{SeverityError, FindingShadowed, "deny-admin-writes", "allow-admins"}},
+		},
+		{
+			name: "redundant rule with the same effect",
+			src: `
+policies:
+  - name: deny-users
+    priority: 50
+    effect: deny
+    reason: users
+    when:
+      - {field: user.role, op: in, value: [user, contractor]}
+  - name: deny-contractors
+    priority: 40
+    effect: deny
+    reason: contractors
+    when:
+      - {field: user.role, op: eq, value: contractor}
+`,
+			want: []want{{SeverityWarning, FindingRedundant, "deny-contractors", "deny-users"}},
+		},
+		{
+			name: "deny-overrides: an allow under a deny never decides",
+			src: `
+algorithm: deny-overrides
+policies:
+  - name: allow-reads
+    priority: 90
+    effect: allow
+    reason: reads
+    when:
+      - {field: action, op: eq, value: read}
+      - {field: resource.sensitivity, op: eq, value: high}
+  - name: deny-high
+    priority: 10
+    effect: deny
+    reason: high
+    when:
+      - {field: resource.sensitivity, op: eq, value: high}
+`,
+			want: []want{{SeverityError, FindingShadowed, "allow-reads", "deny-high"}},
+		},
+		{
+			name: "allow and deny over the same requests",
+			src: `
+algorithm: deny-overrides
+policies:
+  - name: allow-wiki
+    priority: 10
+    effect: allow
+    reason: wiki
+    when:
+      - {field: resource.name, op: eq, value: wiki}
+  - name: deny-wiki
+    priority: 20
+    effect: deny
+    reason: wiki
+    when:
+      - {field: resource.name, op: eq, value: wiki}
+`,
+			want: []want{{SeverityError, FindingContradiction, "allow-wiki", "deny-wiki"}},
+		},
+		{
+			name: "overlap at the same priority",
+			src: `
+policies:
+  - name: allow-devops
+    priority: 10
+    effect: allow
+    reason: devops
+    when:
+      - {field: user.role, op: eq, value: devops}
+  - name: deny-writes
+    priority: 10
+    effect: deny
+    reason: writes
+    when:
+      - {field: action, op: eq, value: write}
+`,
+			want: []want{{SeverityWarning, FindingConflict, "deny-writes", "allow-devops"}},
+		},
+		{
+			name: "conditions that cannot all hold",
+			src: `
+policies:
+  - name: never
+    priority: 10
+    effect: deny
+    reason: never
+    when:
+      - {field: action, op: eq, value: read}
+      - {field: action, op: eq, value: write}
+`,
+			want: []want{{SeverityError, FindingUnreachable, "never", ""}},
+		},
+		{
+			name: "disjoint rules",
+			src: `
+policies:
+  - name: deny-writes
+    priority: 10
+    effect: deny
+    reason: writes
+    when:
+      - {field: action, op: eq, value: write}
+  - name: allow-reads
+    priority: 10
+    effect: allow
+    reason: reads
+    when:
+      - {field: action, op: eq, value: read}
+`,
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := ParseDocument([]byte(tt.src))
+			if err != nil {
+				t.Fatal(err)
+			}
+			findings, err := Analyze(doc, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(findings) != len(tt.want) {
+				t.Fatalf("got %d findings %+v, want %d", len(findings), findings, len(tt.want))
+			}
+			for i, w := range tt.want {
+				f := findings[i]
+				if f.Severity != w.severity || f.Kind != w.kind || f.Policy != w.policy || f.Related != w.related {
+					t.Errorf("finding %d = %+v, want %+v", i, f, w)
+				}
+			}
+			if HasErrors(findings) != (len(tt.want) > 0 && tt.want[0].severity == SeverityError) {
+				t.Errorf("HasErrors = %v", HasErrors(findings))
+			}
+		})
+	}
+}
+
+func TestAnalyzeDefaults(t *testing.T) {
+	doc, err := ParseDocument(DefaultSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	findings, err := Analyze(doc, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if HasErrors(findings) {
+		t.Fatalf("the default policies have errors: %+v", findings)
+	}
+}
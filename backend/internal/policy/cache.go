@@ -0,0 +1,197 @@
+package policy
+
+import (
+	"container/list"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// cache, when set, memoises Evaluate. See EnableCache.
+var cache atomic.Pointer[decisionCache]
+
+// decisionCache is a bounded LRU of decisions keyed by the context fields
+// the active policies read, with the time truncated to the minute
+// (schedules have minute resolution), so users with the same role asking
+// for the same resource share an entry. Other engines see the whole
+// context, so with one installed the key is the full context. Role and
+// attribute changes therefore never hit a stale entry; anything else that
+// changes decisions (policies, engines, zones) must call InvalidateCache.
+type decisionCache struct {
+	ttl time.Duration
+	max int
+
+	mu    sync.Mutex
+	gen   uint64 // bumped by invalidate; stale evaluations are not stored
+	order *list.List
+	items map[string]*list.Element
+
+	hits, misses, evictions, invalidations atomic.Int64
+}
+
+type cacheEntry struct {
+	key     string
+	d       Decision
+	expires time.Time
+}
+
+// CacheStats reports decision cache effectiveness.
+type CacheStats struct {
+	Enabled       bool    `json:"enabled"`
+	Size          int     `json:"size"`
+	Capacity      int     `json:"capacity"`
+	TTLSeconds    float64 `json:"ttl_seconds"`
+	Hits          int64   `json:"hits"`
+	Misses        int64   `json:"misses"`
+	HitRatio      float64 `json:"hit_ratio"`
+	Evictions     int64   `json:"evictions"`
+	Invalidations int64   `json:"invalidations"`
+}
+
+// EnableCache caches up to size decisions for ttl each; size <= 0 or
+// ttl <= 0 disables caching.
+func EnableCache(size int, ttl time.Duration) {
+	if size <= 0 || ttl <= 0 {
+		cache.Store(nil)
+		return
+	}
+	cache.Store(&decisionCache{
+		ttl:   ttl,
+		max:   size,
+		order: list.New(),
+		items: map[string]*list.Element{},
+	})
+}
+
+// InvalidateCache drops every cached decision.
+func InvalidateCache() {
+	if c := cache.Load(); c != nil {
+		c.invalidate()
+	}
+}
+
+// Cache returns the decision cache statistics.
+func Cache() CacheStats {
+	c := cache.Load()
+	if c == nil {
+		return CacheStats{}
+	}
+	c.mu.Lock()
+	size := c.order.Len()
+	c.mu.Unlock()
+
+	st := CacheStats{
+		Enabled:       true,
+		Size:          size,
+		Capacity:      c.max,
+		TTLSeconds:    c.ttl.Seconds(),
+		Hits:          c.hits.Load(),
+		Misses:        c.misses.Load(),
+		Evictions:     c.evictions.Load(),
+		Invalidations: c.invalidations.Load(),
+	}
+	if total := st.Hits + st.Misses; total > 0 {
+		st.HitRatio = float64(st.Hits) / float64(total)
+	}
+	return st
+}
+
+// cachedEvaluate returns a cached decision for ctx or computes and stores
+// it with eval.
+func (c *decisionCache) cachedEvaluate(ctx AccessContext, eval func(AccessContext) Decision) Decision {
+	now := time.Now()
+	c.mu.Lock()
+	// The key depends on the active set, so it is built after reading gen:
+	// a set swapped in meanwhile invalidates and the result is not stored.
+	gen := c.gen
+	key, ok := cacheKey(ctx)
+	if !ok {
+		c.mu.Unlock()
+		return eval(ctx)
+	}
+	if el, ok := c.items[key]; ok {
+		e := el.Value.(*cacheEntry)
+		if now.Before(e.expires) {
+			c.order.MoveToFront(el)
+			d := e.d
+			c.mu.Unlock()
+			c.hits.Add(1)
+			return d.clone()
+		}
+		c.order.Remove(el)
+		delete(c.items, key)
+	}
+	c.mu.Unlock()
+	c.misses.Add(1)
+
+	d := eval(ctx)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.gen != gen {
+		return d
+	}
+	if el, ok := c.items[key]; ok {
+		c.order.Remove(el)
+		delete(c.items, key)
+	}
+	c.items[key] = c.order.PushFront(&cacheEntry{key: key, d: d.clone(), expires: now.Add(c.ttl)})
+	for c.order.Len() > c.max {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*cacheEntry).key)
+		c.evictions.Add(1)
+	}
+	return d
+}
+
+func (c *decisionCache) invalidate() {
+	c.mu.Lock()
+	c.gen++
+	c.order.Init()
+	c.items = map[string]*list.Element{}
+	c.mu.Unlock()
+	c.invalidations.Add(1)
+}
+
+func cacheKey(ctx AccessContext) (string, bool) {
+	ctx.Time = contextTime(&ctx).UTC().Truncate(time.Minute)
+	if engine.Load() == nil {
+		return active.Load().cacheKey(&ctx)
+	}
+	b, err := json.Marshal(ctx) // map keys are sorted, so this is stable
+	if err != nil {
+		return "", false
+	}
+	return string(b), true
+}
+
+// cacheKey lists the values of the fields s reads, in s.refs order.
+func (s *PolicySet) cacheKey(ctx *AccessContext) (string, bool) {
+	vals := make([]string, len(s.refs))
+	for i, f := range s.refs {
+		vals[i] = f.get(ctx)
+	}
+	b, err := json.Marshal(vals)
+	if err != nil {
+		return "", false
+	}
+	return string(b), true
+}
+
+// clone copies the slices a caller could modify so cached decisions stay
+// intact.
+func (d Decision) clone() Decision {
+	if d.Policies != nil {
+		d.Policies = append([]string{}, d.Policies...)
+	}
+	if d.Obligations != nil {
+		d.Obligations = append([]Obligation(nil), d.Obligations...)
+	}
+	if d.Shadow != nil {
+		s := *d.Shadow
+		d.Shadow = &s
+	}
+	return d
+}
@@ -0,0 +1,182 @@
+package policy
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestCache(size int, ttl time.Duration) *decisionCache {
+	EnableCache(size, ttl)
+	c := cache.Load()
+	EnableCache(0, 0)
+	return c
+}
+
+func TestCacheKey(t *testing.T) {
+	set := mustCompile(t, `
+policies:
+  - name: office-hours
+    priority: 10
+    effect: allow
+    reason: ok
+    when:
+      - {field: user.role, op: eq, value: user}
+      - {field: user.department, op: eq, value: sre}
+      - {field: resource.name, op: eq, value: wiki}
+      - {field: request.ip, op: in_cidr, value: 10.0.0.0/8}
+      - {field: risk.score, op: lt, value: 50}
+      - {field: threat.listed, op: eq, value: false}
+      - {field: time, op: within, value: {days: [mon, tue, wed, thu, fri], from: "09:00", to: "17:00"}}
+`)
+	at := time.Date(2026, 10, 19, 10, 0, 5, 0, time.UTC)
+	base := AccessContext{UserID: 1, UserRole: "user", ResourceName: "wiki", Action: "read", Time: at}
+	key := func(c AccessContext) string {
+		c.Time = c.Time.Truncate(time.Minute)
+		k, ok := set.cacheKey(&c)
+		if !ok {
+			t.Fatal("no key")
+		}
+		return k
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(*AccessContext)
+		changes bool
+	}{
+		{name: "same minute", modify: func(c *AccessContext) { c.Time = at.Add(50 * time.Second) }},
+		{name: "next minute", modify: func(c *AccessContext) { c.Time = at.Add(time.Minute) }, changes: true},
+		{name: "role", modify: func(c *AccessContext) { c.UserRole = "admin" }, changes: true},
+		{name: "referenced attribute", modify: func(c *AccessContext) { c.UserAttributes = map[string]string{"department": "sre"} }, changes: true},
+		{name: "resource", modify: func(c *AccessContext) { c.ResourceName = "billing" }, changes: true},
+		{name: "risk", modify: func(c *AccessContext) { c.RiskScore = 80 }, changes: true},
+		{name: "threat intel", modify: func(c *AccessContext) { c.ThreatIntel = &ThreatIntel{Feed: "drop"} }, changes: true},
+		{name: "client IP", modify: func(c *AccessContext) { c.ClientIP = "10.0.0.1" }, changes: true},
+		{name: "user ID", modify: func(c *AccessContext) { c.UserID = 2 }},
+		{name: "other attribute", modify: func(c *AccessContext) { c.UserAttributes = map[string]string{"clearance": "secret"} }},
+		{name: "action", modify: func(c *AccessContext) { c.Action = "write" }},
+		{name: "signals", modify: func(c *AccessContext) { c.Signals = map[string]any{"hr.status": "active"} }},
+	}
+	for _, tt := range tests {
+		c := base
+		tt.modify(&c)
+		if changed := key(c) != key(base); changed != tt.changes {
+			t.Errorf("%s: key changed = %v, want %v", tt.name, changed, tt.changes)
+		}
+	}
+}
+
+func TestCacheSharedAcrossUsers(t *testing.T) {
+	EnableCache(10, time.Hour)
+	t.Cleanup(func() { EnableCache(0, 0) })
+
+	now := time.Now()
+	for i, ip := range []string{"192.0.2.1", "198.51.100.7"} {
+		Evaluate(AccessContext{
+			UserID:       int64(i + 1),
+			UserRole:     "user",
+			ResourceName: "wiki",
+			Sensitivity:  "low",
+			Action:       "read",
+			ClientIP:     ip,
+			Time:         now,
+		})
+	}
+	if st := Cache(); st.Size != 1 || st.Hits != 1 || st.Misses != 1 {
+		t.Fatalf("size=%d hits=%d misses=%d, want one shared entry", st.Size, st.Hits, st.Misses)
+	}
+}
+
+func TestCacheTTLAndInvalidation(t *testing.T) {
+	calls := 0
+	eval := func(AccessContext) Decision {
+		calls++
+		return Decision{Allowed: true, Policy: "p", Policies: []string{"p"}}
+	}
+	ac := AccessContext{UserRole: "user", Action: "read", Time: time.Now()}
+
+	c := newTestCache(10, time.Hour)
+	c.cachedEvaluate(ac, eval)
+	d := c.cachedEvaluate(ac, eval)
+	if calls != 1 || c.hits.Load() != 1 || c.misses.Load() != 1 {
+		t.Fatalf("calls=%d hits=%d misses=%d, want one evaluation and one hit", calls, c.hits.Load(), c.misses.Load())
+	}
+	d.Policies[0] = "changed"
+	if d := c.cachedEvaluate(ac, eval); d.Policies[0] != "p" {
+		t.Fatal("a caller modified the cached decision")
+	}
+
+	c.invalidate()
+	c.cachedEvaluate(ac, eval)
+	if calls != 2 {
+		t.Fatalf("calls = %d after invalidate, want 2", calls)
+	}
+
+	short := newTestCache(10, time.Nanosecond)
+	calls = 0
+	short.cachedEvaluate(ac, eval)
+	time.Sleep(time.Millisecond)
+	short.cachedEvaluate(ac, eval)
+	if calls != 2 {
+		t.Fatalf("calls = %d with an expired entry, want 2", calls)
+	}
+}
+
+func TestCacheSkipsStaleEvaluations(t *testing.T) {
+	c := newTestCache(10, time.Hour)
+	ac := AccessContext{UserRole: "user", Action: "read", Time: time.Now()}
+
+	// The policies change while the decision is being made: the old
+	// decision is returned but not kept.
+	c.cachedEvaluate(ac, func(AccessContext) Decision {
+		c.invalidate()
+		return Decision{Policy: "old"}
+	})
+	if d := c.cachedEvaluate(ac, func(AccessContext) Decision { return Decision{Policy: "new"} }); d.Policy != "new" {
+		t.Fatalf("Policy = %q, want a fresh evaluation", d.Policy)
+	}
+}
+
+func TestCacheEviction(t *testing.T) {
+	c := newTestCache(2, time.Hour)
+	eval := func(ac AccessContext) Decision { return Decision{Policy: ac.Sensitivity} }
+	now := time.Now()
+	for _, name := range []string{"a", "b", "a", "c"} {
+		c.cachedEvaluate(AccessContext{Sensitivity: name, Time: now}, eval)
+	}
+	if c.order.Len() != 2 || c.evictions.Load() != 1 {
+		t.Fatalf("size=%d evictions=%d, want 2 and 1", c.order.Len(), c.evictions.Load())
+	}
+	for name, kept := range map[string]bool{"a": true, "b": false, "c": true} {
+		k, _ := cacheKey(AccessContext{Sensitivity: name, Time: now})
+		if _, ok := c.items[k]; ok != kept {
+			t.Errorf("%s cached = %v, want %v (least recently used goes first)", name, ok, kept)
+		}
+	}
+}
+
+func TestPolicyChangesInvalidateCache(t *testing.T) {
+	EnableCache(10, time.Hour)
+	t.Cleanup(func() {
+		EnableCache(0, 0)
+		SetActive(mustCompile(t, string(DefaultSource)))
+		SetRoles(BuiltinRoles)
+	})
+
+	changes := []struct {
+		name   string
+		change func()
+	}{
+		{name: "SetActive", change: func() { SetActive(mustCompile(t, string(DefaultSource))) }},
+		{name: "SetRoles", change: func() { SetRoles(BuiltinRoles) }},
+		{name: "SetZones", change: func() { SetZones(nil) }},
+		{name: "SetEngine", change: func() { SetEngine(nil, false) }},
+	}
+	for _, tt := range changes {
+		before := Cache().Invalidations
+		tt.change()
+		if Cache().Invalidations != before+1 {
+			t.Errorf("%s did not invalidate the cache", tt.name)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Algorithm decides how the policies matching a request are combined.
+type Algorithm string
+
+const (
+	// FirstApplicable: the highest-priority matching policy decides.
+	FirstApplicable Algorithm = "first-applicable"
+	// DenyOverrides: any matching deny wins; otherwise any matching allow.
+	DenyOverrides Algorithm = "deny-overrides"
+	// PermitOverrides: any matching allow wins; otherwise any matching deny.
+	PermitOverrides Algorithm = "permit-overrides"
+)
+
+func parseAlgorithm(s string) (Algorithm, error) {
+	switch a := Algorithm(s); a {
+	case "":
+		return FirstApplicable, nil
+	case FirstApplicable, DenyOverrides, PermitOverrides:
+		return a, nil
+	}
+	return "", fmt.Errorf("algorithm must be %q, %q or %q", FirstApplicable, DenyOverrides, PermitOverrides)
+}
+
+// combine turns the matching policies (in priority order) into a decision.
+// Every policy with the winning effect contributes; the first of them is
+// reported as the primary Policy.
+func (s *PolicySet) combine(ctx *AccessContext, matched []*compiledPolicy) Decision {
+	if len(matched) == 0 {
+		return Decision{
+			Allowed:  false,
+			Policy:   "implicit-deny",
+			Policies: []string{},
+			Reason:   "no matching allow policy",
+			Version:  s.Version,
+		}
+	}
+
+	var contributing []*compiledPolicy
+	switch s.algorithm {
+	case DenyOverrides, PermitOverrides:
+		preferred := EffectDeny
+		if s.algorithm == PermitOverrides {
+			preferred = EffectAllow
+		}
+		contributing = withEffect(matched, preferred)
+		if len(contributing) == 0 {
+			contributing = matched // all have the other effect
+		}
+	default:
+		contributing = matched[:1]
+	}
+
+	d := Decision{
+		Allowed: contributing[0].spec.Effect == EffectAllow,
+		Policy:  contributing[0].spec.Name,
+		Version: s.Version,
+	}
+	reasons := make([]string, 0, len(contributing))
+	var obligations []Obligation
+	for _, p := range contributing {
+		d.Policies = append(d.Policies, p.spec.Name)
+		reasons = append(reasons, p.reason(ctx))
+		obligations = append(obligations, p.obligations...)
+	}
+	d.Reason = strings.Join(reasons, "; ")
+	if d.Allowed {
+		d.Obligations = mergeObligations(obligations)
+	}
+	return d
+}
+
+func withEffect(list []*compiledPolicy, e Effect) []*compiledPolicy {
+	var out []*compiledPolicy
+	for _, p := range list {
+		if p.spec.Effect == e {
+			out = append(out, p)
+		}
+	}
+	return out
+}
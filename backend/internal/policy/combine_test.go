@@ -0,0 +1,81 @@
+package policy
+
+import (
+	"reflect"
+	"testing"
+)
+
+// combinePolicies has an allow and a deny that both match writes, at
+// different priorities, plus a lower allow for reads.
+const combinePolicies = `
+policies:
+  - name: deny-writes
+    priority: 50
+    effect: deny
+    reason: no writes
+    when:
+      - {field: action, op: eq, value: write}
+  - name: allow-devops
+    priority: 60
+    effect: allow
+    reason: devops may
+    when:
+      - {field: user.role, op: eq, value: devops}
+    advice:
+      - {type: notify_owner, to: ops@example.com}
+  - name: allow-reads
+    priority: 10
+    effect: allow
+    reason: anyone may read
+    when:
+      - {field: action, op: eq, value: read}
+`
+
+func TestCombiningAlgorithms(t *testing.T) {
+	tests := []struct {
+		algorithm    string
+		role, action string
+		wantAllowed  bool
+		wantPolicies []string
+	}{
+		{algorithm: "first-applicable", role: "devops", action: "write", wantAllowed: true, wantPolicies: []string{"allow-devops"}},
+		{algorithm: "first-applicable", role: "user", action: "write", wantPolicies: []string{"deny-writes"}},
+		{algorithm: "deny-overrides", role: "devops", action: "write", wantPolicies: []string{"deny-writes"}},
+		{algorithm: "deny-overrides", role: "devops", action: "read", wantAllowed: true, wantPolicies: []string{"allow-devops", "allow-reads"}},
+		{algorithm: "permit-overrides", role: "devops", action: "write", wantAllowed: true, wantPolicies: []string{"allow-devops"}},
+		{algorithm: "permit-overrides", role: "user", action: "write", wantPolicies: []string{"deny-writes"}},
+		{algorithm: "permit-overrides", role: "user", action: "assume", wantPolicies: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.algorithm+"/"+tt.role+"/"+tt.action, func(t *testing.T) {
+			set := mustCompile(t, "algorithm: "+tt.algorithm+"\n"+combinePolicies)
+			d := set.Evaluate(AccessContext{UserRole: tt.role, Action: tt.action})
+			if d.Allowed != tt.wantAllowed || !reflect.DeepEqual(d.Policies, tt.wantPolicies) {
+				t.Fatalf("got allowed=%v by %v, want allowed=%v by %v", d.Allowed, d.Policies, tt.wantAllowed, tt.wantPolicies)
+			}
+			if len(d.Policies) > 0 && d.Policy != d.Policies[0] {
+				t.Errorf("Policy = %q, want the first of %v", d.Policy, d.Policies)
+			}
+			if !d.Allowed && len(d.Obligations) != 0 {
+				t.Errorf("deny carries obligations %v", d.Obligations)
+			}
+		})
+	}
+
+	t.Run("contributing reasons and advice are merged", func(t *testing.T) {
+		set := mustCompile(t, "algorithm: deny-overrides\n"+combinePolicies)
+		d := set.Evaluate(AccessContext{UserRole: "devops", Action: "read"})
+		if d.Reason != "devops may; anyone may read" {
+			t.Errorf("Reason = %q", d.Reason)
+		}
+		if len(d.Obligations) != 1 || d.Obligations[0].Type != ObligationNotifyOwner || !d.Obligations[0].Advice {
+			t.Errorf("Obligations = %+v, want the notify_owner advice", d.Obligations)
+		}
+	})
+
+	t.Run("unknown algorithm", func(t *testing.T) {
+		if _, err := CompileSource([]byte("algorithm: most-recent\n" + combinePolicies)); err == nil {
+			t.Fatal("want a compile error")
+		}
+	})
+}
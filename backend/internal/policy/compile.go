@@ -0,0 +1,487 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type fieldKind int
+
+const (
+	kindString fieldKind = iota
+	kindBool
+	kindInt
+	kindTime
+	kindIP
+	kindAny // signal values: the condition's value decides
+)
+
+// field describes an AccessContext value that conditions may refer to.
+// Values are compared in their canonical string form.
+type field struct {
+	kind fieldKind
+	get  func(*AccessContext) string
+}
+
+var fields = map[string]field{
+	"user.id": {kindInt, func(c *AccessContext) string {
+		return strconv.FormatInt(c.UserID, 10)
+	}},
+	"user.role": {kindString, func(c *AccessContext) string {
+		return c.UserRole
+	}},
+	// Comma-separated effective roles and permissions, for
+	// contains/not_contains: {field: user.roles, op: contains, value: devops}
+	// also matches admins, since admin inherits devops.
+	"user.roles":       {kindString, userRoles},
+	"user.permissions": {kindString, userPermissions},
+	"user.mfa_enabled": {kindBool, func(c *AccessContext) string {
+		return strconv.FormatBool(c.MFAEnabled)
+	}},
+	"resource.name": {kindString, func(c *AccessContext) string {
+		return c.ResourceName
+	}},
+	"resource.type": {kindString, func(c *AccessContext) string {
+		return c.ResourceType
+	}},
+	"resource.sensitivity": {kindString, func(c *AccessContext) string {
+		return c.Sensitivity
+	}},
+	"action": {kindString, func(c *AccessContext) string {
+		return c.Action
+	}},
+	"request.ip": {kindIP, func(c *AccessContext) string {
+		return c.ClientIP
+	}},
+	"time": {kindTime, func(c *AccessContext) string {
+		return contextTime(c).Format(time.RFC3339)
+	}},
+	"threat.listed": {kindBool, func(c *AccessContext) string {
+		return strconv.FormatBool(c.ThreatIntel != nil)
+	}},
+	"threat.feed": {kindString, func(c *AccessContext) string {
+		if c.ThreatIntel == nil {
+			return ""
+		}
+		return c.ThreatIntel.Feed
+	}},
+	"threat.category": {kindString, func(c *AccessContext) string {
+		if c.ThreatIntel == nil {
+			return ""
+		}
+		return c.ThreatIntel.Category
+	}},
+	"risk.score": {kindInt, func(c *AccessContext) string {
+		return strconv.Itoa(c.RiskScore)
+	}},
+	// Comma-separated signal names, for contains/not_contains.
+	"risk.signals": {kindString, func(c *AccessContext) string {
+		names := make([]string, len(c.RiskSignals))
+		for i, sig := range c.RiskSignals {
+			names[i] = sig.Name
+		}
+		return strings.Join(names, ",")
+	}},
+}
+
+// attrName is what may follow "user." or "resource." to name an attribute.
+var attrName = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// lookupField resolves a condition field. Built-in fields take precedence;
+// any other "user.<name>" or "resource.<name>" refers to an attribute, which
+// reads as "" when the user or resource does not have it, and
+// "signal.<provider>.<name>" to a signal, likewise.
+func lookupField(name string) (field, bool) {
+	if f, ok := fields[name]; ok {
+		return f, true
+	}
+	if attr, ok := strings.CutPrefix(name, "user."); ok && attrName.MatchString(attr) {
+		return field{kindString, func(c *AccessContext) string {
+			return c.UserAttributes[attr]
+		}}, true
+	}
+	if attr, ok := strings.CutPrefix(name, "resource."); ok && attrName.MatchString(attr) {
+		return field{kindString, func(c *AccessContext) string {
+			return c.ResourceAttributes[attr]
+		}}, true
+	}
+	if key, ok := strings.CutPrefix(name, "signal."); ok && signalKey.MatchString(key) {
+		return field{kindAny, func(c *AccessContext) string {
+			v, _ := canonicalValue(c.Signals[key])
+			return v
+		}}, true
+	}
+	return field{}, false
+}
+
+// signalKey is what may follow "signal.": <provider>.<name>.
+var signalKey = regexp.MustCompile(`^[a-z][a-z0-9_]*\.[a-z][a-z0-9_]*$`)
+
+// ValidateAttributeKey checks that key can be stored as an attribute of
+// scope ("user" or "resource") and referenced as <scope>.<key> without
+// being shadowed by a built-in field.
+func ValidateAttributeKey(scope, key string) error {
+	if !attrName.MatchString(key) {
+		return fmt.Errorf("attribute key %q must be lower_snake_case", key)
+	}
+	if _, builtin := fields[scope+"."+key]; builtin {
+		return fmt.Errorf("attribute key %q is reserved", key)
+	}
+	return nil
+}
+
+// PolicySet is a validated, compiled policy document ready for evaluation.
+type PolicySet struct {
+	// Version is the policy_versions row this set was compiled from, or 0
+	// for the built-in defaults. It is copied into every Decision.
+	Version int64
+
+	algorithm     Algorithm
+	denyByDefault bool
+	policies      []compiledPolicy
+	hasShadow     bool
+
+	// refs are the fields the conditions read, sorted. Decisions depend on
+	// nothing else in the context, so they are the decision cache key.
+	refs []field
+}
+
+// DenyByDefault reports whether the set was compiled with deny_by_default.
+func (s *PolicySet) DenyByDefault() bool {
+	return s.denyByDefault
+}
+
+type compiledPolicy struct {
+	spec        PolicySpec
+	conds       []compiledCondition
+	obligations []Obligation // including advice
+}
+
+type compiledCondition struct {
+	spec  Condition
+	match func(*AccessContext) bool
+
+	// get and getOther read the compared values for traces; getOther is
+	// only set for field-to-field comparisons (ValueFrom).
+	get, getOther func(*AccessContext) string
+
+	// explain, if set, describes why the condition matched; it is appended
+	// to the decision reason (used by time windows and network checks).
+	explain func(*AccessContext) string
+}
+
+// Compile validates a document and turns it into a PolicySet. All problems
+// found are reported together so an admin can fix them in one pass.
+func Compile(doc *Document) (*PolicySet, error) {
+	var errs []error
+	seen := map[string]bool{}
+	refs := map[string]bool{}
+
+	set := &PolicySet{}
+	alg, err := parseAlgorithm(doc.Algorithm)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	set.algorithm = alg
+	set.denyByDefault = doc.DenyByDefault
+
+	for i, p := range doc.Policies {
+		where := fmt.Sprintf("policies[%d]", i)
+		if p.Name != "" {
+			where = fmt.Sprintf("policies[%d] %q", i, p.Name)
+		}
+
+		if p.Name == "" {
+			errs = append(errs, fmt.Errorf("%s: name is required", where))
+		} else if seen[p.Name] {
+			errs = append(errs, fmt.Errorf("%s: duplicate policy name", where))
+		}
+		seen[p.Name] = true
+
+		if p.Effect != EffectAllow && p.Effect != EffectDeny {
+			errs = append(errs, fmt.Errorf("%s: effect must be %q or %q", where, EffectAllow, EffectDeny))
+		}
+		if p.Reason == "" {
+			errs = append(errs, fmt.Errorf("%s: reason is required", where))
+		}
+
+		cp := compiledPolicy{spec: p}
+		for j, c := range p.When {
+			cc, err := compileCondition(c)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: when[%d]: %w", where, j, err))
+				continue
+			}
+			cp.conds = append(cp.conds, cc)
+			refs[c.Field] = true
+			if c.ValueFrom != "" {
+				refs[c.ValueFrom] = true
+			}
+		}
+
+		if doc.DenyByDefault && p.Effect == EffectAllow && len(p.When) == 0 {
+			errs = append(errs, fmt.Errorf("%s: allow policies need conditions when deny_by_default is set", where))
+		}
+		if (len(p.Obligations) > 0 || len(p.Advice) > 0) && p.Effect != EffectAllow {
+			errs = append(errs, fmt.Errorf("%s: obligations and advice are only valid on allow policies", where))
+		}
+		for j, o := range p.Obligations {
+			co, err := CompileObligation(o, false)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: obligations[%d]: %w", where, j, err))
+				continue
+			}
+			cp.obligations = append(cp.obligations, co)
+		}
+		for j, o := range p.Advice {
+			co, err := CompileObligation(o, true)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: advice[%d]: %w", where, j, err))
+				continue
+			}
+			cp.obligations = append(cp.obligations, co)
+		}
+		set.policies = append(set.policies, cp)
+		set.hasShadow = set.hasShadow || p.Shadow
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(set.policies, func(a, b int) bool {
+		return set.policies[a].spec.Priority > set.policies[b].spec.Priority
+	})
+	names := make([]string, 0, len(refs))
+	for name := range refs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		f, _ := lookupField(name)
+		set.refs = append(set.refs, f)
+	}
+	return set, nil
+}
+
+// unconditionalAllow reports whether p allows every request that reaches
+// it, like the default-allow policy of the built-in set.
+func (p *compiledPolicy) unconditionalAllow() bool {
+	return p.spec.Effect == EffectAllow && len(p.conds) == 0
+}
+
+func compileCondition(c Condition) (compiledCondition, error) {
+	cc := compiledCondition{spec: c}
+
+	f, ok := lookupField(c.Field)
+	if !ok {
+		return cc, fmt.Errorf("unknown field %q", c.Field)
+	}
+	cc.get = f.get
+
+	op := c.Op
+	if op == "" {
+		op = "eq"
+	}
+
+	if c.ValueFrom != "" {
+		return compileFieldComparison(cc, f, op)
+	}
+
+	if f.kind == kindTime {
+		if op != "within" && op != "not_within" {
+			return cc, fmt.Errorf("field %q only supports ops \"within\" and \"not_within\"", c.Field)
+		}
+		m, explain, err := compileTimeCondition(op, c.Value)
+		if err != nil {
+			return cc, fmt.Errorf("field %q: %w", c.Field, err)
+		}
+		cc.match, cc.explain = m, explain
+		return cc, nil
+	}
+
+	if f.kind == kindIP {
+		m, explain, err := compileNetworkCondition(op, c.Value)
+		if err != nil {
+			return cc, fmt.Errorf("field %q: %w", c.Field, err)
+		}
+		cc.match, cc.explain = m, explain
+		return cc, nil
+	}
+
+	switch op {
+	case "eq", "ne":
+		want, err := scalarValue(f.kind, c.Value)
+		if err != nil {
+			return cc, fmt.Errorf("field %q: %w", c.Field, err)
+		}
+		neg := op == "ne"
+		cc.match = func(ctx *AccessContext) bool {
+			return (f.get(ctx) == want) != neg
+		}
+
+	case "in", "not_in":
+		list, ok := c.Value.([]any)
+		if !ok || len(list) == 0 {
+			return cc, fmt.Errorf("op %q needs a non-empty list value", op)
+		}
+		want := make(map[string]bool, len(list))
+		for _, v := range list {
+			s, err := scalarValue(f.kind, v)
+			if err != nil {
+				return cc, fmt.Errorf("field %q: %w", c.Field, err)
+			}
+			want[s] = true
+		}
+		neg := op == "not_in"
+		cc.match = func(ctx *AccessContext) bool {
+			return want[f.get(ctx)] != neg
+		}
+
+	case "contains", "not_contains":
+		// For list-valued attributes stored comma-separated, e.g. tags.
+		if f.kind != kindString && f.kind != kindAny {
+			return cc, fmt.Errorf("op %q needs a string field", op)
+		}
+		want, err := scalarValue(f.kind, c.Value)
+		if err != nil {
+			return cc, fmt.Errorf("field %q: %w", c.Field, err)
+		}
+		neg := op == "not_contains"
+		cc.match = func(ctx *AccessContext) bool {
+			return listContains(f.get(ctx), want) != neg
+		}
+
+	case "gt", "ge", "lt", "le":
+		// e.g. {field: risk.score, op: gt, value: 70}
+		if f.kind != kindInt && f.kind != kindAny {
+			return cc, fmt.Errorf("op %q needs an integer field", op)
+		}
+		s, err := scalarValue(kindInt, c.Value)
+		if err != nil {
+			return cc, fmt.Errorf("field %q: %w", c.Field, err)
+		}
+		want, _ := strconv.ParseInt(s, 10, 64)
+		cc.match = func(ctx *AccessContext) bool {
+			n, err := strconv.ParseInt(f.get(ctx), 10, 64)
+			if err != nil {
+				return false
+			}
+			switch op {
+			case "gt":
+				return n > want
+			case "ge":
+				return n >= want
+			case "lt":
+				return n < want
+			default:
+				return n <= want
+			}
+		}
+
+	default:
+		return cc, fmt.Errorf("unknown op %q for field %q", op, c.Field)
+	}
+	return cc, nil
+}
+
+// compileFieldComparison handles {field: a, op: eq|ne, value_from: b}.
+// Two missing values are not equal: eq requires both sides to be present,
+// and ne is its negation, so a missing attribute never grants access via eq
+// and always trips a deny via ne.
+func compileFieldComparison(cc compiledCondition, f field, op string) (compiledCondition, error) {
+	c := cc.spec
+	if c.Value != nil {
+		return cc, errors.New("value and value_from are mutually exclusive")
+	}
+	other, ok := lookupField(c.ValueFrom)
+	if !ok {
+		return cc, fmt.Errorf("unknown field %q in value_from", c.ValueFrom)
+	}
+	if f.kind != other.kind || f.kind == kindTime || f.kind == kindIP {
+		return cc, fmt.Errorf("fields %q and %q cannot be compared", c.Field, c.ValueFrom)
+	}
+	if op != "eq" && op != "ne" {
+		return cc, fmt.Errorf("op %q is not supported with value_from (use eq or ne)", op)
+	}
+
+	neg := op == "ne"
+	cc.getOther = other.get
+	cc.match = func(ctx *AccessContext) bool {
+		a, b := f.get(ctx), other.get(ctx)
+		return (a != "" && a == b) != neg
+	}
+	return cc, nil
+}
+
+// scalarValue checks v against the field's kind and returns its canonical
+// string form.
+func scalarValue(kind fieldKind, v any) (string, error) {
+	switch kind {
+	case kindBool:
+		b, ok := v.(bool)
+		if !ok {
+			return "", fmt.Errorf("value %v is not a boolean", v)
+		}
+		return strconv.FormatBool(b), nil
+	case kindInt:
+		switch n := v.(type) {
+		case int:
+			return strconv.Itoa(n), nil
+		case int64:
+			return strconv.FormatInt(n, 10), nil
+		case float64:
+			if n == float64(int64(n)) {
+				return strconv.FormatInt(int64(n), 10), nil
+			}
+		}
+		return "", fmt.Errorf("value %v is not an integer", v)
+	case kindAny:
+		s, ok := canonicalValue(v)
+		if !ok {
+			return "", fmt.Errorf("value %v is not a string, boolean or integer", v)
+		}
+		return s, nil
+	default:
+		s, ok := v.(string)
+		if !ok {
+			return "", fmt.Errorf("value %v is not a string", v)
+		}
+		return s, nil
+	}
+}
+
+// canonicalValue is the string form of a signal value or of a value
+// compared with one. Integers read back from JSON as float64 compare equal
+// to the integers they were.
+func canonicalValue(v any) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case bool:
+		return strconv.FormatBool(v), true
+	case int:
+		return strconv.Itoa(v), true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case float64:
+		if v == float64(int64(v)) {
+			return strconv.FormatInt(int64(v), 10), true
+		}
+	}
+	return "", false
+}
+
+func listContains(list, want string) bool {
+	for _, v := range strings.Split(list, ",") {
+		if strings.TrimSpace(v) == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,137 @@
+package policy
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxDefaultAllowGroups caps how many groups a report lists. The counts
+// always cover every record.
+const maxDefaultAllowGroups = 500
+
+// DefaultAllowGroup is a role/resource/action combination that is only
+// allowed because of an unconditional allow policy, with an explicit allow
+// policy that would keep it allowed under deny_by_default.
+type DefaultAllowGroup struct {
+	Role      string     `json:"role"`
+	Resource  string     `json:"resource"`
+	Action    string     `json:"action"`
+	Policy    string     `json:"policy"` // the unconditional allow that decided
+	Count     int        `json:"count"`
+	Users     int        `json:"users"`
+	LastSeen  time.Time  `json:"last_seen"`
+	Suggested PolicySpec `json:"suggested"`
+}
+
+// DefaultAllowReport lists the logged requests the set only allows through
+// an unconditional allow policy: the ones that would be denied once the
+// set switches to deny_by_default and those policies are removed.
+type DefaultAllowReport struct {
+	DenyByDefault bool                `json:"deny_by_default"`
+	Evaluated     int                 `json:"evaluated"`
+	Relying       int                 `json:"relying"`
+	Groups        []DefaultAllowGroup `json:"groups"`
+	Truncated     bool                `json:"truncated"`
+}
+
+// DefaultAllowScan builds a DefaultAllowReport one logged record at a time.
+type DefaultAllowScan struct {
+	set, strict *PolicySet
+	report      DefaultAllowReport
+	groups      map[[3]string]*DefaultAllowGroup
+	users       map[[3]string]map[int64]bool
+}
+
+func NewDefaultAllowScan(set *PolicySet) *DefaultAllowScan {
+	strict := *set
+	strict.denyByDefault = true
+	strict.policies = nil
+	for _, p := range set.policies {
+		if !p.unconditionalAllow() {
+			strict.policies = append(strict.policies, p)
+		}
+	}
+	return &DefaultAllowScan{
+		set:    set,
+		strict: &strict,
+		report: DefaultAllowReport{DenyByDefault: set.denyByDefault, Groups: []DefaultAllowGroup{}},
+		groups: map[[3]string]*DefaultAllowGroup{},
+		users:  map[[3]string]map[int64]bool{},
+	}
+}
+
+// Add evaluates a logged request against the set with and without its
+// unconditional allow policies.
+func (s *DefaultAllowScan) Add(rec ReplayRecord) {
+	s.report.Evaluated++
+
+	d := s.set.Evaluate(rec.Context)
+	if !d.Allowed || s.strict.Evaluate(rec.Context).Allowed {
+		return
+	}
+	s.report.Relying++
+
+	ac := rec.Context
+	key := [3]string{ac.UserRole, ac.ResourceName, ac.Action}
+	g := s.groups[key]
+	if g == nil {
+		if len(s.groups) >= maxDefaultAllowGroups {
+			s.report.Truncated = true
+			return
+		}
+		g = &DefaultAllowGroup{
+			Role:      ac.UserRole,
+			Resource:  ac.ResourceName,
+			Action:    ac.Action,
+			Policy:    d.Policy,
+			Suggested: suggestAllow(ac),
+		}
+		s.groups[key] = g
+		s.users[key] = map[int64]bool{}
+	}
+	g.Count++
+	s.users[key][ac.UserID] = true
+	g.Users = len(s.users[key])
+	if rec.CreatedAt.After(g.LastSeen) {
+		g.LastSeen = rec.CreatedAt
+	}
+}
+
+// Report returns the groups, most frequent first.
+func (s *DefaultAllowScan) Report() DefaultAllowReport {
+	r := s.report
+	r.Groups = make([]DefaultAllowGroup, 0, len(s.groups))
+	for _, g := range s.groups {
+		r.Groups = append(r.Groups, *g)
+	}
+	sort.Slice(r.Groups, func(i, j int) bool {
+		a, b := r.Groups[i], r.Groups[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Role+a.Resource+a.Action < b.Role+b.Resource+b.Action
+	})
+	return r
+}
+
+var nonNameChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// suggestAllow is the narrowest explicit allow for a role/resource/action.
+func suggestAllow(ac AccessContext) PolicySpec {
+	slug := strings.Trim(nonNameChars.ReplaceAllString(
+		strings.ToLower(ac.UserRole+"-"+ac.Action+"-"+ac.ResourceName), "-"), "-")
+	return PolicySpec{
+		Name:     "allow-" + slug,
+		Priority: 1,
+		Effect:   EffectAllow,
+		Reason:   fmt.Sprintf("%s may %s %s", ac.UserRole, ac.Action, ac.ResourceName),
+		When: []Condition{
+			{Field: "user.role", Op: "eq", Value: ac.UserRole},
+			{Field: "resource.name", Op: "eq", Value: ac.ResourceName},
+			{Field: "action", Op: "eq", Value: ac.Action},
+		},
+	}
+}
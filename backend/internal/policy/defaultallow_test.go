@@ -0,0 +1,47 @@
+package policy
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDenyByDefault(t *testing.T) {
+	t.Run("unconditional allow is rejected", func(t *testing.T) {
+		_, err := CompileSource([]byte(`
+deny_by_default: true
+policies:
+  - {name: everything, priority: 0, effect: allow, reason: ok}
+`))
+		if err == nil || !strings.Contains(err.Error(), "need conditions") {
+			t.Fatalf("err = %v, want a conditions error", err)
+		}
+	})
+
+	set := mustCompile(t, `
+deny_by_default: true
+policies:
+  - name: reads
+    priority: 10
+    effect: allow
+    reason: reads are fine
+    when:
+      - {field: action, op: eq, value: read}
+`)
+	if !set.DenyByDefault() {
+		t.Fatal("DenyByDefault() = false")
+	}
+	tests := []struct {
+		action      string
+		wantAllowed bool
+		wantPolicy  string
+	}{
+		{action: "read", wantAllowed: true, wantPolicy: "reads"},
+		{action: "write", wantPolicy: "implicit-deny"},
+	}
+	for _, tt := range tests {
+		d := set.Evaluate(AccessContext{Action: tt.action})
+		if d.Allowed != tt.wantAllowed || d.Policy != tt.wantPolicy {
+			t.Errorf("%s: got allowed=%v by %s, want allowed=%v by %s", tt.action, d.Allowed, d.Policy, tt.wantAllowed, tt.wantPolicy)
+		}
+	}
+}
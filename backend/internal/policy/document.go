@@ -0,0 +1,83 @@
+package policy
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Effect is what a matching policy does to the request.
+type Effect string
+
+const (
+	EffectAllow Effect = "allow"
+	EffectDeny  Effect = "deny"
+)
+
+// Document is the declarative form of a policy set, as stored in Postgres
+// and shipped in default_policies.yaml. It can be written as YAML or JSON.
+type Document struct {
+	// Algorithm combines the matching policies: first-applicable (the
+	// default), deny-overrides or permit-overrides.
+	Algorithm string `json:"algorithm,omitempty" yaml:"algorithm,omitempty"`
+	// DenyByDefault forbids allow policies without conditions, so every
+	// allowed request is allowed by a policy that names what it allows.
+	// Requests no policy matches are always denied.
+	DenyByDefault bool         `json:"deny_by_default,omitempty" yaml:"deny_by_default,omitempty"`
+	Policies      []PolicySpec `json:"policies" yaml:"policies"`
+}
+
+// PolicySpec is a single named policy. Policies are ordered from the
+// highest Priority down (ties keep document order); which of the matching
+// ones decide the request depends on the document's Algorithm.
+//
+// A Shadow policy is evaluated but not enforced: its would-be outcome is
+// reported in Decision.Shadow so it can be observed before promotion.
+//
+// Allow policies may carry Obligations, which the calling code must
+// fulfil for the allow to hold, and Advice, which it fulfils if it can.
+type PolicySpec struct {
+	Name        string           `json:"name" yaml:"name"`
+	Priority    int              `json:"priority" yaml:"priority"`
+	Effect      Effect           `json:"effect" yaml:"effect"`
+	Reason      string           `json:"reason" yaml:"reason"`
+	Shadow      bool             `json:"shadow,omitempty" yaml:"shadow,omitempty"`
+	When        []Condition      `json:"when,omitempty" yaml:"when,omitempty"`
+	Obligations []ObligationSpec `json:"obligations,omitempty" yaml:"obligations,omitempty"`
+	Advice      []ObligationSpec `json:"advice,omitempty" yaml:"advice,omitempty"`
+}
+
+// Condition compares an AccessContext field against a value, or against
+// another field named by ValueFrom, e.g.
+//
+//	{field: resource.sensitivity, op: eq, value: high}
+//	{field: user.role, op: in, value: [admin, devops]}
+//	{field: user.department, op: eq, value_from: resource.owner_team}
+//	{field: risk.score, op: gt, value: 70}
+//
+// An empty Op means "eq"; gt, ge, lt and le compare integer fields.
+type Condition struct {
+	Field     string `json:"field" yaml:"field"`
+	Op        string `json:"op,omitempty" yaml:"op,omitempty"`
+	Value     any    `json:"value,omitempty" yaml:"value,omitempty"`
+	ValueFrom string `json:"value_from,omitempty" yaml:"value_from,omitempty"`
+}
+
+// ParseDocument decodes a YAML or JSON policy document. It does not validate
+// the policies; use Compile for that.
+func ParseDocument(src []byte) (*Document, error) {
+	var doc Document
+	if err := yaml.Unmarshal(src, &doc); err != nil {
+		return nil, fmt.Errorf("parse policy document: %w", err)
+	}
+	return &doc, nil
+}
+
+// CompileSource parses and compiles a YAML or JSON policy document.
+func CompileSource(src []byte) (*PolicySet, error) {
+	doc, err := ParseDocument(src)
+	if err != nil {
+		return nil, err
+	}
+	return Compile(doc)
+}
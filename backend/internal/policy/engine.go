@@ -1,7 +1,177 @@
 package policy
 
+import (
+	_ "embed"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultSource is the built-in policy set, equivalent to the rules that
+// used to be hard-coded in Go.
+//
+//go:embed default_policies.yaml
+var DefaultSource []byte
+
+// active is the policy set used by Evaluate. It starts as the default set
+// and is replaced by SetActive when policies are loaded or edited.
+var active atomic.Pointer[PolicySet]
+
+func init() {
+	set, err := CompileSource(DefaultSource)
+	if err != nil {
+		panic("invalid default policy set: " + err.Error())
+	}
+	active.Store(set)
+}
+
+// Engine is a policy evaluation backend. The built-in one is the active
+// PolicySet; others (e.g. Rego) are installed with SetEngine.
+type Engine interface {
+	Name() string
+	Evaluate(ctx AccessContext) Decision
+}
+
+// BuiltinEngine is the Name of the built-in engine.
+const BuiltinEngine = "builtin"
+
+type engineState struct {
+	engine  Engine
+	compare bool
+}
+
+// engine, when set, replaces the active PolicySet in Evaluate.
+var engine atomic.Pointer[engineState]
+
 // Evaluate is the single entry point for authorization decisions.
 // All access control must pass through this function.
 func Evaluate(ctx AccessContext) Decision {
-	return evaluateRules(ctx)
+	ResolveRoles(&ctx)
+	if c := cache.Load(); c != nil {
+		return c.cachedEvaluate(ctx, evaluate)
+	}
+	return evaluate(ctx)
+}
+
+func evaluate(ctx AccessContext) Decision {
+	st := engine.Load()
+	if st == nil {
+		return timedEvaluate(active.Load(), ctx)
+	}
+
+	d := timedEvaluate(st.engine, ctx)
+	if st.compare {
+		// Evaluated for timing and agreement only; never enforced.
+		if bd := timedEvaluate(active.Load(), ctx); bd.Allowed != d.Allowed {
+			recordDisagreement(st.engine.Name())
+		}
+	}
+	return d
+}
+
+func timedEvaluate(e Engine, ctx AccessContext) Decision {
+	start := time.Now()
+	d := e.Evaluate(ctx)
+	recordTiming(e.Name(), time.Since(start))
+	return d
+}
+
+// ActiveSet is the policy set used by the built-in engine.
+func ActiveSet() *PolicySet {
+	return active.Load()
+}
+
+// SetActive swaps the policy set used by the built-in engine.
+func SetActive(set *PolicySet) {
+	active.Store(set)
+	InvalidateCache()
+}
+
+// SetEngine makes e the engine used by Evaluate; nil restores the built-in
+// engine. With compare set, the built-in engine also runs on every request
+// so the two can be compared in Timings.
+func SetEngine(e Engine, compare bool) {
+	if e == nil {
+		engine.Store(nil)
+	} else {
+		engine.Store(&engineState{engine: e, compare: compare})
+	}
+	InvalidateCache()
+}
+
+// ActiveEngine is the Name of the engine used by Evaluate.
+func ActiveEngine() string {
+	if st := engine.Load(); st != nil {
+		return st.engine.Name()
+	}
+	return BuiltinEngine
+}
+
+// Name implements Engine.
+func (s *PolicySet) Name() string {
+	return BuiltinEngine
+}
+
+// Evaluate finds every enforced policy whose conditions all match and
+// combines them with the set's algorithm. If none matches the request is
+// denied.
+//
+// When the set contains shadow policies, the decision the set would make
+// with them enforced is attached as Decision.Shadow; it never affects
+// Allowed.
+func (s *PolicySet) Evaluate(ctx AccessContext) Decision {
+	d := s.decide(&ctx, false)
+	if s.hasShadow {
+		sd := s.decide(&ctx, true)
+		d.Shadow = &ShadowDecision{
+			Allowed: sd.Allowed,
+			Policy:  sd.Policy,
+			Reason:  sd.Reason,
+		}
+	}
+	return d
+}
+
+// decide collects the matching policies and combines them. Shadow policies
+// are skipped unless includeShadow is set.
+func (s *PolicySet) decide(ctx *AccessContext, includeShadow bool) Decision {
+	var matched []*compiledPolicy
+	for i := range s.policies {
+		p := &s.policies[i]
+		if p.spec.Shadow && !includeShadow {
+			continue
+		}
+		if p.matches(ctx) {
+			matched = append(matched, p)
+			if s.algorithm == FirstApplicable {
+				break // nothing below can change the outcome
+			}
+		}
+	}
+	return s.combine(ctx, matched)
+}
+
+// reason is the policy's reason, followed by an explanation of any
+// conditions that describe themselves, e.g.
+// "contractors only work hours (Sat 2026-10-17 10:02 is outside mon,tue,wed,thu,fri 08:00–18:00 Europe/Berlin)".
+func (p *compiledPolicy) reason(ctx *AccessContext) string {
+	var notes []string
+	for _, c := range p.conds {
+		if c.explain != nil {
+			notes = append(notes, c.explain(ctx))
+		}
+	}
+	if len(notes) == 0 {
+		return p.spec.Reason
+	}
+	return p.spec.Reason + " (" + strings.Join(notes, "; ") + ")"
+}
+
+func (p *compiledPolicy) matches(ctx *AccessContext) bool {
+	for _, c := range p.conds {
+		if !c.match(ctx) {
+			return false
+		}
+	}
+	return true
 }
@@ -0,0 +1,79 @@
+package policy
+
+import "testing"
+
+func mustCompile(t *testing.T, src string) *PolicySet {
+	t.Helper()
+	set, err := CompileSource([]byte(src))
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+	return set
+}
+
+func TestShadowPolicies(t *testing.T) {
+	set := mustCompile(t, `
+policies:
+  - name: block-writes
+    priority: 50
+    effect: deny
+    reason: writes are frozen
+    shadow: true
+    when:
+      - {field: action, op: eq, value: write}
+  - name: default-allow
+    priority: 0
+    effect: allow
+    reason: ok
+`)
+	tests := []struct {
+		action     string
+		wantShadow ShadowDecision
+	}{
+		{action: "write", wantShadow: ShadowDecision{Allowed: false, Policy: "block-writes", Reason: "writes are frozen"}},
+		{action: "read", wantShadow: ShadowDecision{Allowed: true, Policy: "default-allow", Reason: "ok"}},
+	}
+	for _, tt := range tests {
+		d := set.Evaluate(AccessContext{Action: tt.action})
+		if !d.Allowed || d.Policy != "default-allow" {
+			t.Errorf("%s: shadow policy was enforced: allowed=%v by %s", tt.action, d.Allowed, d.Policy)
+		}
+		if d.Shadow == nil || *d.Shadow != tt.wantShadow {
+			t.Errorf("%s: Shadow = %+v, want %+v", tt.action, d.Shadow, tt.wantShadow)
+		}
+	}
+
+	plain := mustCompile(t, "policies:\n  - {name: default-allow, priority: 0, effect: allow, reason: ok}\n")
+	if d := plain.Evaluate(AccessContext{Action: "read"}); d.Shadow != nil {
+		t.Errorf("set without shadow policies reported Shadow = %+v", d.Shadow)
+	}
+}
+
+type denyEngine struct{}
+
+func (denyEngine) Name() string { return "deny" }
+
+func (denyEngine) Evaluate(AccessContext) Decision {
+	return Decision{Allowed: false, Reason: "denied by deny"}
+}
+
+func TestExplainUsesActiveEngine(t *testing.T) {
+	ctx := AccessContext{Action: "read", UserRole: "admin"}
+	if tr := Explain(ctx); len(tr.Policies) == 0 {
+		t.Fatalf("builtin engine should trace its policies")
+	}
+
+	SetEngine(denyEngine{}, false)
+	defer SetEngine(nil, false)
+
+	if ActiveEngine() != "deny" {
+		t.Fatalf("ActiveEngine() = %q", ActiveEngine())
+	}
+	tr := Explain(ctx)
+	if tr.Decision.Allowed || tr.Decision.Reason != "denied by deny" {
+		t.Errorf("Explain decision = %+v, want the engine's", tr.Decision)
+	}
+	if len(tr.Policies) != 0 {
+		t.Errorf("Explain traced %d builtin policies under another engine", len(tr.Policies))
+	}
+}
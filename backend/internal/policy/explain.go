@@ -0,0 +1,92 @@
+package policy
+
+// Trace is a full account of how a policy set reached a decision.
+type Trace struct {
+	Decision Decision      `json:"decision"`
+	Policies []PolicyTrace `json:"policies"`
+}
+
+// PolicyTrace reports one policy in evaluation order. Every policy is
+// evaluated, even those that cannot change the outcome, so admins can see what
+// would have matched. Contributing marks the policies listed in
+// Decision.Policies; shadow policies never contribute.
+type PolicyTrace struct {
+	Name         string           `json:"name"`
+	Priority     int              `json:"priority"`
+	Effect       Effect           `json:"effect"`
+	Reason       string           `json:"reason"`
+	Shadow       bool             `json:"shadow"`
+	Matched      bool             `json:"matched"`
+	Contributing bool             `json:"contributing"`
+	Conditions   []ConditionTrace `json:"conditions"`
+}
+
+// ConditionTrace reports one condition and the context value it saw.
+type ConditionTrace struct {
+	Field       string `json:"field"`
+	Op          string `json:"op"`
+	Value       any    `json:"value,omitempty"`
+	ValueFrom   string `json:"value_from,omitempty"`
+	Actual      string `json:"actual"`
+	ActualOther string `json:"actual_other,omitempty"` // value of ValueFrom
+	Matched     bool   `json:"matched"`
+}
+
+// Explain evaluates ctx with the engine Evaluate uses and returns the
+// decision together with its trace. Only the built-in engine can trace its
+// policies; for any other engine Policies is empty. It has no side effects.
+func Explain(ctx AccessContext) Trace {
+	if st := engine.Load(); st != nil {
+		return Trace{Decision: st.engine.Evaluate(ctx), Policies: []PolicyTrace{}}
+	}
+	return active.Load().Explain(ctx)
+}
+
+// Explain is Evaluate with a full evaluation trace.
+func (s *PolicySet) Explain(ctx AccessContext) Trace {
+	t := Trace{
+		Decision: s.Evaluate(ctx),
+		Policies: make([]PolicyTrace, 0, len(s.policies)),
+	}
+
+	contributing := map[string]bool{}
+	for _, name := range t.Decision.Policies {
+		contributing[name] = true
+	}
+
+	for _, p := range s.policies {
+		pt := PolicyTrace{
+			Name:       p.spec.Name,
+			Priority:   p.spec.Priority,
+			Effect:     p.spec.Effect,
+			Reason:     p.reason(&ctx),
+			Shadow:     p.spec.Shadow,
+			Matched:    true,
+			Conditions: make([]ConditionTrace, 0, len(p.conds)),
+		}
+		for _, c := range p.conds {
+			ok := c.match(&ctx)
+			op := c.spec.Op
+			if op == "" {
+				op = "eq"
+			}
+			ct := ConditionTrace{
+				Field:     c.spec.Field,
+				Op:        op,
+				Value:     c.spec.Value,
+				ValueFrom: c.spec.ValueFrom,
+				Actual:    c.get(&ctx),
+				Matched:   ok,
+			}
+			if c.getOther != nil {
+				ct.ActualOther = c.getOther(&ctx)
+			}
+			pt.Conditions = append(pt.Conditions, ct)
+			pt.Matched = pt.Matched && ok
+		}
+		pt.Contributing = !pt.Shadow && contributing[pt.Name]
+		t.Policies = append(t.Policies, pt)
+	}
+
+	return t
+}
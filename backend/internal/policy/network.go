@@ -0,0 +1,128 @@
+package policy
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+)
+
+// zones holds the named network zones referenced by in_zone conditions.
+// Zones are loaded separately from policies so they can be edited and
+// reloaded without a new policy version.
+var zones atomic.Pointer[map[string][]netip.Prefix]
+
+// SetZones replaces the named network zones used by in_zone / not_in_zone.
+func SetZones(z map[string][]netip.Prefix) {
+	zones.Store(&z)
+	InvalidateCache()
+}
+
+func zonePrefixes(name string) []netip.Prefix {
+	z := zones.Load()
+	if z == nil {
+		return nil
+	}
+	return (*z)[name]
+}
+
+// compileNetworkCondition builds matchers for the request.ip field:
+//
+//	{field: request.ip, op: in_zone, value: corp-vpn}          // or a list of zones
+//	{field: request.ip, op: not_in_cidr, value: [10.0.0.0/8]}  // literal CIDRs
+//
+// Zones are resolved at evaluation time; an unknown zone contains nothing.
+// A missing or unparsable client IP is in no zone and no CIDR.
+func compileNetworkCondition(op string, v any) (func(*AccessContext) bool, func(*AccessContext) string, error) {
+	names, err := stringList(v)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var contains func(netip.Addr) bool
+	var what string
+
+	switch op {
+	case "in_zone", "not_in_zone":
+		what = "zone " + strings.Join(names, " or ")
+		contains = func(addr netip.Addr) bool {
+			for _, n := range names {
+				if prefixesContain(zonePrefixes(n), addr) {
+					return true
+				}
+			}
+			return false
+		}
+
+	case "in_cidr", "not_in_cidr":
+		prefixes := make([]netip.Prefix, 0, len(names))
+		for _, n := range names {
+			p, err := netip.ParsePrefix(n)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid CIDR %q", n)
+			}
+			prefixes = append(prefixes, p.Masked())
+		}
+		what = strings.Join(names, ", ")
+		contains = func(addr netip.Addr) bool {
+			return prefixesContain(prefixes, addr)
+		}
+
+	default:
+		return nil, nil, fmt.Errorf("unknown op %q (use in_zone, not_in_zone, in_cidr or not_in_cidr)", op)
+	}
+
+	in := func(ctx *AccessContext) bool {
+		addr, err := netip.ParseAddr(ctx.ClientIP)
+		return err == nil && contains(addr.Unmap())
+	}
+
+	neg := strings.HasPrefix(op, "not_")
+	match := func(ctx *AccessContext) bool {
+		return in(ctx) != neg
+	}
+	explain := func(ctx *AccessContext) string {
+		ip := ctx.ClientIP
+		if ip == "" {
+			ip = "unknown client IP"
+		}
+		rel := "not in"
+		if in(ctx) {
+			rel = "in"
+		}
+		return fmt.Sprintf("%s is %s %s", ip, rel, what)
+	}
+	return match, explain, nil
+}
+
+func prefixesContain(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, p := range prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// stringList accepts a single string or a non-empty list of strings.
+func stringList(v any) ([]string, error) {
+	switch x := v.(type) {
+	case string:
+		if x != "" {
+			return []string{x}, nil
+		}
+	case []any:
+		out := make([]string, 0, len(x))
+		for _, e := range x {
+			s, ok := e.(string)
+			if !ok || s == "" {
+				return nil, fmt.Errorf("value %v is not a string", e)
+			}
+			out = append(out, s)
+		}
+		if len(out) > 0 {
+			return out, nil
+		}
+	}
+	return nil, fmt.Errorf("value must be a string or a non-empty list of strings")
+}
@@ -0,0 +1,99 @@
+package policy
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestNetworkCondition(t *testing.T) {
+	SetZones(map[string][]netip.Prefix{
+		"corp-vpn": {netip.MustParsePrefix("10.8.0.0/16")},
+		"office":   {netip.MustParsePrefix("192.0.2.0/24"), netip.MustParsePrefix("2001:db8::/32")},
+	})
+	t.Cleanup(func() { SetZones(nil) })
+
+	tests := []struct {
+		name  string
+		op    string
+		value any
+		ip    string
+		want  bool
+	}{
+		{name: "in zone", op: "in_zone", value: "corp-vpn", ip: "10.8.1.2", want: true},
+		{name: "outside zone", op: "in_zone", value: "corp-vpn", ip: "10.9.1.2", want: false},
+		{name: "any zone of a list", op: "in_zone", value: []any{"corp-vpn", "office"}, ip: "192.0.2.7", want: true},
+		{name: "IPv6 in zone", op: "in_zone", value: "office", ip: "2001:db8::1", want: true},
+		{name: "IPv4-mapped IPv6 in zone", op: "in_zone", value: "corp-vpn", ip: "::ffff:10.8.1.2", want: true},
+		{name: "unknown zone contains nothing", op: "in_zone", value: "nowhere", ip: "10.8.1.2", want: false},
+		{name: "not in unknown zone", op: "not_in_zone", value: "nowhere", ip: "10.8.1.2", want: true},
+		{name: "not in zone", op: "not_in_zone", value: "corp-vpn", ip: "10.8.1.2", want: false},
+		{name: "in CIDR", op: "in_cidr", value: []any{"10.0.0.0/8"}, ip: "10.1.2.3", want: true},
+		{name: "unmasked CIDR", op: "in_cidr", value: "10.1.2.3/8", ip: "10.200.0.1", want: true},
+		{name: "outside CIDR", op: "in_cidr", value: "10.0.0.0/8", ip: "172.16.0.1", want: false},
+		{name: "IPv4-mapped IPv6 in CIDR", op: "in_cidr", value: "10.0.0.0/8", ip: "::ffff:10.1.2.3", want: true},
+		{name: "not in CIDR", op: "not_in_cidr", value: "10.0.0.0/8", ip: "172.16.0.1", want: true},
+		{name: "missing IP is in nothing", op: "in_cidr", value: "0.0.0.0/0", ip: "", want: false},
+		{name: "unparsable IP is in nothing", op: "in_zone", value: "corp-vpn", ip: "10.8.1.2:443", want: false},
+		{name: "missing IP is not in a CIDR", op: "not_in_cidr", value: "10.0.0.0/8", ip: "", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match, _, err := compileNetworkCondition(tt.op, tt.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := match(&AccessContext{ClientIP: tt.ip}); got != tt.want {
+				t.Errorf("%s %v for %q = %v, want %v", tt.op, tt.value, tt.ip, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNetworkConditionErrors(t *testing.T) {
+	tests := []struct {
+		op    string
+		value any
+	}{
+		{op: "in_cidr", value: "10.0.0.0"},
+		{op: "in_cidr", value: []any{"10.0.0.0/8", "nonsense"}},
+		{op: "in_zone", value: ""},
+		{op: "in_zone", value: []any{}},
+		{op: "in_zone", value: []any{"corp-vpn", 7}},
+		{op: "in_subnet", value: "10.0.0.0/8"},
+	}
+	for _, tt := range tests {
+		if _, _, err := compileNetworkCondition(tt.op, tt.value); err == nil {
+			t.Errorf("%s %v compiled, want an error", tt.op, tt.value)
+		}
+	}
+}
+
+func TestNetworkConditionZoneReload(t *testing.T) {
+	t.Cleanup(func() { SetZones(nil) })
+	match, explain, err := compileNetworkCondition("in_zone", "corp-vpn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := &AccessContext{ClientIP: "10.8.1.2"}
+
+	SetZones(nil)
+	if match(ctx) {
+		t.Fatal("matched before the zone existed")
+	}
+
+	SetZones(map[string][]netip.Prefix{"corp-vpn": {netip.MustParsePrefix("10.8.0.0/16")}})
+	if !match(ctx) {
+		t.Fatal("compiled condition did not pick up the new zone")
+	}
+	if got, want := explain(ctx), "10.8.1.2 is in zone corp-vpn"; got != want {
+		t.Errorf("explain = %q, want %q", got, want)
+	}
+
+	SetZones(map[string][]netip.Prefix{"corp-vpn": {netip.MustParsePrefix("10.9.0.0/16")}})
+	if match(ctx) {
+		t.Fatal("still matched after the zone moved")
+	}
+	if got, want := explain(&AccessContext{}), "unknown client IP is not in zone corp-vpn"; got != want {
+		t.Errorf("explain = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,182 @@
+package policy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ObligationType names something the caller must do for an allow to hold.
+type ObligationType string
+
+const (
+	// ObligationMaxSessionDuration caps the lifetime of whatever the
+	// request creates (e.g. an AWS console session) at Duration.
+	ObligationMaxSessionDuration ObligationType = "max_session_duration"
+	// ObligationRequireJustification needs a free-text justification from
+	// the user, which is recorded in the audit log.
+	ObligationRequireJustification ObligationType = "require_justification"
+	// ObligationNotifyOwner tells the resource owner (or To) about the
+	// access.
+	ObligationNotifyOwner ObligationType = "notify_owner"
+	// ObligationStepUpMFA needs a fresh MFA code with the request, e.g.
+	// for a request the risk engine scored as suspicious.
+	ObligationStepUpMFA ObligationType = "step_up_mfa"
+)
+
+// obligationOrder is the order obligations are fulfilled in: notifications
+// go last so nobody is told about an access that then fails.
+var obligationOrder = map[ObligationType]int{
+	ObligationMaxSessionDuration:   0,
+	ObligationStepUpMFA:            1,
+	ObligationRequireJustification: 2,
+	ObligationNotifyOwner:          3,
+}
+
+// ObligationSpec is the declarative form of an obligation or advice, e.g.
+//
+//	{type: max_session_duration, duration: 15m}
+//	{type: require_justification}
+//	{type: step_up_mfa}
+//	{type: notify_owner, to: security@example.com}
+type ObligationSpec struct {
+	Type     ObligationType `json:"type" yaml:"type"`
+	Duration string         `json:"duration,omitempty" yaml:"duration,omitempty"`
+	To       string         `json:"to,omitempty" yaml:"to,omitempty"`
+}
+
+// Obligation is attached to an allow decision. Obligations must be
+// fulfilled by the caller or the decision becomes a deny (see Fulfil);
+// advice is fulfilled on a best-effort basis.
+type Obligation struct {
+	Type     ObligationType
+	Duration time.Duration // max_session_duration
+	To       string        // notify_owner; empty means the resource owner
+	Advice   bool
+
+	// Set by Fulfil.
+	Fulfilled bool
+	Detail    string // what was done, e.g. the justification given
+}
+
+func (o Obligation) MarshalJSON() ([]byte, error) {
+	out := struct {
+		Type      ObligationType `json:"type"`
+		Duration  string         `json:"duration,omitempty"`
+		To        string         `json:"to,omitempty"`
+		Advice    bool           `json:"advice,omitempty"`
+		Fulfilled bool           `json:"fulfilled"`
+		Detail    string         `json:"detail,omitempty"`
+	}{o.Type, "", o.To, o.Advice, o.Fulfilled, o.Detail}
+	if o.Duration > 0 {
+		out.Duration = o.Duration.String()
+	}
+	return json.Marshal(out)
+}
+
+// CompileObligation validates an obligation (or, with advice set, an
+// advice) spec. It is used by the policy compiler and by other engines.
+func CompileObligation(spec ObligationSpec, advice bool) (Obligation, error) {
+	o := Obligation{Type: spec.Type, To: spec.To, Advice: advice}
+	if _, ok := obligationOrder[spec.Type]; !ok {
+		return o, fmt.Errorf("unknown type %q", spec.Type)
+	}
+	if (spec.Type == ObligationMaxSessionDuration) != (spec.Duration != "") {
+		return o, errors.New("duration is required for, and only valid with, max_session_duration")
+	}
+	if spec.Duration != "" {
+		d, err := time.ParseDuration(spec.Duration)
+		if err != nil || d <= 0 {
+			return o, fmt.Errorf("invalid duration %q", spec.Duration)
+		}
+		o.Duration = d
+	}
+	if spec.To != "" && spec.Type != ObligationNotifyOwner {
+		return o, errors.New("to is only valid with notify_owner")
+	}
+	return o, nil
+}
+
+// mergeObligations combines the obligations of every contributing policy:
+// the shortest session cap wins and duplicates are dropped.
+func mergeObligations(list []Obligation) []Obligation {
+	var out []Obligation
+	for _, o := range list {
+		dup := false
+		for i := range out {
+			if out[i].Type != o.Type || out[i].Advice != o.Advice || out[i].To != o.To {
+				continue
+			}
+			if o.Duration > 0 && o.Duration < out[i].Duration {
+				out[i].Duration = o.Duration
+			}
+			dup = true
+			break
+		}
+		if !dup {
+			out = append(out, o)
+		}
+	}
+	sort.SliceStable(out, func(a, b int) bool {
+		return obligationOrder[out[a].Type] < obligationOrder[out[b].Type]
+	})
+	return out
+}
+
+// ObligationHandler carries out one obligation for the calling code. It
+// returns a short description of what it did for the audit log, or an
+// error if the obligation cannot be met.
+type ObligationHandler func(Obligation) (detail string, err error)
+
+// Fulfil runs the handler for each of the decision's obligations and
+// advice. An obligation with no handler, or whose handler fails, turns the
+// decision into a deny; advice failures are ignored. Denials are returned
+// unchanged.
+func (d Decision) Fulfil(handlers map[ObligationType]ObligationHandler) Decision {
+	if !d.Allowed || len(d.Obligations) == 0 {
+		return d
+	}
+
+	list := make([]Obligation, len(d.Obligations))
+	copy(list, d.Obligations)
+	d.Obligations = list
+
+	for i := range list {
+		o := &list[i]
+		h := handlers[o.Type]
+		if h == nil {
+			if o.Advice {
+				continue
+			}
+			d.Allowed = false
+			d.Reason = fmt.Sprintf("obligation %s is not supported here", o.Type)
+			return d
+		}
+		detail, err := h(*o)
+		if err != nil {
+			if o.Advice {
+				continue
+			}
+			d.Allowed = false
+			d.Reason = fmt.Sprintf("obligation %s not fulfilled: %v", o.Type, err)
+			return d
+		}
+		o.Fulfilled = true
+		o.Detail = detail
+	}
+	return d
+}
+
+// FulfilledObligations returns the obligations and advice that were
+// carried out, for the audit log.
+func (d Decision) FulfilledObligations() []Obligation {
+	var out []Obligation
+	for _, o := range d.Obligations {
+		if o.Fulfilled {
+			out = append(out, o)
+		}
+	}
+	return out
+}
@@ -0,0 +1,97 @@
+package policy
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMergeObligations(t *testing.T) {
+	got := mergeObligations([]Obligation{
+		{Type: ObligationNotifyOwner, Advice: true},
+		{Type: ObligationMaxSessionDuration, Duration: time.Hour},
+		{Type: ObligationRequireJustification},
+		{Type: ObligationMaxSessionDuration, Duration: 15 * time.Minute},
+		{Type: ObligationRequireJustification},
+		{Type: ObligationNotifyOwner, To: "sec@example.com"},
+	})
+	want := []Obligation{
+		{Type: ObligationMaxSessionDuration, Duration: 15 * time.Minute},
+		{Type: ObligationRequireJustification},
+		{Type: ObligationNotifyOwner, Advice: true},
+		{Type: ObligationNotifyOwner, To: "sec@example.com"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestDecisionFulfil(t *testing.T) {
+	ok := func(detail string) ObligationHandler {
+		return func(Obligation) (string, error) { return detail, nil }
+	}
+	fail := func(Obligation) (string, error) { return "", errors.New("no") }
+
+	allow := Decision{Allowed: true, Policy: "p", Obligations: []Obligation{
+		{Type: ObligationRequireJustification},
+		{Type: ObligationNotifyOwner, Advice: true},
+	}}
+	tests := []struct {
+		name          string
+		d             Decision
+		handlers      map[ObligationType]ObligationHandler
+		wantAllowed   bool
+		wantFulfilled []ObligationType
+	}{
+		{
+			name:          "all fulfilled",
+			d:             allow,
+			handlers:      map[ObligationType]ObligationHandler{ObligationRequireJustification: ok("because"), ObligationNotifyOwner: ok("notified")},
+			wantAllowed:   true,
+			wantFulfilled: []ObligationType{ObligationRequireJustification, ObligationNotifyOwner},
+		},
+		{
+			name:          "failed advice is ignored",
+			d:             allow,
+			handlers:      map[ObligationType]ObligationHandler{ObligationRequireJustification: ok("because"), ObligationNotifyOwner: fail},
+			wantAllowed:   true,
+			wantFulfilled: []ObligationType{ObligationRequireJustification},
+		},
+		{
+			name:     "failed obligation denies",
+			d:        allow,
+			handlers: map[ObligationType]ObligationHandler{ObligationRequireJustification: fail},
+		},
+		{
+			name:     "unsupported obligation denies",
+			d:        allow,
+			handlers: map[ObligationType]ObligationHandler{},
+		},
+		{
+			name:     "denials are unchanged",
+			d:        Decision{Allowed: false, Policy: "deny"},
+			handlers: map[ObligationType]ObligationHandler{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := tt.d.Fulfil(tt.handlers)
+			if d.Allowed != tt.wantAllowed {
+				t.Fatalf("Allowed = %v (%s), want %v", d.Allowed, d.Reason, tt.wantAllowed)
+			}
+			var got []ObligationType
+			for _, o := range d.FulfilledObligations() {
+				got = append(got, o.Type)
+			}
+			if d.Allowed && !reflect.DeepEqual(got, tt.wantFulfilled) {
+				t.Errorf("fulfilled = %v, want %v", got, tt.wantFulfilled)
+			}
+			for _, o := range tt.d.Obligations {
+				if o.Fulfilled {
+					t.Fatal("Fulfil modified the original decision")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,261 @@
+// Package rego is an alternative policy engine that evaluates a bundle of
+// Rego modules (Open Policy Agent) against the AccessContext.
+//
+// The query result is mapped into a policy.Decision. It may be a boolean
+// (allow / deny) or an object such as
+//
+//	{"allow": true, "policy": "prod-aws", "reason": "...",
+//	 "obligations": [{"type": "max_session_duration", "duration": "15m"}],
+//	 "advice": [{"type": "notify_owner"}]}
+//
+// An undefined result, an evaluation error or a malformed result denies.
+package rego
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/open-policy-agent/opa/v1/rego"
+	"github.com/open-policy-agent/opa/v1/storage/inmem"
+
+	"zero-trust-access-platform/backend/internal/policy"
+)
+
+// Name is the engine name reported by policy.ActiveEngine and Timings.
+const Name = "rego"
+
+// DefaultQuery is evaluated when no query is configured.
+const DefaultQuery = "data.zerotrust.authz.decision"
+
+// evalTimeout bounds a single evaluation; a runaway policy denies.
+const evalTimeout = time.Second
+
+// Bundle is a set of Rego modules (file name -> source) plus optional
+// base documents available under data.
+type Bundle struct {
+	Modules map[string]string
+	Data    map[string]any
+}
+
+// Revision is a content hash of the bundle, used to detect changes.
+func (b *Bundle) Revision() string {
+	h := sha256.New()
+	names := make([]string, 0, len(b.Modules))
+	for name := range b.Modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(h, "%s\x00%s\x00", name, b.Modules[name])
+	}
+	data, _ := json.Marshal(b.Data) // map keys are sorted
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))[:12]
+}
+
+// Engine is a compiled bundle ready for evaluation. It implements
+// policy.Engine.
+type Engine struct {
+	query    string
+	prepared rego.PreparedEvalQuery
+
+	Source   string    // where the bundle came from, e.g. "dir:/etc/zt/rego" or "db"
+	Revision string    // Bundle.Revision
+	Modules  []string  // module names
+	LoadedAt time.Time // when the engine was compiled
+}
+
+// Compile parses and compiles the bundle's modules for query. All module
+// errors are returned together.
+func Compile(ctx context.Context, query string, b *Bundle, source string) (*Engine, error) {
+	if query == "" {
+		query = DefaultQuery
+	}
+	if len(b.Modules) == 0 {
+		return nil, errors.New("rego bundle has no modules")
+	}
+
+	opts := []func(*rego.Rego){
+		rego.Query(query),
+		rego.Store(inmem.NewFromObject(orEmpty(b.Data))),
+	}
+	names := make([]string, 0, len(b.Modules))
+	for name, src := range b.Modules {
+		opts = append(opts, rego.Module(name, src))
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	prepared, err := rego.New(opts...).PrepareForEval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Engine{
+		query:    query,
+		prepared: prepared,
+		Source:   source,
+		Revision: b.Revision(),
+		Modules:  names,
+		LoadedAt: time.Now(),
+	}, nil
+}
+
+func orEmpty(m map[string]any) map[string]any {
+	if m == nil {
+		return map[string]any{}
+	}
+	return m
+}
+
+// Name implements policy.Engine.
+func (e *Engine) Name() string {
+	return Name
+}
+
+// Query is the Rego query the engine evaluates.
+func (e *Engine) Query() string {
+	return e.query
+}
+
+// Evaluate implements policy.Engine.
+func (e *Engine) Evaluate(ac policy.AccessContext) policy.Decision {
+	input, err := toInput(ac)
+	if err != nil {
+		return deny("rego-error", "rego: cannot encode input: "+err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), evalTimeout)
+	defer cancel()
+
+	rs, err := e.prepared.Eval(ctx, rego.EvalInput(input))
+	if err != nil {
+		return deny("rego-error", "rego evaluation failed: "+err.Error())
+	}
+	if len(rs) == 0 || len(rs[0].Expressions) == 0 {
+		return deny("rego-undefined", "rego policy returned no decision")
+	}
+
+	d, err := toDecision(rs[0].Expressions[0].Value)
+	if err != nil {
+		return deny("rego-error", "rego: invalid decision: "+err.Error())
+	}
+	return d
+}
+
+// toInput converts the context to the JSON shape policies see as input,
+// using the AccessContext json tags. The risk assessment is also given as
+// input.risk, with the score and the signal names, as the built-in engine's
+// risk.score and risk.signals fields see it.
+func toInput(ac policy.AccessContext) (any, error) {
+	b, err := json.Marshal(ac)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var v map[string]any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	names := make([]any, len(ac.RiskSignals))
+	for i, sig := range ac.RiskSignals {
+		names[i] = sig.Name
+	}
+	v["risk"] = map[string]any{
+		"score":   json.Number(strconv.Itoa(ac.RiskScore)),
+		"signals": names,
+	}
+	return v, nil
+}
+
+// result is the object form of a decision.
+type result struct {
+	Allow       *bool                   `json:"allow"`
+	Allowed     *bool                   `json:"allowed"`
+	Policy      string                  `json:"policy"`
+	Reason      string                  `json:"reason"`
+	Obligations []policy.ObligationSpec `json:"obligations"`
+	Advice      []policy.ObligationSpec `json:"advice"`
+}
+
+func toDecision(v any) (policy.Decision, error) {
+	if allowed, ok := v.(bool); ok {
+		reason := "rego policy denied the request"
+		if allowed {
+			reason = "rego policy allowed the request"
+		}
+		return decision(allowed, Name, reason), nil
+	}
+
+	obj, ok := v.(map[string]any)
+	if !ok {
+		return policy.Decision{}, fmt.Errorf("expected a boolean or an object, got %T", v)
+	}
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		return policy.Decision{}, err
+	}
+	var res result
+	if err := json.Unmarshal(raw, &res); err != nil {
+		return policy.Decision{}, err
+	}
+
+	allow := res.Allow
+	if allow == nil {
+		allow = res.Allowed
+	}
+	if allow == nil {
+		return policy.Decision{}, errors.New(`object has no boolean "allow"`)
+	}
+	if res.Policy == "" {
+		res.Policy = Name
+	}
+	if res.Reason == "" {
+		res.Reason = "rego policy denied the request"
+		if *allow {
+			res.Reason = "rego policy allowed the request"
+		}
+	}
+
+	d := decision(*allow, res.Policy, res.Reason)
+	if !*allow {
+		return d, nil
+	}
+	for i, spec := range res.Obligations {
+		o, err := policy.CompileObligation(spec, false)
+		if err != nil {
+			return policy.Decision{}, fmt.Errorf("obligations[%d]: %w", i, err)
+		}
+		d.Obligations = append(d.Obligations, o)
+	}
+	for i, spec := range res.Advice {
+		o, err := policy.CompileObligation(spec, true)
+		if err != nil {
+			return policy.Decision{}, fmt.Errorf("advice[%d]: %w", i, err)
+		}
+		d.Obligations = append(d.Obligations, o)
+	}
+	return d, nil
+}
+
+func decision(allowed bool, name, reason string) policy.Decision {
+	return policy.Decision{
+		Allowed:  allowed,
+		Policy:   name,
+		Policies: []string{name},
+		Reason:   reason,
+	}
+}
+
+func deny(name, reason string) policy.Decision {
+	return decision(false, name, reason)
+}
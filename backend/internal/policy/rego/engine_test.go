@@ -0,0 +1,82 @@
+package rego
+
+import (
+	"context"
+	"testing"
+
+	"zero-trust-access-platform/backend/internal/policy"
+)
+
+// TestExampleBundleMatchesDefaults checks that backend/rego, the example
+// bundle, decides like the built-in default policies, so that
+// POLICY_ENGINE_COMPARE reports no disagreements for it.
+func TestExampleBundleMatchesDefaults(t *testing.T) {
+	b, err := LoadDir("../../../rego")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := Compile(context.Background(), "", b, "dir:rego")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defaults, err := policy.CompileSource(policy.DefaultSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := policy.AccessContext{
+		UserRole:     "admin",
+		MFAEnabled:   true,
+		ResourceName: "wiki",
+		ResourceType: "app",
+		Sensitivity:  "low",
+		Action:       "read",
+	}
+	tests := []struct {
+		name   string
+		modify func(*policy.AccessContext)
+		want   string // deciding policy
+	}{
+		{name: "admin read", want: "default-allow"},
+		{name: "admin write", modify: func(c *policy.AccessContext) { c.Action = "write" }, want: "default-allow"},
+		{name: "user write", modify: func(c *policy.AccessContext) { c.UserRole = "user"; c.Action = "write" }, want: "user-read-only"},
+		{name: "user high sensitivity", modify: func(c *policy.AccessContext) { c.UserRole = "user"; c.Sensitivity = "high" }, want: "high-sensitivity-admin-only"},
+		{name: "admin without MFA", modify: func(c *policy.AccessContext) { c.Sensitivity = "high"; c.MFAEnabled = false }, want: "high-sensitivity-mfa-required"},
+		{name: "user AWS role", modify: func(c *policy.AccessContext) { c.UserRole = "user"; c.ResourceType = "aws_role"; c.Action = "assume" }, want: "aws-non-privileged-deny"},
+		{name: "devops AWS role", modify: func(c *policy.AccessContext) { c.UserRole = "devops"; c.ResourceType = "aws_role"; c.Action = "assume" }, want: "default-allow"},
+		{
+			name: "listed client IP",
+			modify: func(c *policy.AccessContext) {
+				c.ClientIP = "203.0.113.9"
+				c.ThreatIntel = &policy.ThreatIntel{Feed: "spamhaus-drop", Prefix: "203.0.113.0/24"}
+			},
+			want: "threat-intel-deny",
+		},
+		{
+			name: "high risk",
+			modify: func(c *policy.AccessContext) {
+				c.RiskScore = 85
+				c.RiskSignals = []policy.RiskSignal{{Name: "failed_logins", Score: 40}, {Name: "ip_reputation", Score: 45}}
+			},
+			want: "high-risk-deny",
+		},
+		{name: "moderate risk", modify: func(c *policy.AccessContext) { c.RiskScore = 70 }, want: "default-allow"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ac := base
+			if tt.modify != nil {
+				tt.modify(&ac)
+			}
+			policy.ResolveRoles(&ac)
+
+			got, want := e.Evaluate(ac), defaults.Evaluate(ac)
+			if got.Allowed != want.Allowed || got.Policy != want.Policy {
+				t.Fatalf("rego = %v by %s, builtin = %v by %s", got.Allowed, got.Policy, want.Allowed, want.Policy)
+			}
+			if got.Policy != tt.want {
+				t.Fatalf("policy = %s, want %s", got.Policy, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package rego
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/open-policy-agent/opa/v1/loader"
+)
+
+// LoadDir reads every .rego module and .json / .yaml data document below
+// dir, as `opa run` would.
+func LoadDir(dir string) (*Bundle, error) {
+	res, err := loader.NewFileLoader().All([]string{dir})
+	if err != nil {
+		return nil, fmt.Errorf("load rego bundle %s: %w", dir, err)
+	}
+	b := &Bundle{Modules: map[string]string{}, Data: res.Documents}
+	for name, f := range res.Modules {
+		b.Modules[name] = string(f.Raw)
+	}
+	return b, nil
+}
+
+// moduleName is what a module stored in the database may be called.
+var moduleName = regexp.MustCompile(`^[A-Za-z0-9_.-]+\.rego$`)
+
+// ErrInvalidName is returned for module names that do not match
+// moduleName.
+var ErrInvalidName = errors.New(`module name must look like "authz.rego"`)
+
+// Module is a Rego module stored in rego_modules.
+type Module struct {
+	Name      string    `json:"name"`
+	Source    string    `json:"source"`
+	UpdatedBy *int64    `json:"updated_by"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Repository provides DB access for rego_modules.
+type Repository struct {
+	DB *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{DB: db}
+}
+
+// List returns every stored module, by name.
+func (r *Repository) List(ctx context.Context) ([]Module, error) {
+	rows, err := r.DB.QueryContext(ctx,
+		`SELECT name, source, updated_by, updated_at
+		 FROM rego_modules
+		 ORDER BY name`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []Module{}
+	for rows.Next() {
+		var m Module
+		if err := rows.Scan(&m.Name, &m.Source, &m.UpdatedBy, &m.UpdatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, m)
+	}
+	return list, rows.Err()
+}
+
+// Load returns the stored modules as a bundle.
+func (r *Repository) Load(ctx context.Context) (*Bundle, error) {
+	list, err := r.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return bundleOf(list), nil
+}
+
+func bundleOf(list []Module) *Bundle {
+	b := &Bundle{Modules: make(map[string]string, len(list))}
+	for _, m := range list {
+		b.Modules[m.Name] = m.Source
+	}
+	return b
+}
+
+// Put creates or replaces a module.
+func (r *Repository) Put(ctx context.Context, name, source string, userID int64) error {
+	if !moduleName.MatchString(name) {
+		return ErrInvalidName
+	}
+	_, err := r.DB.ExecContext(ctx,
+		`INSERT INTO rego_modules (name, source, updated_by, updated_at)
+		 VALUES ($1, $2, $3, now())
+		 ON CONFLICT (name) DO UPDATE
+		 SET source = EXCLUDED.source, updated_by = EXCLUDED.updated_by, updated_at = now()`,
+		name, source, userID,
+	)
+	return err
+}
+
+// Delete removes a module. It returns sql.ErrNoRows if there is none.
+func (r *Repository) Delete(ctx context.Context, name string) error {
+	res, err := r.DB.ExecContext(ctx, `DELETE FROM rego_modules WHERE name = $1`, name)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// WithModule returns the stored bundle as it would be after Put, so the
+// change can be compiled before it is saved.
+func (r *Repository) WithModule(ctx context.Context, name, source string) (*Bundle, error) {
+	b, err := r.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	b.Modules[name] = source
+	return b, nil
+}
@@ -0,0 +1,127 @@
+package policy
+
+import "time"
+
+// maxReplayFlips caps how many individual flips a report lists. The
+// aggregate counts always cover every replayed record.
+const maxReplayFlips = 1000
+
+// ReplayRecord is a logged decision together with the context it was made on.
+type ReplayRecord struct {
+	LogID     int64
+	CreatedAt time.Time
+	Context   AccessContext
+	Allowed   bool
+	Policy    string
+}
+
+// Flip is a logged decision that the candidate set would decide differently.
+type Flip struct {
+	LogID     int64     `json:"log_id"`
+	CreatedAt time.Time `json:"created_at"`
+	UserID    int64     `json:"user_id"`
+	Resource  string    `json:"resource"`
+	Action    string    `json:"action"`
+	Direction string    `json:"direction"` // allow_to_deny / deny_to_allow
+	OldPolicy string    `json:"old_policy"`
+	NewPolicy string    `json:"new_policy"`
+	NewReason string    `json:"new_reason"`
+}
+
+// FlipCount aggregates flips in both directions.
+type FlipCount struct {
+	AllowToDeny int `json:"allow_to_deny"`
+	DenyToAllow int `json:"deny_to_allow"`
+}
+
+func (c *FlipCount) add(allowToDeny bool) {
+	if allowToDeny {
+		c.AllowToDeny++
+	} else {
+		c.DenyToAllow++
+	}
+}
+
+// ReplayReport summarises the blast radius of a candidate policy set.
+// ByPolicy is keyed by the candidate policy that made the new decision.
+type ReplayReport struct {
+	Evaluated  int                   `json:"evaluated"`
+	Unchanged  int                   `json:"unchanged"`
+	Total      FlipCount             `json:"total"`
+	ByUser     map[int64]*FlipCount  `json:"by_user"`
+	ByResource map[string]*FlipCount `json:"by_resource"`
+	ByPolicy   map[string]*FlipCount `json:"by_policy"`
+	Flips      []Flip                `json:"flips"`
+	Truncated  bool                  `json:"truncated"`
+}
+
+// Replay re-evaluates logged decisions against a candidate set one record
+// at a time, so callers can stream rows straight from the database.
+type Replay struct {
+	set    *PolicySet
+	report ReplayReport
+}
+
+func NewReplay(candidate *PolicySet) *Replay {
+	return &Replay{
+		set: candidate,
+		report: ReplayReport{
+			ByUser:     map[int64]*FlipCount{},
+			ByResource: map[string]*FlipCount{},
+			ByPolicy:   map[string]*FlipCount{},
+			Flips:      []Flip{},
+		},
+	}
+}
+
+// Add replays a single record.
+func (r *Replay) Add(rec ReplayRecord) {
+	r.report.Evaluated++
+
+	d := r.set.Evaluate(rec.Context)
+	if d.Allowed == rec.Allowed {
+		r.report.Unchanged++
+		return
+	}
+
+	allowToDeny := rec.Allowed
+	r.report.Total.add(allowToDeny)
+	bump(r.report.ByUser, rec.Context.UserID, allowToDeny)
+	bump(r.report.ByResource, rec.Context.ResourceName, allowToDeny)
+	bump(r.report.ByPolicy, d.Policy, allowToDeny)
+
+	if len(r.report.Flips) >= maxReplayFlips {
+		r.report.Truncated = true
+		return
+	}
+
+	dir := "deny_to_allow"
+	if allowToDeny {
+		dir = "allow_to_deny"
+	}
+	r.report.Flips = append(r.report.Flips, Flip{
+		LogID:     rec.LogID,
+		CreatedAt: rec.CreatedAt,
+		UserID:    rec.Context.UserID,
+		Resource:  rec.Context.ResourceName,
+		Action:    rec.Context.Action,
+		Direction: dir,
+		OldPolicy: rec.Policy,
+		NewPolicy: d.Policy,
+		NewReason: d.Reason,
+	})
+}
+
+// Report returns the accumulated report.
+func (r *Replay) Report() ReplayReport {
+	return r.report
+}
+
+func bump[K comparable](m map[K]*FlipCount, k K, allowToDeny bool) {
+	c, ok := m[k]
+	if !ok {
+		c = &FlipCount{}
+		m[k] = c
+	}
+	c.add(allowToDeny)
+}
@@ -0,0 +1,237 @@
+package policy
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Version is one immutable revision of the policy document.
+type Version struct {
+	ID        int64     `json:"id"`
+	ParentID  *int64    `json:"parent_id"`
+	Source    string    `json:"source,omitempty"`
+	Comment   string    `json:"comment"`
+	Diff      string    `json:"diff"`
+	AuthorID  *int64    `json:"author_id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// Derived from policy_activations: whether this is the live version and
+	// who last activated it (nil if it has never been live).
+	Active      bool       `json:"active"`
+	ActivatedBy *int64     `json:"activated_by,omitempty"`
+	ActivatedAt *time.Time `json:"activated_at,omitempty"`
+}
+
+// Activation kinds recorded in policy_activations.
+const (
+	ActivationActivate = "activate"
+	ActivationRollback = "rollback"
+)
+
+// ErrAlreadyActive is returned by CheckActivation for the live version.
+var ErrAlreadyActive = errors.New("policy version is already active")
+
+// ErrOwnVersion is returned by CheckActivation when the author of a new
+// version tries to activate it: every change needs a second admin.
+var ErrOwnVersion = errors.New("a policy version must be activated by an admin other than its author")
+
+// ErrNeverActive is returned by CheckActivation for a rollback to a
+// version that has never been live.
+var ErrNeverActive = errors.New("only previously active versions can be rolled back to")
+
+// CheckActivation enforces the review rules for making v live: a new
+// version must be activated by an admin other than its author, while any
+// admin may roll back to a version that has been live before.
+func (v *Version) CheckActivation(kind string, actorID int64) error {
+	if v.Active {
+		return ErrAlreadyActive
+	}
+	switch kind {
+	case ActivationActivate:
+		if v.AuthorID != nil && *v.AuthorID == actorID {
+			return ErrOwnVersion
+		}
+	case ActivationRollback:
+		if v.ActivatedAt == nil {
+			return ErrNeverActive
+		}
+	default:
+		return fmt.Errorf("unknown activation kind %q", kind)
+	}
+	return nil
+}
+
+// CompileVersion compiles a stored version, tagging the set with its id.
+func CompileVersion(v *Version) (*PolicySet, error) {
+	set, err := CompileSource([]byte(v.Source))
+	if err != nil {
+		return nil, fmt.Errorf("policy version %d is invalid: %w", v.ID, err)
+	}
+	set.Version = v.ID
+	return set, nil
+}
+
+// Repository provides DB access for policy_versions and policy_activations.
+type Repository struct {
+	DB *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{DB: db}
+}
+
+const versionColumns = `
+    v.id, v.parent_id, v.source, v.comment, v.diff, v.author_id, v.created_at,
+    v.id = (SELECT version_id FROM policy_activations ORDER BY id DESC LIMIT 1),
+    la.activated_by, la.activated_at
+FROM policy_versions v
+LEFT JOIN LATERAL (
+    SELECT activated_by, activated_at
+    FROM policy_activations a
+    WHERE a.version_id = v.id
+    ORDER BY a.id DESC
+    LIMIT 1
+) la ON true`
+
+func scanVersion(row interface{ Scan(...any) error }) (*Version, error) {
+	var v Version
+	var active sql.NullBool
+	if err := row.Scan(
+		&v.ID,
+		&v.ParentID,
+		&v.Source,
+		&v.Comment,
+		&v.Diff,
+		&v.AuthorID,
+		&v.CreatedAt,
+		&active,
+		&v.ActivatedBy,
+		&v.ActivatedAt,
+	); err != nil {
+		return nil, err
+	}
+	v.Active = active.Bool
+	return &v, nil
+}
+
+// Active returns the live version, or sql.ErrNoRows if no version has ever
+// been activated (in which case the built-in default set applies).
+func (r *Repository) Active(ctx context.Context) (*Version, error) {
+	return scanVersion(r.DB.QueryRowContext(ctx, `
+SELECT `+versionColumns+`
+WHERE v.id = (SELECT version_id FROM policy_activations ORDER BY id DESC LIMIT 1);
+`))
+}
+
+// Get returns a single version by id.
+func (r *Repository) Get(ctx context.Context, id int64) (*Version, error) {
+	return scanVersion(r.DB.QueryRowContext(ctx, `
+SELECT `+versionColumns+`
+WHERE v.id = $1;
+`, id))
+}
+
+// List returns all versions, newest first, without their sources.
+func (r *Repository) List(ctx context.Context) ([]Version, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+SELECT `+versionColumns+`
+ORDER BY v.id DESC;
+`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Version{}
+	for rows.Next() {
+		v, err := scanVersion(rows)
+		if err != nil {
+			return nil, err
+		}
+		v.Source = ""
+		out = append(out, *v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Create stores a new version. Its parent is the currently active version
+// (or the built-in defaults) and its diff is computed against that parent.
+// Callers must Compile the source first; Create does not validate it.
+func (r *Repository) Create(ctx context.Context, source, comment string, authorID int64) (*Version, error) {
+	var parentID *int64
+	parentSource := string(DefaultSource)
+
+	active, err := r.Active(ctx)
+	switch {
+	case err == nil:
+		parentID = &active.ID
+		parentSource = active.Source
+	case !errors.Is(err, sql.ErrNoRows):
+		return nil, err
+	}
+
+	var id int64
+	err = r.DB.QueryRowContext(ctx, `
+INSERT INTO policy_versions (parent_id, source, comment, diff, author_id)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id;
+`, parentID, source, comment, Diff(parentSource, source), authorID).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+	return r.Get(ctx, id)
+}
+
+// Activate makes a version live. kind is ActivationActivate or
+// ActivationRollback; callers enforce the review rules with
+// CheckActivation.
+func (r *Repository) Activate(ctx context.Context, id int64, kind string, actorID int64) error {
+	_, err := r.DB.ExecContext(ctx, `
+INSERT INTO policy_activations (version_id, kind, activated_by)
+VALUES ($1, $2, $3);
+`, id, kind, actorID)
+	return err
+}
+
+// Inventory lists the roles, resources and attribute keys in use, for
+// Analyze. AWS roles are resources of type aws_role with an env attribute
+// (see the AWS session handler).
+func (r *Repository) Inventory(ctx context.Context) (*Inventory, error) {
+	inv := &Inventory{}
+	for _, q := range []struct {
+		dst   *[]string
+		query string
+	}{
+		{&inv.Roles, `SELECT name FROM roles ORDER BY 1`},
+		{&inv.Resources, `SELECT name FROM resources UNION SELECT name FROM aws_roles ORDER BY 1`},
+		{&inv.ResourceTypes, `SELECT type FROM resources UNION SELECT 'aws_role' ORDER BY 1`},
+		{&inv.UserAttributes, `SELECT DISTINCT key FROM user_attributes ORDER BY 1`},
+		{&inv.ResourceAttributes, `SELECT key FROM resource_attributes UNION SELECT 'env' ORDER BY 1`},
+	} {
+		rows, err := r.DB.QueryContext(ctx, q.query)
+		if err != nil {
+			return nil, err
+		}
+		*q.dst = []string{}
+		for rows.Next() {
+			var v string
+			if err := rows.Scan(&v); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			*q.dst = append(*q.dst, v)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return inv, nil
+}
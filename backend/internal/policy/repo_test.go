@@ -0,0 +1,86 @@
+package policy
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCheckActivation(t *testing.T) {
+	author, reviewer := int64(1), int64(2)
+	live := time.Now()
+	tests := []struct {
+		name  string
+		v     Version
+		kind  string
+		actor int64
+		want  error
+	}{
+		{name: "another admin activates", v: Version{AuthorID: &author}, kind: ActivationActivate, actor: reviewer},
+		{name: "author activates", v: Version{AuthorID: &author}, kind: ActivationActivate, actor: author, want: ErrOwnVersion},
+		{name: "author activates after an earlier rollout", v: Version{AuthorID: &author, ActivatedAt: &live}, kind: ActivationActivate, actor: author, want: ErrOwnVersion},
+		{name: "version without an author", v: Version{}, kind: ActivationActivate, actor: author},
+		{name: "already active", v: Version{AuthorID: &author, Active: true, ActivatedAt: &live}, kind: ActivationActivate, actor: reviewer, want: ErrAlreadyActive},
+		{name: "author rolls back", v: Version{AuthorID: &author, ActivatedAt: &live}, kind: ActivationRollback, actor: author},
+		{name: "rollback to a version never live", v: Version{AuthorID: &author}, kind: ActivationRollback, actor: reviewer, want: ErrNeverActive},
+		{name: "rollback to the live version", v: Version{Active: true, ActivatedAt: &live}, kind: ActivationRollback, actor: reviewer, want: ErrAlreadyActive},
+	}
+	for _, tt := range tests {
+		if err := tt.v.CheckActivation(tt.kind, tt.actor); !errors.Is(err, tt.want) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+	if err := (&Version{}).CheckActivation("promote", reviewer); err == nil {
+		t.Error("unknown kind accepted")
+	}
+}
+
+func TestRollbackRestoresEarlierVersion(t *testing.T) {
+	t.Cleanup(func() { SetActive(mustCompile(t, string(DefaultSource))) })
+
+	author, reviewer := int64(1), int64(2)
+	v1 := &Version{ID: 1, AuthorID: &author, Source: `
+policies:
+  - {name: allow-all, priority: 0, effect: allow, reason: open}
+`}
+	v2 := &Version{ID: 2, AuthorID: &author, Source: `
+policies:
+  - {name: deny-all, priority: 0, effect: deny, reason: frozen}
+`}
+
+	// activate applies the review rules and makes v live, like the
+	// activate and rollback endpoints; Active and ActivatedAt mirror what
+	// policy_activations would report afterwards.
+	activate := func(v *Version, kind string, others ...*Version) {
+		t.Helper()
+		if err := v.CheckActivation(kind, reviewer); err != nil {
+			t.Fatalf("%s version %d: %v", kind, v.ID, err)
+		}
+		set, err := CompileVersion(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		SetActive(set)
+		now := time.Now()
+		v.Active, v.ActivatedAt = true, &now
+		for _, o := range others {
+			o.Active = false
+		}
+	}
+
+	activate(v1, ActivationActivate)
+	activate(v2, ActivationActivate, v1)
+	if d := Evaluate(AccessContext{Action: "read"}); d.Allowed || d.Version != 2 {
+		t.Fatalf("after activating v2: %+v", d)
+	}
+
+	activate(v1, ActivationRollback, v2)
+	d := Evaluate(AccessContext{Action: "read"})
+	if !d.Allowed || d.Version != 1 || d.Policy != "allow-all" {
+		t.Fatalf("after rolling back to v1: %+v, want v1's allow-all", d)
+	}
+
+	if err := v2.CheckActivation(ActivationRollback, author); err != nil {
+		t.Errorf("rolling forward to v2 again: %v", err)
+	}
+}
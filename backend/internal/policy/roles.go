@@ -0,0 +1,144 @@
+package policy
+
+import (
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// Role is an application role in the hierarchy: it has its own
+// permissions plus those of every role it inherits.
+type Role struct {
+	Name        string
+	Inherits    []string
+	Permissions []string
+}
+
+type resolvedRole struct {
+	roles       []string // the role itself first, then inherited roles by name
+	permissions []string // sorted, without duplicates
+}
+
+// BuiltinRoles is the hierarchy seeded by migrations 017 and 018:
+// admin ⊇ devops ⊇ user, with devops allowed to write and assume AWS
+// roles. It is in effect until SetRoles replaces it with the stored roles,
+// so tools that evaluate policies without a database (policytest,
+// policylint) agree with the server on the default policies.
+var BuiltinRoles = []Role{
+	{Name: "user"},
+	{Name: "devops", Inherits: []string{"user"}, Permissions: []string{"resources:write", "aws:assume"}},
+	{Name: "admin", Inherits: []string{"devops"}, Permissions: []string{"aws_role:ZTPortalReadOnlyLogs"}},
+}
+
+// roles holds the resolved hierarchy. Like zones it is loaded separately
+// from policies; it starts as BuiltinRoles. A role missing from it has no
+// inherited roles and no permissions.
+var roles atomic.Pointer[map[string]resolvedRole]
+
+func init() {
+	SetRoles(BuiltinRoles)
+}
+
+// SetRoles replaces the role hierarchy. Inheritance is transitive; unknown
+// inherited roles and cycles are ignored (they are rejected on write).
+func SetRoles(list []Role) {
+	byName := make(map[string]Role, len(list))
+	for _, r := range list {
+		byName[r.Name] = r
+	}
+
+	resolved := make(map[string]resolvedRole, len(list))
+	for _, r := range list {
+		seen := map[string]bool{r.Name: true}
+		perms := map[string]bool{}
+		var inherited []string
+		var walk func(Role)
+		walk = func(r Role) {
+			for _, p := range r.Permissions {
+				perms[p] = true
+			}
+			for _, name := range r.Inherits {
+				parent, ok := byName[name]
+				if !ok || seen[name] {
+					continue
+				}
+				seen[name] = true
+				inherited = append(inherited, name)
+				walk(parent)
+			}
+		}
+		walk(r)
+
+		sort.Strings(inherited)
+		rr := resolvedRole{roles: append([]string{r.Name}, inherited...)}
+		for p := range perms {
+			rr.permissions = append(rr.permissions, p)
+		}
+		sort.Strings(rr.permissions)
+		resolved[r.Name] = rr
+	}
+
+	roles.Store(&resolved)
+	InvalidateCache()
+}
+
+func resolveRole(role string) resolvedRole {
+	if m := roles.Load(); m != nil {
+		if rr, ok := (*m)[role]; ok {
+			return rr
+		}
+	}
+	if role == "" {
+		return resolvedRole{}
+	}
+	return resolvedRole{roles: []string{role}}
+}
+
+// EffectiveRoles returns role followed by every role it inherits.
+func EffectiveRoles(role string) []string {
+	return resolveRole(role).roles
+}
+
+// RolePermissions returns the permissions of role, including inherited ones.
+func RolePermissions(role string) []string {
+	return resolveRole(role).permissions
+}
+
+// HasRole reports whether role is want or inherits it, e.g. whether a user
+// counts as an admin.
+func HasRole(role, want string) bool {
+	for _, r := range EffectiveRoles(role) {
+		if r == want {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveRoles fills ac.UserRoles and ac.UserPermissions from the
+// hierarchy unless the caller already set them.
+func ResolveRoles(ac *AccessContext) {
+	if ac.UserRoles != nil {
+		return
+	}
+	rr := resolveRole(ac.UserRole)
+	ac.UserRoles = rr.roles
+	ac.UserPermissions = rr.permissions
+}
+
+// userRoles and userPermissions back the user.roles and user.permissions
+// fields, resolving contexts that were not filled by Evaluate (replays,
+// explanations).
+func userRoles(c *AccessContext) string {
+	if c.UserRoles != nil {
+		return strings.Join(c.UserRoles, ",")
+	}
+	return strings.Join(EffectiveRoles(c.UserRole), ",")
+}
+
+func userPermissions(c *AccessContext) string {
+	if c.UserRoles != nil {
+		return strings.Join(c.UserPermissions, ",")
+	}
+	return strings.Join(RolePermissions(c.UserRole), ",")
+}
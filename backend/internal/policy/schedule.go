@@ -0,0 +1,300 @@
+package policy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	_ "time/tzdata" // the runtime image ships without a zoneinfo database
+
+	"gopkg.in/yaml.v3"
+)
+
+// Schedule is the value of a "within" / "not_within" condition on the
+// "time" field. Every part is optional; a schedule matches when all of the
+// parts that are set match. For example
+//
+//	{timezone: Europe/Berlin, days: [mon, tue, wed, thu, fri], from: "08:00", to: "18:00"}
+//	{timezone: UTC, start_date: "2026-11-01T22:00", end_date: "2026-11-02T02:00"}
+//
+// A window whose From is after its To runs overnight (e.g. 22:00–06:00);
+// its early-morning part counts towards the previous day in Days.
+type Schedule struct {
+	Timezone  string   `yaml:"timezone"`
+	Days      []string `yaml:"days"`
+	From      string   `yaml:"from"`
+	To        string   `yaml:"to"`
+	StartDate string   `yaml:"start_date"`
+	EndDate   string   `yaml:"end_date"`
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+type compiledSchedule struct {
+	loc        *time.Location
+	days       map[time.Weekday]bool
+	hasHours   bool
+	from, to   int // minutes since midnight
+	start, end time.Time
+	desc       string
+}
+
+// compileSchedules accepts a single schedule or a list of them; the
+// condition matches if any schedule does.
+func compileSchedules(v any) ([]compiledSchedule, error) {
+	var list []Schedule
+	if _, isList := v.([]any); isList {
+		if err := decodeValue(v, &list); err != nil {
+			return nil, err
+		}
+	} else {
+		var one Schedule
+		if err := decodeValue(v, &one); err != nil {
+			return nil, err
+		}
+		list = []Schedule{one}
+	}
+	if len(list) == 0 {
+		return nil, errors.New("at least one schedule is required")
+	}
+
+	out := make([]compiledSchedule, 0, len(list))
+	for i, sc := range list {
+		cs, err := sc.compile()
+		if err != nil {
+			return nil, fmt.Errorf("schedule[%d]: %w", i, err)
+		}
+		out = append(out, cs)
+	}
+	return out, nil
+}
+
+func (sc Schedule) compile() (compiledSchedule, error) {
+	cs := compiledSchedule{loc: time.UTC}
+	if sc.Timezone != "" {
+		loc, err := time.LoadLocation(sc.Timezone)
+		if err != nil {
+			return cs, fmt.Errorf("unknown timezone %q", sc.Timezone)
+		}
+		cs.loc = loc
+	}
+
+	if len(sc.Days) > 0 {
+		cs.days = map[time.Weekday]bool{}
+		for _, d := range sc.Days {
+			wd, ok := weekdays[strings.ToLower(d)]
+			if !ok {
+				return cs, fmt.Errorf("unknown day %q (use mon, tue, ...)", d)
+			}
+			cs.days[wd] = true
+		}
+	}
+
+	if sc.From != "" || sc.To != "" {
+		var err error
+		if cs.from, err = parseClock(sc.From); err != nil {
+			return cs, fmt.Errorf("from: %w", err)
+		}
+		if cs.to, err = parseClock(sc.To); err != nil {
+			return cs, fmt.Errorf("to: %w", err)
+		}
+		if cs.from == cs.to {
+			return cs, errors.New("from and to must differ")
+		}
+		cs.hasHours = true
+	}
+
+	if sc.StartDate != "" {
+		t, _, err := parseDate(sc.StartDate, cs.loc)
+		if err != nil {
+			return cs, fmt.Errorf("start_date: %w", err)
+		}
+		cs.start = t
+	}
+	if sc.EndDate != "" {
+		t, dateOnly, err := parseDate(sc.EndDate, cs.loc)
+		if err != nil {
+			return cs, fmt.Errorf("end_date: %w", err)
+		}
+		if dateOnly {
+			t = t.AddDate(0, 0, 1) // a plain end date includes the whole day
+		}
+		cs.end = t
+	}
+	if !cs.start.IsZero() && !cs.end.IsZero() && !cs.end.After(cs.start) {
+		return cs, errors.New("end_date must be after start_date")
+	}
+
+	cs.desc = sc.describe(cs.loc)
+	return cs, nil
+}
+
+func (cs *compiledSchedule) matches(t time.Time) bool {
+	t = t.In(cs.loc)
+
+	if !cs.start.IsZero() && t.Before(cs.start) {
+		return false
+	}
+	if !cs.end.IsZero() && !t.Before(cs.end) {
+		return false
+	}
+
+	day := t.Weekday()
+	if cs.hasHours {
+		m := t.Hour()*60 + t.Minute()
+		if cs.from < cs.to {
+			if m < cs.from || m >= cs.to {
+				return false
+			}
+		} else {
+			switch {
+			case m >= cs.from:
+			case m < cs.to:
+				day = (day + 6) % 7 // belongs to the shift that started yesterday
+			default:
+				return false
+			}
+		}
+	}
+
+	if cs.days != nil && !cs.days[day] {
+		return false
+	}
+	return true
+}
+
+func (sc Schedule) describe(loc *time.Location) string {
+	var parts []string
+	if len(sc.Days) > 0 {
+		parts = append(parts, strings.Join(sc.Days, ","))
+	}
+	if sc.From != "" {
+		parts = append(parts, sc.From+"–"+sc.To)
+	}
+	if sc.StartDate != "" {
+		parts = append(parts, "from "+sc.StartDate)
+	}
+	if sc.EndDate != "" {
+		parts = append(parts, "until "+sc.EndDate)
+	}
+	parts = append(parts, loc.String())
+	return strings.Join(parts, " ")
+}
+
+// compileTimeCondition builds the matcher and reason explanation for a
+// within / not_within condition.
+func compileTimeCondition(op string, v any) (func(*AccessContext) bool, func(*AccessContext) string, error) {
+	scheds, err := compileSchedules(v)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	within := func(ctx *AccessContext) bool {
+		t := contextTime(ctx)
+		for i := range scheds {
+			if scheds[i].matches(t) {
+				return true
+			}
+		}
+		return false
+	}
+
+	neg := op == "not_within"
+	match := func(ctx *AccessContext) bool {
+		return within(ctx) != neg
+	}
+	// Each schedule is reported with the time in its own location.
+	explain := func(ctx *AccessContext) string {
+		t := contextTime(ctx)
+		notes := make([]string, len(scheds))
+		for i := range scheds {
+			rel := "outside"
+			if scheds[i].matches(t) {
+				rel = "within"
+			}
+			notes[i] = fmt.Sprintf("%s is %s %s", t.In(scheds[i].loc).Format("Mon 2006-01-02 15:04"), rel, scheds[i].desc)
+		}
+		return strings.Join(notes, "; ")
+	}
+	return match, explain, nil
+}
+
+// contextTime is the request time, defaulting to now for callers that did
+// not set it.
+func contextTime(ctx *AccessContext) time.Time {
+	if ctx.Time.IsZero() {
+		return time.Now()
+	}
+	return ctx.Time
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// parseDate accepts a date, a local date-time or an RFC 3339 timestamp.
+func parseDate(s string, loc *time.Location) (t time.Time, dateOnly bool, err error) {
+	if t, err := time.ParseInLocation("2006-01-02", s, loc); err == nil {
+		return t, true, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02T15:04", s, loc); err == nil {
+		return t, false, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, false, nil
+	}
+	return time.Time{}, false, fmt.Errorf("%q is not a date (YYYY-MM-DD[THH:MM])", s)
+}
+
+// decodeValue converts a generic condition value into a typed struct,
+// rejecting unknown keys so typos surface at validation time.
+func decodeValue(v any, out any) error {
+	raw, err := yaml.Marshal(undoTimestamps(v))
+	if err != nil {
+		return err
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(raw))
+	dec.KnownFields(true)
+	if err := dec.Decode(out); err != nil {
+		return fmt.Errorf("invalid value: %w", err)
+	}
+	return nil
+}
+
+// undoTimestamps turns values YAML parsed as timestamps (unquoted
+// 2026-12-24) back into the date strings the author wrote.
+func undoTimestamps(v any) any {
+	switch x := v.(type) {
+	case time.Time:
+		if x.Equal(x.Truncate(24 * time.Hour)) {
+			return x.Format("2006-01-02")
+		}
+		return x.Format(time.RFC3339)
+	case []any:
+		out := make([]any, len(x))
+		for i := range x {
+			out[i] = undoTimestamps(x[i])
+		}
+		return out
+	case map[string]any:
+		out := make(map[string]any, len(x))
+		for k, e := range x {
+			out[k] = undoTimestamps(e)
+		}
+		return out
+	}
+	return v
+}
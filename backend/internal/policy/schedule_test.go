@@ -0,0 +1,99 @@
+package policy
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeCondition(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no tz database:", err)
+	}
+	at := func(loc *time.Location, s string) time.Time {
+		tm, err := time.ParseInLocation("2006-01-02 15:04", s, loc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tm
+	}
+
+	tests := []struct {
+		name  string
+		value any
+		at    time.Time
+		want  bool
+	}{
+		// 2026-10-16 is a Friday.
+		{name: "overnight, evening of the day", value: map[string]any{"days": []any{"fri"}, "from": "22:00", "to": "06:00"}, at: at(time.UTC, "2026-10-16 23:00"), want: true},
+		{name: "overnight, morning after counts for the day before", value: map[string]any{"days": []any{"fri"}, "from": "22:00", "to": "06:00"}, at: at(time.UTC, "2026-10-17 05:59"), want: true},
+		{name: "overnight, morning of the day belongs to the day before", value: map[string]any{"days": []any{"fri"}, "from": "22:00", "to": "06:00"}, at: at(time.UTC, "2026-10-16 03:00"), want: false},
+		{name: "overnight, end is exclusive", value: map[string]any{"days": []any{"fri"}, "from": "22:00", "to": "06:00"}, at: at(time.UTC, "2026-10-17 06:00"), want: false},
+		{name: "overnight, between shifts", value: map[string]any{"from": "22:00", "to": "06:00"}, at: at(time.UTC, "2026-10-16 12:00"), want: false},
+		{name: "end_date includes the last minute of its day", value: map[string]any{"start_date": "2026-10-01", "end_date": "2026-10-31"}, at: at(time.UTC, "2026-10-31 23:59"), want: true},
+		{name: "end_date excludes the next day", value: map[string]any{"start_date": "2026-10-01", "end_date": "2026-10-31"}, at: at(time.UTC, "2026-11-01 00:00"), want: false},
+		{name: "start_date is inclusive", value: map[string]any{"start_date": "2026-10-01", "end_date": "2026-10-31"}, at: at(time.UTC, "2026-10-01 00:00"), want: true},
+		{name: "end_date with a time is exact", value: map[string]any{"end_date": "2026-10-31T12:00"}, at: at(time.UTC, "2026-10-31 12:00"), want: false},
+		{name: "hours are local to the timezone", value: map[string]any{"timezone": "Europe/Berlin", "from": "09:00", "to": "17:00"}, at: at(time.UTC, "2026-10-16 07:30"), want: true},
+		{name: "hours past the local end", value: map[string]any{"timezone": "Europe/Berlin", "from": "09:00", "to": "17:00"}, at: at(time.UTC, "2026-10-16 15:30"), want: false},
+		{name: "days are local to the timezone", value: map[string]any{"timezone": "Europe/Berlin", "days": []any{"sat"}}, at: at(time.UTC, "2026-10-16 22:30"), want: true},
+		{name: "dates are local to the timezone", value: map[string]any{"timezone": "Europe/Berlin", "end_date": "2026-10-31"}, at: at(berlin, "2026-10-31 23:30"), want: true},
+		{name: "dates past the local end", value: map[string]any{"timezone": "Europe/Berlin", "end_date": "2026-10-31"}, at: at(time.UTC, "2026-10-31 23:30"), want: false},
+		{name: "any schedule of a list", value: []any{
+			map[string]any{"days": []any{"mon"}},
+			map[string]any{"days": []any{"fri"}},
+		}, at: at(time.UTC, "2026-10-16 12:00"), want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, op := range []string{"within", "not_within"} {
+				match, _, err := compileTimeCondition(op, tt.value)
+				if err != nil {
+					t.Fatal(err)
+				}
+				want := tt.want != (op == "not_within")
+				if got := match(&AccessContext{Time: tt.at}); got != want {
+					t.Errorf("%s at %s = %v, want %v", op, tt.at, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestTimeConditionErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+		want  string
+	}{
+		{name: "unknown timezone", value: map[string]any{"timezone": "Mars/Olympus"}, want: "unknown timezone"},
+		{name: "unknown day", value: map[string]any{"days": []any{"someday"}}, want: "unknown day"},
+		{name: "empty window", value: map[string]any{"from": "09:00", "to": "09:00"}, want: "from and to must differ"},
+		{name: "end before start", value: map[string]any{"start_date": "2026-10-31", "end_date": "2026-10-01"}, want: "end_date must be after start_date"},
+		{name: "unknown key", value: map[string]any{"weekdays": []any{"mon"}}, want: "invalid value"},
+		{name: "empty list", value: []any{}, want: "at least one schedule"},
+	}
+	for _, tt := range tests {
+		_, _, err := compileTimeCondition("within", tt.value)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestTimeConditionExplainUsesEachLocation(t *testing.T) {
+	_, explain, err := compileTimeCondition("within", []any{
+		map[string]any{"timezone": "Europe/Berlin", "from": "09:00", "to": "17:00"},
+		map[string]any{"timezone": "America/New_York", "from": "09:00", "to": "17:00"},
+	})
+	if err != nil {
+		t.Skip("no tz database:", err)
+	}
+	got := explain(&AccessContext{Time: time.Date(2026, 10, 16, 14, 0, 0, 0, time.UTC)})
+	want := "Fri 2026-10-16 16:00 is within 09:00–17:00 Europe/Berlin; " +
+		"Fri 2026-10-16 10:00 is within 09:00–17:00 America/New_York"
+	if got != want {
+		t.Errorf("explain = %q\nwant %q", got, want)
+	}
+}
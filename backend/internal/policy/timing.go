@@ -0,0 +1,115 @@
+package policy
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// timingBuckets are the upper bounds of the latency histogram; the last
+// bucket catches everything slower.
+var timingBuckets = []time.Duration{
+	10 * time.Microsecond,
+	25 * time.Microsecond,
+	50 * time.Microsecond,
+	100 * time.Microsecond,
+	250 * time.Microsecond,
+	500 * time.Microsecond,
+	time.Millisecond,
+	2500 * time.Microsecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+}
+
+type engineTiming struct {
+	count         atomic.Int64
+	totalNanos    atomic.Int64
+	maxNanos      atomic.Int64
+	disagreements atomic.Int64
+	buckets       []atomic.Int64 // len(timingBuckets)+1
+}
+
+var timings sync.Map // engine name -> *engineTiming
+
+func timingFor(name string) *engineTiming {
+	if t, ok := timings.Load(name); ok {
+		return t.(*engineTiming)
+	}
+	t, _ := timings.LoadOrStore(name, &engineTiming{
+		buckets: make([]atomic.Int64, len(timingBuckets)+1),
+	})
+	return t.(*engineTiming)
+}
+
+func recordTiming(name string, d time.Duration) {
+	t := timingFor(name)
+	t.count.Add(1)
+	t.totalNanos.Add(int64(d))
+	for {
+		old := t.maxNanos.Load()
+		if int64(d) <= old || t.maxNanos.CompareAndSwap(old, int64(d)) {
+			break
+		}
+	}
+	i := sort.Search(len(timingBuckets), func(i int) bool { return d <= timingBuckets[i] })
+	t.buckets[i].Add(1)
+}
+
+func recordDisagreement(name string) {
+	timingFor(name).disagreements.Add(1)
+}
+
+// TimingStats summarises the decision latency of one engine since start.
+// Percentiles are the upper bound of the histogram bucket they fall in,
+// capped at the maximum seen.
+type TimingStats struct {
+	Count  int64   `json:"count"`
+	MeanUS float64 `json:"mean_us"`
+	P50US  int64   `json:"p50_us"`
+	P95US  int64   `json:"p95_us"`
+	P99US  int64   `json:"p99_us"`
+	MaxUS  int64   `json:"max_us"`
+
+	// Disagreements counts requests where this engine and the built-in one
+	// reached a different allow/deny (only when comparing).
+	Disagreements int64 `json:"disagreements,omitempty"`
+}
+
+// Timings returns the decision latency of every engine that has run.
+func Timings() map[string]TimingStats {
+	out := map[string]TimingStats{}
+	timings.Range(func(k, v any) bool {
+		t := v.(*engineTiming)
+		st := TimingStats{
+			Count:         t.count.Load(),
+			MaxUS:         t.maxNanos.Load() / int64(time.Microsecond),
+			Disagreements: t.disagreements.Load(),
+		}
+		if st.Count > 0 {
+			st.MeanUS = float64(t.totalNanos.Load()) / float64(st.Count) / float64(time.Microsecond)
+			st.P50US = t.percentile(st.Count, 0.50)
+			st.P95US = t.percentile(st.Count, 0.95)
+			st.P99US = t.percentile(st.Count, 0.99)
+		}
+		out[k.(string)] = st
+		return true
+	})
+	return out
+}
+
+func (t *engineTiming) percentile(count int64, p float64) int64 {
+	want := int64(float64(count)*p + 0.5)
+	maxNanos := t.maxNanos.Load()
+	var seen int64
+	for i := range t.buckets {
+		seen += t.buckets[i].Load()
+		if seen >= want && i < len(timingBuckets) {
+			return int64(min(timingBuckets[i], time.Duration(maxNanos)) / time.Microsecond)
+		}
+	}
+	return maxNanos / int64(time.Microsecond)
+}
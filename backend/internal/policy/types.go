@@ -5,21 +5,87 @@ import "time"
 // AccessContext represents all inputs required
 // to make a Zero Trust authorization decision.
 type AccessContext struct {
-	UserID     int64
-	UserRole   string
-	MFAEnabled bool
+	UserID     int64  `json:"user_id"`
+	UserRole   string `json:"user_role"`
+	MFAEnabled bool   `json:"mfa_enabled"`
 
-	ResourceName string
-	ResourceType string
-	Sensitivity  string // low / medium / high
-	Action       string // read / write / assume
+	// UserRoles is UserRole plus every role it inherits, and
+	// UserPermissions the union of their permission sets. Evaluate fills
+	// them from the role hierarchy (see SetRoles) when they are empty.
+	// Policies refer to them as user.roles and user.permissions.
+	UserRoles       []string `json:"user_roles,omitempty"`
+	UserPermissions []string `json:"user_permissions,omitempty"`
 
-	Time time.Time
+	ResourceName string `json:"resource_name"`
+	ResourceType string `json:"resource_type"`
+	Sensitivity  string `json:"sensitivity"` // low / medium / high
+	Action       string `json:"action"`      // read / write / assume
+
+	ClientIP string `json:"client_ip"` // resolved by middleware.RealIP
+
+	// Free-form attributes, e.g. department / employment_type / clearance
+	// on users and owner_team / data_classification / environment on
+	// resources. Policies refer to them as user.<key> and resource.<key>.
+	UserAttributes     map[string]string `json:"user_attributes,omitempty"`
+	ResourceAttributes map[string]string `json:"resource_attributes,omitempty"`
+
+	// RiskScore (0-100) and the signals behind it, as assessed by the risk
+	// engine for this request. Policies refer to them as risk.score and
+	// risk.signals.
+	RiskScore   int          `json:"risk_score"`
+	RiskSignals []RiskSignal `json:"risk_signals,omitempty"`
+
+	// Signals are typed attributes from signal providers, keyed
+	// <provider>.<name> (e.g. "hr.status"). Values are strings, booleans
+	// or integers. Policies refer to them as signal.<provider>.<name>.
+	Signals map[string]any `json:"signals,omitempty"`
+
+	// ThreatIntel is the blocklist entry the client IP matched, if any.
+	// Policies refer to it as threat.listed, threat.feed and
+	// threat.category.
+	ThreatIntel *ThreatIntel `json:"threat_intel,omitempty"`
+
+	Time time.Time `json:"time"`
+}
+
+// RiskSignal is one contribution to AccessContext.RiskScore.
+type RiskSignal struct {
+	Name   string `json:"name"`   // e.g. failed_logins
+	Score  int    `json:"score"`  // points added to the risk score
+	Detail string `json:"detail"` // e.g. "3 failed logins in the last hour"
+}
+
+// ThreatIntel is a match of the client IP in a threat-intelligence feed.
+type ThreatIntel struct {
+	Feed     string `json:"feed"`               // e.g. spamhaus-drop
+	Category string `json:"category,omitempty"` // e.g. botnet; CSV feeds only
+	Prefix   string `json:"prefix"`             // the listed CIDR
 }
 
 // Decision is the result of a policy evaluation.
 type Decision struct {
-	Allowed bool
-	Policy  string
-	Reason  string
+	Allowed bool `json:"allowed"`
+
+	// Policies lists every policy that contributed to the outcome; Policy
+	// is the first of them and is what access_logs.policy_name records.
+	Policy   string   `json:"policy"`
+	Policies []string `json:"policies"`
+
+	Reason  string `json:"reason"`
+	Version int64  `json:"version"` // policy_versions id, 0 for the built-in defaults
+
+	// Obligations and advice of the contributing policies (allows only).
+	// Callers must run Fulfil before acting on Allowed.
+	Obligations []Obligation `json:"obligations,omitempty"`
+
+	// Shadow is what the set would have decided with its shadow policies
+	// enforced. Nil when the set has no shadow policies.
+	Shadow *ShadowDecision `json:"shadow,omitempty"`
+}
+
+// ShadowDecision is the would-be outcome of a policy set's shadow policies.
+type ShadowDecision struct {
+	Allowed bool   `json:"allowed"`
+	Policy  string `json:"policy"`
+	Reason  string `json:"reason"`
 }
@@ -0,0 +1,36 @@
+package risk
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Login stages recorded in login_attempts.
+const (
+	StagePassword = "password"
+	StageMFA      = "mfa"
+)
+
+// LoginAttempt is one password or MFA check. UserID is 0 when the email
+// does not belong to any user.
+type LoginAttempt struct {
+	Email     string
+	UserID    int64
+	IP        string
+	Stage     string
+	Succeeded bool
+}
+
+// RecordLogin stores a login attempt for the failed_logins and
+// ip_reputation signals (best-effort).
+func RecordLogin(ctx context.Context, db *sql.DB, a LoginAttempt) {
+	var userID any
+	if a.UserID != 0 {
+		userID = a.UserID
+	}
+	_, _ = db.ExecContext(ctx,
+		`INSERT INTO login_attempts (email, user_id, ip, stage, succeeded)
+         VALUES ($1, $2, $3, $4, $5)`,
+		a.Email, userID, a.IP, a.Stage, a.Succeeded,
+	)
+}
@@ -0,0 +1,97 @@
+package risk
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"zero-trust-access-platform/backend/internal/policy"
+	"zero-trust-access-platform/backend/internal/signals"
+)
+
+// fixed is a provider that always reports the same severity.
+type fixed struct {
+	name     string
+	severity float64
+}
+
+func (f fixed) Name() string { return f.name }
+
+func (f fixed) Assess(context.Context, Request) (float64, string, error) {
+	return f.severity, f.name + " seen", nil
+}
+
+func TestSignals(t *testing.T) {
+	got := Signals(map[string]int{"a": 10, "b": 0}, fixed{name: "a"}, fixed{name: "b"}, fixed{name: "c"})
+	if len(got) != 1 || got[0].Provider.Name() != "a" || got[0].Weight != 10 {
+		t.Fatalf("Signals = %+v, want only a with weight 10", got)
+	}
+	if got[0].Name() != "risk_a" {
+		t.Errorf("Name = %q, want risk_a", got[0].Name())
+	}
+}
+
+func TestSignalScore(t *testing.T) {
+	tests := []struct {
+		name     string
+		signals  []Signal
+		start    int
+		want     int
+		wantSigs []policy.RiskSignal
+	}{
+		{
+			name:    "weighted severities add up",
+			signals: []Signal{{fixed{"failed_logins", 0.5}, 40}, {fixed{"new_device", 1}, 15}},
+			want:    35,
+			wantSigs: []policy.RiskSignal{
+				{Name: "failed_logins", Score: 20, Detail: "failed_logins seen"},
+				{Name: "new_device", Score: 15, Detail: "new_device seen"},
+			},
+		},
+		{
+			name:     "severity is capped at 1",
+			signals:  []Signal{{fixed{"deny_burst", 3}, 30}},
+			want:     30,
+			wantSigs: []policy.RiskSignal{{Name: "deny_burst", Score: 30, Detail: "deny_burst seen"}},
+		},
+		{
+			name:    "nothing unusual is not listed",
+			signals: []Signal{{fixed{"unusual_hour", 0}, 20}},
+			want:    0,
+		},
+		{
+			name:     "score is capped at MaxScore",
+			signals:  []Signal{{fixed{"ip_reputation", 1}, 50}},
+			start:    80,
+			want:     MaxScore,
+			wantSigs: []policy.RiskSignal{{Name: "ip_reputation", Score: 50, Detail: "ip_reputation seen"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ac := policy.AccessContext{UserID: 7, RiskScore: tt.start}
+			for _, s := range tt.signals {
+				attrs, err := s.Signals(context.Background(), ac, signals.Request{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				s.Apply(&ac, attrs)
+			}
+			if ac.RiskScore != tt.want {
+				t.Errorf("RiskScore = %d, want %d", ac.RiskScore, tt.want)
+			}
+			if !reflect.DeepEqual(ac.RiskSignals, tt.wantSigs) {
+				t.Errorf("RiskSignals = %+v, want %+v", ac.RiskSignals, tt.wantSigs)
+			}
+		})
+	}
+}
+
+func TestDenyQueriesSkipListings(t *testing.T) {
+	for name, q := range map[string]string{"ip_reputation": deniedUsersQuery, "deny_burst": denyBurstQuery} {
+		if !strings.Contains(q, "AND NOT (method = 'GET' AND path = '/resources')") {
+			t.Errorf("%s counts resource listing denies: %s", name, q)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package risk
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Signal names, as used in RISK_WEIGHTS and listed in risk.signals.
+const (
+	SignalFailedLogins = "failed_logins"
+	SignalNewDevice    = "new_device"
+	SignalUnusualHour  = "unusual_hour"
+	SignalIPReputation = "ip_reputation"
+	SignalDenyBurst    = "deny_burst"
+)
+
+// Providers returns the built-in providers, all backed by the database.
+func Providers(db *sql.DB) []Provider {
+	return []Provider{
+		FailedLogins{DB: db},
+		NewDevice{DB: db},
+		UnusualHour{DB: db},
+		IPReputation{DB: db},
+		DenyBurst{DB: db},
+	}
+}
+
+// FailedLogins counts the user's failed password and MFA attempts in the
+// last hour; five or more is full severity.
+type FailedLogins struct{ DB *sql.DB }
+
+func (FailedLogins) Name() string { return SignalFailedLogins }
+
+func (p FailedLogins) Assess(ctx context.Context, req Request) (float64, string, error) {
+	var n int
+	err := p.DB.QueryRowContext(ctx,
+		`SELECT count(*) FROM login_attempts
+         WHERE user_id = $1 AND NOT succeeded AND created_at > $2`,
+		req.UserID, req.Time.Add(-time.Hour),
+	).Scan(&n)
+	if err != nil || n == 0 {
+		return 0, "", err
+	}
+	return ratio(n, 5), plural(n, "failed login") + " in the last hour", nil
+}
+
+// NewDevice compares the request's user agent and IP with the user's
+// sessions from before the last day. A user agent never seen before is
+// full severity, a known one from a new IP half. Users without such
+// sessions yet have nothing to compare against and are not flagged.
+type NewDevice struct{ DB *sql.DB }
+
+func (NewDevice) Name() string { return SignalNewDevice }
+
+func (p NewDevice) Assess(ctx context.Context, req Request) (float64, string, error) {
+	var total, sameAgent, sameIP int
+	err := p.DB.QueryRowContext(ctx,
+		`SELECT count(*),
+                count(*) FILTER (WHERE user_agent = $2),
+                count(*) FILTER (WHERE ip = $3)
+         FROM user_sessions
+         WHERE user_id = $1 AND created_at < $4`,
+		req.UserID, req.UserAgent, req.IP, req.Time.Add(-24*time.Hour),
+	).Scan(&total, &sameAgent, &sameIP)
+	if err != nil || total == 0 {
+		return 0, "", err
+	}
+	switch {
+	case req.UserAgent != "" && sameAgent == 0:
+		return 1, "first sign-in from this browser or device", nil
+	case req.IP != "" && sameIP == 0:
+		return 0.5, "first sign-in from " + req.IP, nil
+	}
+	return 0, "", nil
+}
+
+// UnusualHour flags requests at an hour (UTC, give or take one) when the
+// user has made under 1% of their requests over the last 30 days. It needs
+// at least 50 logged requests to judge.
+type UnusualHour struct{ DB *sql.DB }
+
+func (UnusualHour) Name() string { return SignalUnusualHour }
+
+func (p UnusualHour) Assess(ctx context.Context, req Request) (float64, string, error) {
+	rows, err := p.DB.QueryContext(ctx,
+		`SELECT EXTRACT(HOUR FROM created_at AT TIME ZONE 'UTC')::int, count(*)
+         FROM access_logs
+         WHERE user_id = $1 AND created_at > $2
+         GROUP BY 1`,
+		req.UserID, req.Time.AddDate(0, 0, -30),
+	)
+	if err != nil {
+		return 0, "", err
+	}
+	defer rows.Close()
+
+	var byHour [24]int
+	total := 0
+	for rows.Next() {
+		var h, n int
+		if err := rows.Scan(&h, &n); err != nil {
+			return 0, "", err
+		}
+		byHour[h%24] = n
+		total += n
+	}
+	if err := rows.Err(); err != nil {
+		return 0, "", err
+	}
+	if total < 50 {
+		return 0, "", nil
+	}
+
+	h := req.Time.UTC().Hour()
+	near := byHour[(h+23)%24] + byHour[h] + byHour[(h+1)%24]
+	if float64(near) >= 0.01*float64(total) {
+		return 0, "", nil
+	}
+	return 1, fmt.Sprintf("%d of the user's %d requests in the last 30 days were around %02d:00 UTC", near, total, h), nil
+}
+
+// notListing leaves out the denies GET /resources logs for every resource
+// the user may not see; they say nothing about the user or the IP.
+const notListing = `NOT (method = 'GET' AND path = '/resources')`
+
+const deniedUsersQuery = `SELECT count(DISTINCT user_id) FROM access_logs
+         WHERE ip = $1 AND decision = 'deny' AND created_at > $2
+           AND ` + notListing
+
+const denyBurstQuery = `SELECT count(*) FROM access_logs
+         WHERE user_id = $1 AND decision = 'deny' AND created_at > $2
+           AND ` + notListing
+
+// IPReputation judges the client IP by the last day: failed logins from
+// it to any account (20 is full severity) and distinct users denied from
+// it (5 is full severity). Resource listings are left out (see
+// notListing), or everyone listing resources behind a shared office IP
+// would count.
+type IPReputation struct{ DB *sql.DB }
+
+func (IPReputation) Name() string { return SignalIPReputation }
+
+func (p IPReputation) Assess(ctx context.Context, req Request) (float64, string, error) {
+	if req.IP == "" {
+		return 0, "", nil
+	}
+	since := req.Time.Add(-24 * time.Hour)
+
+	var failed, deniedUsers int
+	err := p.DB.QueryRowContext(ctx,
+		`SELECT count(*) FROM login_attempts
+         WHERE ip = $1 AND NOT succeeded AND created_at > $2`,
+		req.IP, since,
+	).Scan(&failed)
+	if err != nil {
+		return 0, "", err
+	}
+	err = p.DB.QueryRowContext(ctx, deniedUsersQuery, req.IP, since).Scan(&deniedUsers)
+	if err != nil {
+		return 0, "", err
+	}
+
+	severity := max(ratio(failed, 20), ratio(deniedUsers, 5))
+	if severity < 0.2 {
+		return 0, "", nil
+	}
+	return severity, fmt.Sprintf("%s and %s denied from %s in the last day",
+		plural(failed, "failed login"), plural(deniedUsers, "user"), req.IP), nil
+}
+
+// DenyBurst counts the user's denied requests in the last 10 minutes;
+// three is where it starts, ten is full severity. Resource listings are
+// left out (see notListing).
+type DenyBurst struct{ DB *sql.DB }
+
+func (DenyBurst) Name() string { return SignalDenyBurst }
+
+func (p DenyBurst) Assess(ctx context.Context, req Request) (float64, string, error) {
+	var n int
+	err := p.DB.QueryRowContext(ctx, denyBurstQuery, req.UserID, req.Time.Add(-10*time.Minute)).Scan(&n)
+	if err != nil || n < 3 {
+		return 0, "", err
+	}
+	return ratio(n, 10), plural(n, "denied request") + " in the last 10 minutes", nil
+}
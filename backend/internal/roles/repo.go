@@ -0,0 +1,196 @@
+// Package roles stores the application roles users are assigned, their
+// inheritance and their permission sets.
+package roles
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/lib/pq"
+
+	"zero-trust-access-platform/backend/internal/policy"
+)
+
+// Role is an application role, e.g. "devops". A role has its own
+// permissions plus those of the roles it inherits, transitively.
+type Role struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Inherits    []string  `json:"inherits"`
+	Permissions []string  `json:"permissions"` // "<kind>:<name>", e.g. aws_role:ZTPortalReadOnlyLogs
+	Builtin     bool      `json:"builtin"`     // user, devops and admin cannot be deleted
+	UpdatedBy   *int64    `json:"updated_by"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ErrInUse is returned by Delete for a role that is still assigned to
+// users or inherited by another role.
+var ErrInUse = errors.New("role is in use")
+
+// ErrBuiltin is returned by Delete for a built-in role.
+var ErrBuiltin = errors.New("built-in roles cannot be deleted")
+
+var (
+	roleName   = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+	permission = regexp.MustCompile(`^[a-z][a-z0-9_]*:[A-Za-z0-9_.+=,@-]+$`)
+)
+
+// Repository provides DB access for roles.
+type Repository struct {
+	DB *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{DB: db}
+}
+
+// List returns all roles ordered by name.
+func (r *Repository) List(ctx context.Context) ([]Role, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+SELECT name, description, inherits, permissions, builtin, updated_by, updated_at
+FROM roles
+ORDER BY name;
+`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Role{}
+	for rows.Next() {
+		var role Role
+		if err := rows.Scan(
+			&role.Name,
+			&role.Description,
+			pq.Array(&role.Inherits),
+			pq.Array(&role.Permissions),
+			&role.Builtin,
+			&role.UpdatedBy,
+			&role.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		out = append(out, role)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Exists reports whether a role can be assigned to users.
+func (r *Repository) Exists(ctx context.Context, name string) (bool, error) {
+	var ok bool
+	err := r.DB.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM roles WHERE name = $1);`, name).Scan(&ok)
+	return ok, err
+}
+
+// Put creates or replaces a role. It must already be validated.
+func (r *Repository) Put(ctx context.Context, role Role, updatedBy int64) error {
+	_, err := r.DB.ExecContext(ctx, `
+INSERT INTO roles (name, description, inherits, permissions, updated_by, updated_at)
+VALUES ($1, $2, $3, $4, $5, NOW())
+ON CONFLICT (name) DO UPDATE
+SET description = EXCLUDED.description,
+    inherits = EXCLUDED.inherits,
+    permissions = EXCLUDED.permissions,
+    updated_by = EXCLUDED.updated_by,
+    updated_at = EXCLUDED.updated_at;
+`, role.Name, role.Description, pq.Array(role.Inherits), pq.Array(role.Permissions), updatedBy)
+	return err
+}
+
+// Delete removes a role, returning sql.ErrNoRows if it does not exist,
+// ErrBuiltin for user, devops and admin, and ErrInUse if users have it or
+// other roles inherit it.
+func (r *Repository) Delete(ctx context.Context, name string) error {
+	var builtin, inUse bool
+	err := r.DB.QueryRowContext(ctx, `
+SELECT builtin,
+       EXISTS (SELECT 1 FROM users WHERE role = $1)
+       OR EXISTS (SELECT 1 FROM roles WHERE $1 = ANY(inherits))
+FROM roles
+WHERE name = $1;
+`, name).Scan(&builtin, &inUse)
+	if err != nil {
+		return err
+	}
+	if builtin {
+		return ErrBuiltin
+	}
+	if inUse {
+		return ErrInUse
+	}
+
+	_, err = r.DB.ExecContext(ctx, `DELETE FROM roles WHERE name = $1;`, name)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23503" { // assigned meanwhile
+		return ErrInUse
+	}
+	return err
+}
+
+// Validate checks a new or changed role against the existing ones: names
+// and permissions are well-formed, inherited roles exist and the role does
+// not end up inheriting itself.
+func Validate(role Role, existing []Role) error {
+	if !roleName.MatchString(role.Name) {
+		return fmt.Errorf("role name %q must be lowercase letters, digits, '-' or '_'", role.Name)
+	}
+	for _, p := range role.Permissions {
+		if !permission.MatchString(p) {
+			return fmt.Errorf("permission %q must look like <kind>:<name>, e.g. aws_role:ZTPortalReadOnlyLogs", p)
+		}
+	}
+
+	byName := make(map[string]Role, len(existing)+1)
+	for _, e := range existing {
+		byName[e.Name] = e
+	}
+	byName[role.Name] = role
+
+	for _, parent := range role.Inherits {
+		if _, ok := byName[parent]; !ok {
+			return fmt.Errorf("inherited role %q does not exist", parent)
+		}
+	}
+
+	// Only the new edges can close a cycle, and any such cycle runs
+	// through role.
+	seen := map[string]bool{}
+	var reaches func(name string) bool
+	reaches = func(name string) bool {
+		if name == role.Name {
+			return true
+		}
+		if seen[name] {
+			return false
+		}
+		seen[name] = true
+		for _, parent := range byName[name].Inherits {
+			if reaches(parent) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, parent := range role.Inherits {
+		if reaches(parent) {
+			return fmt.Errorf("role %q would inherit itself through %q", role.Name, parent)
+		}
+	}
+	return nil
+}
+
+// Hierarchy converts roles for policy.SetRoles.
+func Hierarchy(list []Role) []policy.Role {
+	out := make([]policy.Role, len(list))
+	for i, r := range list {
+		out[i] = policy.Role{Name: r.Name, Inherits: r.Inherits, Permissions: r.Permissions}
+	}
+	return out
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"zero-trust-access-platform/backend/internal/policy"
+)
+
+type analyzeRequest struct {
+	Source string `json:"source"`
+}
+
+type analysisResponse struct {
+	Findings []policy.Finding `json:"findings"`
+	// BlocksActivation is set when any finding is an error.
+	BlocksActivation bool `json:"blocks_activation"`
+}
+
+// analyzePolicy runs the static analyzer against the current inventory.
+// Callers compile source first; a compile error here is returned as is.
+func (s *Server) analyzePolicy(ctx context.Context, source string) (*analysisResponse, error) {
+	doc, err := policy.ParseDocument([]byte(source))
+	if err != nil {
+		return nil, err
+	}
+	inv, err := policy.NewRepository(s.db).Inventory(ctx)
+	if err != nil {
+		return nil, err
+	}
+	findings, err := policy.Analyze(doc, inv)
+	if err != nil {
+		return nil, err
+	}
+	return &analysisResponse{Findings: findings, BlocksActivation: policy.HasErrors(findings)}, nil
+}
+
+// POST /admin/policy/analyze {source} -> findings for a draft policy document
+func (s *Server) handleAnalyzePolicy(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req analyzeRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxPolicySourceBytes)).Decode(&req); err != nil || req.Source == "" {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := policy.CompileSource([]byte(req.Source)); err != nil {
+		s.writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	res, err := s.analyzePolicy(r.Context(), req.Source)
+	if err != nil {
+		http.Error(w, "failed to analyze policy", http.StatusInternalServerError)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, res)
+}
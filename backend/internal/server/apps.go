@@ -0,0 +1,165 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"zero-trust-access-platform/backend/internal/apps"
+	"zero-trust-access-platform/backend/internal/middleware"
+)
+
+// appRefreshInterval is how often the app table is re-read so that edits
+// made on another replica take effect without a restart.
+const appRefreshInterval = time.Minute
+
+type appRequest struct {
+	Host       string `json:"host"`
+	PathPrefix string `json:"path_prefix"`
+	ResourceID int64  `json:"resource_id"`
+	Upstream   string `json:"upstream"`
+}
+
+// loadApps makes the stored host/path mappings available to forward auth.
+func (s *Server) loadApps(ctx context.Context) error {
+	list, err := apps.NewRepository(s.db).List(ctx)
+	if err != nil {
+		return err
+	}
+	s.apps.Store(apps.NewTable(list))
+	return nil
+}
+
+func (s *Server) refreshApps() {
+	for range time.Tick(appRefreshInterval) {
+		if err := s.loadApps(context.Background()); err != nil {
+			log.Printf("refresh protected apps: %v", err)
+		}
+	}
+}
+
+// GET  /admin/apps
+// POST /admin/apps {host, path_prefix, resource_id, upstream}
+func (s *Server) handleApps(w http.ResponseWriter, r *http.Request) {
+	repo := apps.NewRepository(s.db)
+
+	switch r.Method {
+	case http.MethodGet:
+		list, err := repo.List(r.Context())
+		if err != nil {
+			http.Error(w, "failed to list apps", http.StatusInternalServerError)
+			return
+		}
+		s.writeJSON(w, http.StatusOK, list)
+
+	case http.MethodPost:
+		s.saveApp(w, r, 0)
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// PUT    /admin/apps/{id} {host, path_prefix, resource_id, upstream}
+// DELETE /admin/apps/{id}
+// Changes take effect immediately on this replica and within a minute on others.
+func (s *Server) handleApp(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) != 3 || parts[0] != "admin" || parts[1] != "apps" {
+		http.NotFound(w, r)
+		return
+	}
+	id, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid app id", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		s.saveApp(w, r, id)
+
+	case http.MethodDelete:
+		err := apps.NewRepository(s.db).Delete(r.Context(), id)
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "app not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "failed to delete app", http.StatusInternalServerError)
+			return
+		}
+		if err := s.loadApps(r.Context()); err != nil {
+			http.Error(w, "failed to reload apps", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// saveApp creates (id == 0) or updates an app from the request body.
+func (s *Server) saveApp(w http.ResponseWriter, r *http.Request, id int64) {
+	userID, ok := middleware.UserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req appRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	a := apps.App{ID: id, Host: req.Host, PathPrefix: req.PathPrefix, ResourceID: req.ResourceID, Upstream: req.Upstream}
+	if err := a.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	repo := apps.NewRepository(s.db)
+	status := http.StatusOK
+	if id == 0 {
+		err := repo.Create(r.Context(), &a, userID)
+		status = http.StatusCreated
+		if !s.appSaved(w, err) {
+			return
+		}
+	} else if !s.appSaved(w, repo.Update(r.Context(), &a, userID)) {
+		return
+	}
+
+	if err := s.loadApps(r.Context()); err != nil {
+		http.Error(w, "failed to reload apps", http.StatusInternalServerError)
+		return
+	}
+	s.writeJSON(w, status, a)
+}
+
+// appSaved reports whether err is nil, answering the request otherwise.
+func (s *Server) appSaved(w http.ResponseWriter, err error) bool {
+	var pqErr *pq.Error
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, sql.ErrNoRows):
+		http.Error(w, "app not found", http.StatusNotFound)
+	case errors.As(err, &pqErr) && pqErr.Code == "23505":
+		http.Error(w, "an app with this host and path prefix already exists", http.StatusConflict)
+	case errors.As(err, &pqErr) && pqErr.Code == "23503":
+		http.Error(w, "resource not found", http.StatusBadRequest)
+	default:
+		http.Error(w, "failed to save app", http.StatusInternalServerError)
+	}
+	return false
+}
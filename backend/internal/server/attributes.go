@@ -0,0 +1,72 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"zero-trust-access-platform/backend/internal/attributes"
+	"zero-trust-access-platform/backend/internal/policy"
+)
+
+// GET /admin/users/{id}/attributes
+// PUT /admin/users/{id}/attributes  {"department": "finance", ...}
+func (s *Server) handleUserAttributes(w http.ResponseWriter, r *http.Request, userID int64) {
+	s.serveAttributes(w, r, attributes.User, "user", userID)
+}
+
+// GET /admin/resources/{id}/attributes
+// PUT /admin/resources/{id}/attributes  {"owner_team": "finance", "tags": "pci,customer-data"}
+func (s *Server) handleResourceAttributes(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) != 4 || parts[0] != "admin" || parts[1] != "resources" || parts[3] != "attributes" {
+		http.NotFound(w, r)
+		return
+	}
+
+	id, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid resource id", http.StatusBadRequest)
+		return
+	}
+
+	s.serveAttributes(w, r, attributes.Resource, "resource", id)
+}
+
+func (s *Server) serveAttributes(w http.ResponseWriter, r *http.Request, kind attributes.Kind, scope string, id int64) {
+	repo := attributes.NewRepository(s.db)
+
+	switch r.Method {
+	case http.MethodGet:
+		attrs, err := repo.Get(r.Context(), kind, id)
+		if err != nil {
+			http.Error(w, "failed to load attributes", http.StatusInternalServerError)
+			return
+		}
+		s.writeJSON(w, http.StatusOK, attrs)
+
+	case http.MethodPut:
+		var attrs map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&attrs); err != nil {
+			http.Error(w, "invalid body", http.StatusBadRequest)
+			return
+		}
+		for k := range attrs {
+			if err := policy.ValidateAttributeKey(scope, k); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		// FK violations (unknown id) surface as a failed insert.
+		if err := repo.Replace(r.Context(), kind, id, attrs); err != nil {
+			http.Error(w, "failed to save attributes", http.StatusInternalServerError)
+			return
+		}
+		s.writeJSON(w, http.StatusOK, attrs)
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"database/sql"
 	"encoding/json"
 	"net/http"
-	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/pquerna/otp/totp"
@@ -12,6 +11,9 @@ import (
 
 	"zero-trust-access-platform/backend/internal/middleware"
 	"zero-trust-access-platform/backend/internal/models"
+	"zero-trust-access-platform/backend/internal/policy"
+	"zero-trust-access-platform/backend/internal/risk"
+	"zero-trust-access-platform/backend/internal/sessions"
 )
 
 type signupRequest struct {
@@ -34,13 +36,69 @@ type mfaVerifyRequest struct {
 	Code string `json:"code"`
 }
 
-// shared helper to build a JWT for a user
-func (s *Server) generateToken(u models.User) (string, error) {
+// shared helper to build a JWT for a user.
+// Every token is backed by a user_sessions row (its "jti") so it can be
+// listed and revoked before it expires. Lifetime, idle timeout and the
+// concurrent session limit come from the role's session limits.
+//
+// mfa marks tokens issued after a successful MFA check; forward auth only
+// accepts those.
+func (s *Server) generateToken(r *http.Request, u models.User, mfa bool) (string, error) {
+	jti, err := sessions.NewID()
+	if err != nil {
+		return "", err
+	}
+
+	sess := sessions.Session{
+		ID:        jti,
+		UserID:    u.ID,
+		IP:        middleware.ClientIP(r),
+		UserAgent: r.UserAgent(),
+		MFA:       mfa,
+	}
+	if err := s.sessions.Create(r.Context(), &sess, u.Role); err != nil {
+		return "", err
+	}
+
+	return s.signSession(sess, u.Role, mfa, "")
+}
+
+// hostToken issues the cookie token for a redeemed login ticket: a new
+// session minted from parentID and scoped to host (the "aud" claim).
+func (s *Server) hostToken(r *http.Request, parentID, host string) (string, error) {
+	jti, err := sessions.NewID()
+	if err != nil {
+		return "", err
+	}
+	sess := sessions.Session{
+		ID:        jti,
+		IP:        middleware.ClientIP(r),
+		UserAgent: r.UserAgent(),
+	}
+	if err := s.sessions.CreateForHost(r.Context(), &sess, parentID, host); err != nil {
+		return "", err
+	}
+
+	var role string
+	if err := s.db.QueryRowContext(r.Context(), `SELECT role FROM users WHERE id = $1`, sess.UserID).Scan(&role); err != nil {
+		return "", err
+	}
+	return s.signSession(sess, role, true, host)
+}
+
+// signSession signs the JWT backed by sess. host, if set, scopes the token
+// to one app host.
+func (s *Server) signSession(sess sessions.Session, role string, mfa bool, host string) (string, error) {
 	claims := jwt.MapClaims{
-		"sub":  u.ID,
-		"role": u.Role,
-		"exp":  time.Now().Add(24 * time.Hour).Unix(),
-		"iat":  time.Now().Unix(),
+		"sub":  sess.UserID,
+		"role": role,
+		"jti":  sess.ID,
+		"exp":  sess.ExpiresAt.Unix(),
+		"iat":  sess.CreatedAt.Unix(),
+		"mfa":  mfa,
+	}
+	if host != "" {
+		claims["aud"] = host
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString(s.jwtSecret)
@@ -63,6 +121,13 @@ func (s *Server) handleSignup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// A new account has no MFA yet, so from a listed network it could go
+	// no further than login allows (deny, or no enrollment under step_up).
+	if s.loginThreat(r) != nil {
+		http.Error(w, "sign-up from this network is not allowed", http.StatusForbidden)
+		return
+	}
+
 	hashed, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
 		http.Error(w, "failed to hash password", http.StatusInternalServerError)
@@ -83,12 +148,13 @@ func (s *Server) handleSignup(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Do NOT issue a long‑lived token here; user must still enroll+verify MFA
-	token, err := s.generateToken(u)
+	token, err := s.generateToken(r, u, false)
 	if err != nil {
 		http.Error(w, "failed to create token", http.StatusInternalServerError)
 		return
 	}
 
+	u.Roles = policy.EffectiveRoles(u.Role)
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(authResponse{
 		Token: token,
@@ -114,6 +180,8 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	threat := s.loginThreat(r)
+
 	var u models.User
 	var passwordHash string
 	var mfaEnabled bool
@@ -125,7 +193,21 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
          WHERE email = $1`,
 		req.Email,
 	).Scan(&u.ID, &u.Email, &u.FullName, &u.Role, &passwordHash, &u.CreatedAt, &mfaEnabled, &mfaSecret)
+	if threat != nil && s.cfg.ThreatIntelLogin == threatLoginDeny {
+		// Refused before the password check, and alike for unknown
+		// emails, so the answer reveals nothing about the account.
+		if err == nil {
+			s.logLoginThreat(r, threat, u, false)
+		}
+		http.Error(w, "sign-in from this network is not allowed", http.StatusForbidden)
+		return
+	}
 	if err == sql.ErrNoRows {
+		risk.RecordLogin(r.Context(), s.db, risk.LoginAttempt{
+			Email: req.Email,
+			IP:    middleware.ClientIP(r),
+			Stage: risk.StagePassword,
+		})
 		http.Error(w, "invalid credentials", http.StatusUnauthorized)
 		return
 	} else if err != nil {
@@ -133,22 +215,37 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	attempt := risk.LoginAttempt{Email: u.Email, UserID: u.ID, IP: middleware.ClientIP(r), Stage: risk.StagePassword}
 	if err := bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(req.Password)); err != nil {
+		risk.RecordLogin(r.Context(), s.db, attempt)
 		http.Error(w, "invalid credentials", http.StatusUnauthorized)
 		return
 	}
+	attempt.Succeeded = true
+	risk.RecordLogin(r.Context(), s.db, attempt)
 
 	// MFA is mandatory for everyone.
 	// If there is no stored secret yet, client must enroll before verifying.
 	enrollmentRequired := !mfaSecret.Valid || mfaSecret.String == ""
 
+	// From a listed network, only users who already have MFA may go on:
+	// a stolen password must not be enough to enroll an attacker's device.
+	if threat != nil {
+		s.logLoginThreat(r, threat, u, !enrollmentRequired)
+		if enrollmentRequired {
+			http.Error(w, "sign-in from this network is not allowed", http.StatusForbidden)
+			return
+		}
+	}
+
 	// Issue a short‑lived temp token used only for MFA enroll/verify calls.
-	tempToken, err := s.generateToken(u)
+	tempToken, err := s.generateToken(r, u, false)
 	if err != nil {
 		http.Error(w, "failed to create temp token", http.StatusInternalServerError)
 		return
 	}
 
+	u.Roles = policy.EffectiveRoles(u.Role)
 	s.writeJSON(w, http.StatusOK, map[string]any{
 		"mfa_required":        true,
 		"enrollment_required": enrollmentRequired,
@@ -245,10 +342,14 @@ func (s *Server) handleMFAVerify(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	attempt := risk.LoginAttempt{UserID: userID, IP: middleware.ClientIP(r), Stage: risk.StageMFA}
 	if !totp.Validate(req.Code, secret) {
+		risk.RecordLogin(r.Context(), s.db, attempt)
 		http.Error(w, "invalid code", http.StatusUnauthorized)
 		return
 	}
+	attempt.Succeeded = true
+	risk.RecordLogin(r.Context(), s.db, attempt)
 
 	// enable MFA
 	_, err = s.db.Exec(
@@ -272,12 +373,18 @@ func (s *Server) handleMFAVerify(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, err := s.generateToken(u)
+	token, err := s.generateToken(r, u, true)
 	if err != nil {
 		http.Error(w, "failed to create token", http.StatusInternalServerError)
 		return
 	}
 
+	// The temp token used for this call has served its purpose.
+	if sid, ok := middleware.SessionID(r); ok {
+		_ = s.sessions.Revoke(r.Context(), userID, sid)
+	}
+
+	u.Roles = policy.EffectiveRoles(u.Role)
 	s.writeJSON(w, http.StatusOK, authResponse{
 		Token: token,
 		User:  u,
@@ -0,0 +1,285 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"zero-trust-access-platform/backend/internal/middleware"
+	"zero-trust-access-platform/backend/internal/policy"
+	"zero-trust-access-platform/backend/internal/signals"
+)
+
+// maxAuthzenBatch bounds the number of evaluations in one batch request.
+const maxAuthzenBatch = 100
+
+// AuthZEN batch semantics (options.evaluations_semantic).
+const (
+	authzenExecuteAll          = "execute_all"
+	authzenDenyOnFirstDeny     = "deny_on_first_deny"
+	authzenPermitOnFirstPermit = "permit_on_first_permit"
+)
+
+// authzenEntity is an AuthZEN subject or resource. Subjects are platform
+// users, identified by numeric ID or email; resources are registered
+// resources, identified by name. Properties are accepted for compatibility
+// but ignored: the platform's own records are authoritative.
+type authzenEntity struct {
+	Type       string         `json:"type"`
+	ID         string         `json:"id"`
+	Properties map[string]any `json:"properties,omitempty"`
+}
+
+type authzenAction struct {
+	Name       string         `json:"name"`
+	Properties map[string]any `json:"properties,omitempty"`
+}
+
+// authzenContext is the request context; only the end user's IP is used.
+type authzenContext struct {
+	IP string `json:"ip,omitempty"`
+}
+
+type authzenRequest struct {
+	Subject  *authzenEntity  `json:"subject,omitempty"`
+	Resource *authzenEntity  `json:"resource,omitempty"`
+	Action   *authzenAction  `json:"action,omitempty"`
+	Context  *authzenContext `json:"context,omitempty"`
+}
+
+type authzenBatchRequest struct {
+	authzenRequest
+	Evaluations []authzenRequest `json:"evaluations"`
+	Options     struct {
+		Semantic string `json:"evaluations_semantic"`
+	} `json:"options"`
+}
+
+type authzenResponse struct {
+	Decision bool                   `json:"decision"`
+	Context  authzenDecisionContext `json:"context"`
+}
+
+// authzenDecisionContext explains the decision. Obligations are returned
+// for the calling service (the policy enforcement point) to fulfil.
+type authzenDecisionContext struct {
+	ID          string              `json:"id"`
+	ReasonAdmin map[string]string   `json:"reason_admin"`
+	Obligations []policy.Obligation `json:"obligations,omitempty"`
+}
+
+type authzenBatchResponse struct {
+	Evaluations []authzenResponse `json:"evaluations"`
+}
+
+// GET /.well-known/authzen-configuration
+func (s *Server) handleAuthzenConfiguration(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	base := middleware.Scheme(r) + "://" + r.Host
+	s.writeJSON(w, http.StatusOK, map[string]string{
+		"policy_decision_point":       base,
+		"access_evaluation_endpoint":  base + "/access/v1/evaluation",
+		"access_evaluations_endpoint": base + "/access/v1/evaluations",
+	})
+}
+
+// POST /access/v1/evaluation  (service clients)
+func (s *Server) handleAuthzenEvaluation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req authzenRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&req); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	if err := req.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := s.authzenEvaluate(r, req)
+	if err != nil {
+		http.Error(w, "failed to evaluate request", http.StatusInternalServerError)
+		return
+	}
+	echoRequestID(w, r)
+	s.writeJSON(w, http.StatusOK, resp)
+}
+
+// POST /access/v1/evaluations  (service clients)
+//
+// Each entry in evaluations inherits subject, resource, action and context
+// from the top level unless it sets its own.
+func (s *Server) handleAuthzenEvaluations(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req authzenBatchRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	if len(req.Evaluations) == 0 {
+		req.Evaluations = []authzenRequest{{}}
+	}
+	if len(req.Evaluations) > maxAuthzenBatch {
+		http.Error(w, "too many evaluations", http.StatusBadRequest)
+		return
+	}
+	switch req.Options.Semantic {
+	case "", authzenExecuteAll, authzenDenyOnFirstDeny, authzenPermitOnFirstPermit:
+	default:
+		http.Error(w, "unknown evaluations_semantic", http.StatusBadRequest)
+		return
+	}
+
+	evals := make([]authzenRequest, len(req.Evaluations))
+	for i, e := range req.Evaluations {
+		evals[i] = e.inherit(req.authzenRequest)
+		if err := evals[i].validate(); err != nil {
+			http.Error(w, "evaluations["+strconv.Itoa(i)+"]: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	out := authzenBatchResponse{Evaluations: make([]authzenResponse, 0, len(evals))}
+	for _, e := range evals {
+		resp, err := s.authzenEvaluate(r, e)
+		if err != nil {
+			http.Error(w, "failed to evaluate request", http.StatusInternalServerError)
+			return
+		}
+		out.Evaluations = append(out.Evaluations, resp)
+
+		if (req.Options.Semantic == authzenDenyOnFirstDeny && !resp.Decision) ||
+			(req.Options.Semantic == authzenPermitOnFirstPermit && resp.Decision) {
+			break
+		}
+	}
+	echoRequestID(w, r)
+	s.writeJSON(w, http.StatusOK, out)
+}
+
+func (e authzenRequest) inherit(def authzenRequest) authzenRequest {
+	if e.Subject == nil {
+		e.Subject = def.Subject
+	}
+	if e.Resource == nil {
+		e.Resource = def.Resource
+	}
+	if e.Action == nil {
+		e.Action = def.Action
+	}
+	if e.Context == nil {
+		e.Context = def.Context
+	}
+	return e
+}
+
+func (e authzenRequest) validate() error {
+	switch {
+	case e.Subject == nil || e.Subject.ID == "":
+		return errors.New("subject.id is required")
+	case e.Resource == nil || e.Resource.ID == "":
+		return errors.New("resource.id is required")
+	case e.Action == nil || e.Action.Name == "":
+		return errors.New("action.name is required")
+	case e.Subject.Type != "" && e.Subject.Type != "user":
+		return errors.New(`subject.type must be "user"`)
+	}
+	return nil
+}
+
+// authzenEvaluate runs one evaluation and logs it. Unknown subjects and
+// resources are denied rather than reported as errors, so a caller cannot
+// tell them apart from a policy deny.
+func (s *Server) authzenEvaluate(r *http.Request, e authzenRequest) (authzenResponse, error) {
+	ac, d, err := s.authzenDecide(r.Context(), e)
+	if err != nil {
+		return authzenResponse{}, err
+	}
+	s.logAccess(r, ac, d)
+
+	return authzenResponse{
+		Decision: d.Allowed,
+		Context: authzenDecisionContext{
+			ID:          d.Policy,
+			ReasonAdmin: map[string]string{"en": d.Reason},
+			Obligations: d.Obligations,
+		},
+	}, nil
+}
+
+func (s *Server) authzenDecide(ctx context.Context, e authzenRequest) (policy.AccessContext, policy.Decision, error) {
+	unknown := func(ac policy.AccessContext, what string) (policy.AccessContext, policy.Decision, error) {
+		return ac, policy.Decision{
+			Allowed:  false,
+			Policy:   "unknown-" + what,
+			Policies: []string{},
+			Reason:   what + " is not registered with the platform",
+		}, nil
+	}
+
+	fallback := policy.AccessContext{ResourceName: e.Resource.ID, Action: e.Action.Name}
+
+	userID, err := s.resolveUserID(ctx, e.Subject.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return unknown(fallback, "subject")
+	}
+	if err != nil {
+		return fallback, policy.Decision{}, err
+	}
+
+	ac, err := s.lookupAccessContext(ctx, userID, e.Resource.ID, e.Action.Name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return unknown(ac, "resource")
+	}
+	if err != nil {
+		return ac, policy.Decision{}, err
+	}
+	if e.Resource.Type != "" && e.Resource.Type != ac.ResourceType {
+		return ac, policy.Decision{
+			Allowed:  false,
+			Policy:   "resource-type-mismatch",
+			Policies: []string{},
+			Reason:   "resource " + ac.ResourceName + " is a " + ac.ResourceType + ", not a " + e.Resource.Type,
+		}, nil
+	}
+	if e.Context != nil {
+		ac.ClientIP = e.Context.IP
+	}
+	if err := s.signals.Enrich(ctx, &ac, signals.Request{}); err != nil {
+		return ac, signals.Denied(err), nil
+	}
+	return ac, policy.Evaluate(ac), nil
+}
+
+// resolveUserID accepts a numeric user ID or an email address. It returns
+// sql.ErrNoRows if there is no such user.
+func (s *Server) resolveUserID(ctx context.Context, subject string) (int64, error) {
+	var id int64
+	err := s.db.QueryRowContext(ctx,
+		`SELECT id FROM users WHERE id::text = $1 OR email = $1`,
+		subject,
+	).Scan(&id)
+	return id, err
+}
+
+// echoRequestID returns the caller's X-Request-ID, as AuthZEN asks.
+func echoRequestID(w http.ResponseWriter, r *http.Request) {
+	if id := r.Header.Get("X-Request-ID"); id != "" {
+		w.Header().Set("X-Request-ID", id)
+	}
+}
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/lib/pq"
+
+	"zero-trust-access-platform/backend/internal/db"
+	"zero-trust-access-platform/backend/internal/policy"
+)
+
+// policyChangedChannel is notified by the triggers in migration 014 with
+// the name of the table that changed.
+const policyChangedChannel = "zt_policy_changed"
+
+// setupDecisionCache enables the decision cache and starts listening for
+// changes made on any replica. The listener also reloads policies, zones,
+// roles and DB-stored Rego modules, so activations take effect everywhere
+// without waiting for a restart or a refresh tick.
+func (s *Server) setupDecisionCache() error {
+	policy.EnableCache(s.cfg.DecisionCacheSize, s.cfg.DecisionCacheTTL)
+
+	l := pq.NewListener(db.DSN(s.cfg), 10*time.Second, time.Minute,
+		func(ev pq.ListenerEventType, err error) {
+			if err != nil {
+				log.Printf("policy change listener: %v", err)
+			}
+		})
+	if err := l.Listen(policyChangedChannel); err != nil {
+		l.Close()
+		return err
+	}
+	go s.watchPolicyChanges(l)
+	return nil
+}
+
+func (s *Server) watchPolicyChanges(l *pq.Listener) {
+	for {
+		select {
+		case n := <-l.Notify:
+			if n == nil {
+				// The connection was re-established; notifications sent
+				// in the meantime are lost, so assume everything changed.
+				s.applyPolicyChange("")
+				continue
+			}
+			s.applyPolicyChange(n.Extra)
+		case <-time.After(90 * time.Second):
+			go l.Ping()
+		}
+	}
+}
+
+// applyPolicyChange reloads what table affects ("" for everything) and
+// drops the cached decisions.
+func (s *Server) applyPolicyChange(table string) {
+	ctx := context.Background()
+	if table == "" || table == "policy_activations" {
+		if err := s.loadPolicies(ctx); err != nil {
+			log.Printf("reload policies: %v", err)
+		}
+	}
+	if table == "" || table == "network_zones" {
+		if err := s.loadZones(ctx); err != nil {
+			log.Printf("reload network zones: %v", err)
+		}
+	}
+	if table == "" || table == "roles" {
+		if err := s.loadRoles(ctx); err != nil {
+			log.Printf("reload roles: %v", err)
+		}
+	}
+	if (table == "" || table == "rego_modules") && s.usingRego() && s.cfg.RegoBundleDir == "" {
+		if err := s.reloadRego(ctx); err != nil {
+			log.Printf("reload rego policy bundle: %v", err)
+		}
+	}
+	policy.InvalidateCache()
+}
+
+// GET    /admin/policy/cache -> decision cache hit/miss statistics
+// DELETE /admin/policy/cache -> drop every cached decision on this replica
+func (s *Server) handleDecisionCache(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.writeJSON(w, http.StatusOK, policy.Cache())
+	case http.MethodDelete:
+		policy.InvalidateCache()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
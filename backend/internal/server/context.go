@@ -0,0 +1,45 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"zero-trust-access-platform/backend/internal/attributes"
+	"zero-trust-access-platform/backend/internal/policy"
+)
+
+// lookupAccessContext builds the policy input for a user acting on a named
+// resource from what is stored in the database. It returns sql.ErrNoRows if
+// either the user or the resource does not exist.
+func (s *Server) lookupAccessContext(ctx context.Context, userID int64, resourceName, action string) (policy.AccessContext, error) {
+	ac := policy.AccessContext{
+		UserID:       userID,
+		ResourceName: resourceName,
+		Action:       action,
+		Time:         time.Now(),
+	}
+
+	err := s.db.QueryRowContext(ctx,
+		`SELECT role, mfa_enabled FROM users WHERE id = $1`,
+		userID,
+	).Scan(&ac.UserRole, &ac.MFAEnabled)
+	if err != nil {
+		return ac, err
+	}
+
+	var resourceID int64
+	err = s.db.QueryRowContext(ctx,
+		`SELECT id, type, sensitivity FROM resources WHERE name = $1`,
+		resourceName,
+	).Scan(&resourceID, &ac.ResourceType, &ac.Sensitivity)
+	if err != nil {
+		return ac, err
+	}
+
+	attrRepo := attributes.NewRepository(s.db)
+	if ac.UserAttributes, err = attrRepo.Get(ctx, attributes.User, userID); err != nil {
+		return ac, err
+	}
+	ac.ResourceAttributes, err = attrRepo.Get(ctx, attributes.Resource, resourceID)
+	return ac, err
+}
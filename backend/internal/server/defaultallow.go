@@ -0,0 +1,75 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"zero-trust-access-platform/backend/internal/policy"
+)
+
+type defaultAllowResponse struct {
+	VersionID int64     `json:"version_id"`
+	Since     time.Time `json:"since"`
+	policy.DefaultAllowReport
+}
+
+// GET /admin/policy/default-allow?days=7[&version_id=N]
+// Migration report for deny_by_default: which logged requests the active
+// (or given) policy version only allows through an unconditional allow
+// policy, grouped by role, resource and action, with a suggested explicit
+// allow policy for each group.
+func (s *Server) handleDefaultAllowReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	days := 7
+	if v := r.URL.Query().Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxReplayDays {
+			http.Error(w, "invalid days", http.StatusBadRequest)
+			return
+		}
+		days = n
+	}
+	since := time.Now().AddDate(0, 0, -days)
+
+	set := policy.ActiveSet()
+	if v := r.URL.Query().Get("version_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid version_id", http.StatusBadRequest)
+			return
+		}
+		ver, err := policy.NewRepository(s.db).Get(r.Context(), id)
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "policy version not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "failed to load policy version", http.StatusInternalServerError)
+			return
+		}
+		set, err = policy.CompileVersion(ver)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+	}
+
+	scan := policy.NewDefaultAllowScan(set)
+	if err := s.scanLogs(r.Context(), since, scan.Add); err != nil {
+		http.Error(w, "failed to read logs", http.StatusInternalServerError)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, defaultAllowResponse{
+		VersionID:          set.Version,
+		Since:              since,
+		DefaultAllowReport: scan.Report(),
+	})
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"context"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	"zero-trust-access-platform/backend/internal/middleware"
+)
+
+// extAuthz implements Envoy's external authorization gRPC service on top
+// of forwardAuthorize.
+type extAuthz struct {
+	authv3.UnimplementedAuthorizationServer
+	s *Server
+}
+
+// serveExtAuthz runs the ext_authz gRPC service on addr until it fails.
+func (s *Server) serveExtAuthz(addr string) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	srv := grpc.NewServer()
+	authv3.RegisterAuthorizationServer(srv, &extAuthz{s: s})
+	log.Printf("envoy ext_authz listening on %s", addr)
+	return srv.Serve(lis)
+}
+
+func (e *extAuthz) Check(ctx context.Context, req *authv3.CheckRequest) (*authv3.CheckResponse, error) {
+	attrs := req.GetAttributes()
+	h := attrs.GetRequest().GetHttp()
+
+	// Envoy passes headers lower-cased; a throwaway request lets us reuse
+	// the usual token extraction (Authorization header or cookie).
+	hdr := http.Header{}
+	for k, v := range h.GetHeaders() {
+		hdr.Set(k, v)
+	}
+	token, _ := middleware.SessionToken(&http.Request{Header: hdr})
+
+	path := h.GetPath()
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+
+	res, err := e.s.forwardAuthorize(ctx, forwardRequest{
+		Host:          h.GetHost(),
+		Path:          path,
+		Method:        h.GetMethod(),
+		IP:            attrs.GetSource().GetAddress().GetSocketAddress().GetAddress(),
+		Token:         token,
+		UserAgent:     hdr.Get("User-Agent"),
+		Justification: hdr.Get(headerJustification),
+		MFACode:       hdr.Get(headerMFACode),
+		Proxy:         "envoy",
+	})
+	if err != nil {
+		log.Printf("ext_authz check: %v", err)
+		return denied(codes.Unavailable, typev3.StatusCode_ServiceUnavailable, "failed to evaluate request"), nil
+	}
+
+	switch res.Status {
+	case http.StatusOK:
+		// The justification and MFA code are for us, not the upstream.
+		ok := &authv3.OkHttpResponse{
+			HeadersToRemove: []string{headerJustification, headerMFACode},
+		}
+		for k, v := range res.Headers {
+			ok.Headers = append(ok.Headers, &corev3.HeaderValueOption{
+				Header:       &corev3.HeaderValue{Key: k, Value: v},
+				AppendAction: corev3.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD,
+			})
+		}
+		return &authv3.CheckResponse{
+			Status:       &status.Status{Code: int32(codes.OK)},
+			HttpResponse: &authv3.CheckResponse_OkResponse{OkResponse: ok},
+		}, nil
+	case http.StatusUnauthorized:
+		if u, ok := e.s.loginURL(h.GetScheme(), h.GetHost(), h.GetPath(), h.GetMethod(), hdr.Get("Accept")); ok {
+			return loginRedirect(u), nil
+		}
+		return denied(codes.Unauthenticated, typev3.StatusCode_Unauthorized, res.Reason), nil
+	default:
+		return denied(codes.PermissionDenied, typev3.StatusCode_Forbidden, res.Reason), nil
+	}
+}
+
+func denied(code codes.Code, httpStatus typev3.StatusCode, reason string) *authv3.CheckResponse {
+	return &authv3.CheckResponse{
+		Status: &status.Status{Code: int32(code), Message: reason},
+		HttpResponse: &authv3.CheckResponse_DeniedResponse{
+			DeniedResponse: &authv3.DeniedHttpResponse{
+				Status: &typev3.HttpStatus{Code: httpStatus},
+				Headers: []*corev3.HeaderValueOption{{
+					Header:       &corev3.HeaderValue{Key: "Content-Type", Value: "text/plain; charset=utf-8"},
+					AppendAction: corev3.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD,
+				}},
+				Body: reason + "\n",
+			},
+		},
+	}
+}
+
+// loginRedirect sends a browser without a session to the login page.
+func loginRedirect(location string) *authv3.CheckResponse {
+	return &authv3.CheckResponse{
+		Status: &status.Status{Code: int32(codes.Unauthenticated), Message: "login required"},
+		HttpResponse: &authv3.CheckResponse_DeniedResponse{
+			DeniedResponse: &authv3.DeniedHttpResponse{
+				Status: &typev3.HttpStatus{Code: typev3.StatusCode_Found},
+				Headers: []*corev3.HeaderValueOption{{
+					Header:       &corev3.HeaderValue{Key: "Location", Value: location},
+					AppendAction: corev3.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD,
+				}},
+			},
+		},
+	}
+}
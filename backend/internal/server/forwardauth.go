@@ -0,0 +1,203 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"zero-trust-access-platform/backend/internal/apps"
+	"zero-trust-access-platform/backend/internal/audit"
+	"zero-trust-access-platform/backend/internal/middleware"
+	"zero-trust-access-platform/backend/internal/obligations"
+	"zero-trust-access-platform/backend/internal/policy"
+	"zero-trust-access-platform/backend/internal/signals"
+)
+
+// Identity headers set on allowed requests for the upstream app. Proxies
+// must strip any client-supplied copies.
+const (
+	headerUserID    = "X-ZT-User-Id"
+	headerUserEmail = "X-ZT-User-Email"
+	headerUserRole  = "X-ZT-User-Role"
+	headerResource  = "X-ZT-Resource"
+	headerPolicy    = "X-ZT-Policy"
+
+	// headerJustification lets a user satisfy require_justification, and
+	// headerMFACode step_up_mfa.
+	headerJustification = "X-ZT-Justification"
+	headerMFACode       = "X-ZT-MFA-Code"
+)
+
+// forwardRequest is a request a proxy is asking about.
+type forwardRequest struct {
+	Host, Path, Method string
+	IP                 string
+	Token              string // platform JWT from the Authorization header or cookie
+	UserAgent          string
+	Justification      string
+	MFACode            string
+	Proxy              string // recorded as access_logs.service_client
+}
+
+// forwardResult is the answer for the proxy: 200 with identity headers, 401
+// without a valid MFA-backed session, or 403.
+type forwardResult struct {
+	Status  int
+	Reason  string
+	Headers map[string]string
+}
+
+// methodAction maps an HTTP method to the policy action.
+func methodAction(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return "read"
+	}
+	return "write"
+}
+
+// forwardAuthorize validates the platform session, maps host and path to a
+// protected app and evaluates the policy for its resource. Every request
+// with a valid session is streamed to access_logs.
+func (s *Server) forwardAuthorize(ctx context.Context, req forwardRequest) (forwardResult, error) {
+	if req.Token == "" {
+		return forwardResult{Status: http.StatusUnauthorized, Reason: "no session"}, nil
+	}
+	id, err := middleware.ParseToken(ctx, s.jwtSecret, s.sessions, req.Token)
+	if errors.Is(err, middleware.ErrSessionUnavailable) {
+		return forwardResult{}, err
+	}
+	if err != nil {
+		return forwardResult{Status: http.StatusUnauthorized, Reason: err.Error()}, nil
+	}
+	if !id.MFA {
+		return forwardResult{Status: http.StatusUnauthorized, Reason: "MFA has not been completed"}, nil
+	}
+	if id.Host != "" && id.Host != apps.NormalizeHost(req.Host) {
+		return forwardResult{Status: http.StatusUnauthorized, Reason: "session is for another host"}, nil
+	}
+
+	action := methodAction(req.Method)
+
+	// Decide on, and log, the path the upstream will actually serve.
+	cleaned, err := apps.CleanPath(req.Path)
+	if err != nil {
+		origin := audit.Origin{Path: req.Host + req.Path, Method: req.Method, IP: req.IP, ServiceClient: req.Proxy}
+		ac := policy.AccessContext{UserID: id.UserID, UserRole: id.Role, Action: action, ClientIP: req.IP, Time: time.Now()}
+		d := policy.Decision{
+			Allowed:  false,
+			Policy:   "invalid-path",
+			Policies: []string{},
+			Reason:   "the request path cannot be normalized",
+		}
+		s.decisions.Write(origin, ac, d)
+		return forwardResult{Status: http.StatusForbidden, Reason: d.Reason}, nil
+	}
+	req.Path = cleaned
+	origin := audit.Origin{Path: req.Host + req.Path, Method: req.Method, IP: req.IP, ServiceClient: req.Proxy}
+
+	app, ok := s.apps.Load().Match(req.Host, req.Path)
+	if !ok {
+		ac := policy.AccessContext{UserID: id.UserID, UserRole: id.Role, Action: action, ClientIP: req.IP, Time: time.Now()}
+		d := policy.Decision{
+			Allowed:  false,
+			Policy:   "unregistered-app",
+			Policies: []string{},
+			Reason:   "no app is registered for " + req.Host + req.Path,
+		}
+		s.decisions.Write(origin, ac, d)
+		return forwardResult{Status: http.StatusForbidden, Reason: d.Reason}, nil
+	}
+
+	ac, err := s.lookupAccessContext(ctx, id.UserID, app.ResourceName, action)
+	if errors.Is(err, sql.ErrNoRows) {
+		return forwardResult{Status: http.StatusUnauthorized, Reason: "user no longer exists"}, nil
+	}
+	if err != nil {
+		return forwardResult{}, err
+	}
+	ac.ClientIP = req.IP
+	if err := s.signals.Enrich(ctx, &ac, signals.Request{UserAgent: req.UserAgent}); err != nil {
+		d := signals.Denied(err)
+		s.decisions.Write(origin, ac, d)
+		return forwardResult{Status: http.StatusForbidden, Reason: d.Reason}, nil
+	}
+
+	d := obligations.Fulfil(ctx, s.notifier, ac, policy.Evaluate(ac), obligations.Request{
+		Justification: req.Justification,
+		MFACode:       req.MFACode,
+		VerifyMFA:     obligations.VerifyTOTP(ctx, s.db, id.UserID),
+	})
+	s.decisions.Write(origin, ac, d)
+
+	if !d.Allowed {
+		return forwardResult{Status: http.StatusForbidden, Reason: d.Reason}, nil
+	}
+
+	var email string
+	if err := s.db.QueryRowContext(ctx, `SELECT email FROM users WHERE id = $1`, id.UserID).Scan(&email); err != nil {
+		return forwardResult{}, err
+	}
+	return forwardResult{
+		Status: http.StatusOK,
+		Headers: map[string]string{
+			headerUserID:    strconv.FormatInt(id.UserID, 10),
+			headerUserEmail: email,
+			headerUserRole:  ac.UserRole,
+			headerResource:  ac.ResourceName,
+			headerPolicy:    d.Policy,
+		},
+	}, nil
+}
+
+// GET /ext-authz/nginx  (nginx auth_request subrequest)
+//
+// The original request is described by X-Original-URI, X-Original-Method
+// and Host (or X-Original-Host); the session comes from the forwarded
+// Authorization header or cookie. Identity headers are returned on 200
+// for auth_request_set.
+func (s *Server) handleNginxAuth(w http.ResponseWriter, r *http.Request) {
+	host := r.Header.Get("X-Original-Host")
+	if host == "" {
+		host = r.Host
+	}
+	method := r.Header.Get("X-Original-Method")
+	if method == "" {
+		method = http.MethodGet
+	}
+	path := r.Header.Get("X-Original-URI")
+	if path == "" {
+		http.Error(w, "X-Original-URI is required", http.StatusForbidden)
+		return
+	}
+
+	token, _ := middleware.SessionToken(r)
+	res, err := s.forwardAuthorize(r.Context(), forwardRequest{
+		Host:          host,
+		Path:          path,
+		Method:        method,
+		IP:            middleware.ClientIP(r),
+		Token:         token,
+		UserAgent:     r.UserAgent(),
+		Justification: r.Header.Get(headerJustification),
+		MFACode:       r.Header.Get(headerMFACode),
+		Proxy:         "nginx",
+	})
+	if err != nil {
+		// auth_request only understands 2xx, 401 and 403; fail closed.
+		http.Error(w, "failed to evaluate request", http.StatusForbidden)
+		return
+	}
+
+	for k, v := range res.Headers {
+		w.Header().Set(k, v)
+	}
+	if res.Status != http.StatusOK {
+		http.Error(w, res.Reason, res.Status)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
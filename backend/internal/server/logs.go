@@ -4,22 +4,32 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/lib/pq"
+
 	"zero-trust-access-platform/backend/internal/middleware"
 )
 
 type accessLogRow struct {
-	ID             int64  `json:"id"`
-	UserID         *int64 `json:"user_id"`
-	Role           string `json:"role"`
-	ResourceName   string `json:"resource_name"`
-	Action         string `json:"action"`
-	Decision       string `json:"decision"`
-	PolicyName     string `json:"policy_name"`
-	DecisionReason string `json:"decision_reason"`
-	Path           string `json:"path"`
-	Method         string `json:"method"`
-	IP             string `json:"ip"`
-	CreatedAt      string `json:"created_at"`
+	ID             int64           `json:"id"`
+	UserID         *int64          `json:"user_id"`
+	Role           string          `json:"role"`
+	ResourceName   string          `json:"resource_name"`
+	Action         string          `json:"action"`
+	Decision       string          `json:"decision"`
+	PolicyName     string          `json:"policy_name"`
+	Policies       []string        `json:"contributing_policies"`
+	DecisionReason string          `json:"decision_reason"`
+	Obligations    json.RawMessage `json:"obligations,omitempty"`
+	PolicyVersion  *int64          `json:"policy_version_id"`
+	ShadowDecision *string         `json:"shadow_decision"`
+	ShadowPolicy   *string         `json:"shadow_policy_name"`
+	ServiceClient  *string         `json:"service_client"`
+	RiskScore      *int            `json:"risk_score"`
+	ThreatFeed     *string         `json:"threat_feed"`
+	Path           string          `json:"path"`
+	Method         string          `json:"method"`
+	IP             string          `json:"ip"`
+	CreatedAt      string          `json:"created_at"`
 }
 
 // Admin: list recent logs
@@ -28,7 +38,8 @@ func (s *Server) handleListLogs(w http.ResponseWriter, r *http.Request) {
 
 	rows, err := s.db.Query(
 		`SELECT id, user_id, role, resource_name, action, decision,
-		        policy_name, decision_reason,
+		        policy_name, contributing_policies, decision_reason, obligations, policy_version_id,
+		        shadow_decision, shadow_policy_name, service_client, risk_score, threat_feed,
 		        path, method, ip, created_at
 		 FROM access_logs
 		 ORDER BY created_at DESC
@@ -51,7 +62,15 @@ func (s *Server) handleListLogs(w http.ResponseWriter, r *http.Request) {
 			&row.Action,
 			&row.Decision,
 			&row.PolicyName,
+			pq.Array(&row.Policies),
 			&row.DecisionReason,
+			&row.Obligations,
+			&row.PolicyVersion,
+			&row.ShadowDecision,
+			&row.ShadowPolicy,
+			&row.ServiceClient,
+			&row.RiskScore,
+			&row.ThreatFeed,
 			&row.Path,
 			&row.Method,
 			&row.IP,
@@ -78,7 +97,8 @@ func (s *Server) handleMyActivity(w http.ResponseWriter, r *http.Request) {
 
 	rows, err := s.db.Query(
 		`SELECT id, user_id, role, resource_name, action, decision,
-		        policy_name, decision_reason,
+		        policy_name, contributing_policies, decision_reason, obligations, policy_version_id,
+		        shadow_decision, shadow_policy_name, service_client, risk_score, threat_feed,
 		        path, method, ip, created_at
          FROM access_logs
          WHERE user_id = $1
@@ -103,7 +123,15 @@ func (s *Server) handleMyActivity(w http.ResponseWriter, r *http.Request) {
 			&row.Action,
 			&row.Decision,
 			&row.PolicyName,
+			pq.Array(&row.Policies),
 			&row.DecisionReason,
+			&row.Obligations,
+			&row.PolicyVersion,
+			&row.ShadowDecision,
+			&row.ShadowPolicy,
+			&row.ServiceClient,
+			&row.RiskScore,
+			&row.ThreatFeed,
 			&row.Path,
 			&row.Method,
 			&row.IP,
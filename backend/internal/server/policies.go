@@ -0,0 +1,231 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"zero-trust-access-platform/backend/internal/middleware"
+	"zero-trust-access-platform/backend/internal/policy"
+)
+
+// maxPolicySourceBytes bounds the size of an uploaded policy document.
+const maxPolicySourceBytes = 1 << 20
+
+type createPolicyVersionRequest struct {
+	Source  string `json:"source"`
+	Comment string `json:"comment"`
+}
+
+// loadPolicies compiles the active policy version and makes it live. If no
+// version has been activated yet the built-in default set stays active. An
+// active version that fails validation is an error: we refuse to start
+// rather than guess.
+func (s *Server) loadPolicies(ctx context.Context) error {
+	v, err := policy.NewRepository(s.db).Active(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		log.Printf("no active policy version, using built-in defaults")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("load policy version: %w", err)
+	}
+
+	set, err := policy.CompileVersion(v)
+	if err != nil {
+		return err
+	}
+	policy.SetActive(set)
+	return nil
+}
+
+// GET /admin/policy -> active policy version (or the built-in defaults)
+func (s *Server) handleActivePolicy(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	v, err := policy.NewRepository(s.db).Active(r.Context())
+	if errors.Is(err, sql.ErrNoRows) {
+		s.writeJSON(w, http.StatusOK, policy.Version{
+			Source:  string(policy.DefaultSource),
+			Comment: "built-in defaults",
+			Active:  true,
+		})
+		return
+	}
+	if err != nil {
+		http.Error(w, "failed to load policy version", http.StatusInternalServerError)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, v)
+}
+
+// GET  /admin/policy/versions -> history, newest first
+// POST /admin/policy/versions -> propose a new version {source, comment}
+func (s *Server) handlePolicyVersions(w http.ResponseWriter, r *http.Request) {
+	repo := policy.NewRepository(s.db)
+
+	switch r.Method {
+	case http.MethodGet:
+		list, err := repo.List(r.Context())
+		if err != nil {
+			http.Error(w, "failed to list policy versions", http.StatusInternalServerError)
+			return
+		}
+		s.writeJSON(w, http.StatusOK, list)
+
+	case http.MethodPost:
+		userID, ok := middleware.UserID(r)
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var req createPolicyVersionRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxPolicySourceBytes)).Decode(&req); err != nil || req.Source == "" {
+			http.Error(w, "invalid body", http.StatusBadRequest)
+			return
+		}
+
+		if _, err := policy.CompileSource([]byte(req.Source)); err != nil {
+			s.writeJSON(w, http.StatusBadRequest, map[string]string{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		v, err := repo.Create(r.Context(), req.Source, req.Comment, userID)
+		if err != nil {
+			http.Error(w, "failed to create policy version", http.StatusInternalServerError)
+			return
+		}
+		s.writeJSON(w, http.StatusCreated, v)
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// GET  /admin/policy/versions/{id}
+// GET  /admin/policy/versions/{id}/analysis  (static analysis findings)
+// POST /admin/policy/versions/{id}/activate  (admin other than the author, no error findings)
+// POST /admin/policy/versions/{id}/rollback  (any admin, previously live versions only)
+func (s *Server) handlePolicyVersion(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) < 4 || len(parts) > 5 || parts[0] != "admin" || parts[1] != "policy" || parts[2] != "versions" {
+		http.NotFound(w, r)
+		return
+	}
+
+	id, err := strconv.ParseInt(parts[3], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid version id", http.StatusBadRequest)
+		return
+	}
+
+	repo := policy.NewRepository(s.db)
+	v, err := repo.Get(r.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "policy version not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "failed to load policy version", http.StatusInternalServerError)
+		return
+	}
+
+	if len(parts) == 4 {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		s.writeJSON(w, http.StatusOK, v)
+		return
+	}
+
+	if parts[4] == "analysis" {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		res, err := s.analyzePolicy(r.Context(), v.Source)
+		if err != nil {
+			http.Error(w, "failed to analyze policy version", http.StatusInternalServerError)
+			return
+		}
+		s.writeJSON(w, http.StatusOK, res)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := middleware.UserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var kind string
+	switch parts[4] {
+	case "activate":
+		kind = policy.ActivationActivate
+	case "rollback":
+		kind = policy.ActivationRollback
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	switch err := v.CheckActivation(kind, userID); {
+	case errors.Is(err, policy.ErrOwnVersion):
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	// Compile before recording the activation so a broken version can never
+	// become the one loaded at startup.
+	set, err := policy.CompileVersion(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	// New versions must pass static analysis; a rollback restores a version
+	// that has already been live and is not held back.
+	if kind == policy.ActivationActivate {
+		res, err := s.analyzePolicy(r.Context(), v.Source)
+		if err != nil {
+			http.Error(w, "failed to analyze policy version", http.StatusInternalServerError)
+			return
+		}
+		if res.BlocksActivation {
+			s.writeJSON(w, http.StatusConflict, map[string]any{
+				"error":    "policy analysis found errors",
+				"findings": res.Findings,
+			})
+			return
+		}
+	}
+	if err := repo.Activate(r.Context(), v.ID, kind, userID); err != nil {
+		http.Error(w, "failed to activate policy version", http.StatusInternalServerError)
+		return
+	}
+	policy.SetActive(set)
+
+	v.Active = true
+	s.writeJSON(w, http.StatusOK, v)
+}
@@ -0,0 +1,366 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"zero-trust-access-platform/backend/internal/apps"
+	"zero-trust-access-platform/backend/internal/middleware"
+	"zero-trust-access-platform/backend/internal/sessions"
+)
+
+// Paths the platform answers itself on every app host: the proxy serves
+// them directly, and nginx or Envoy route them to the backend.
+const (
+	proxyCallbackPath = "/.zt/callback"
+	proxyLogoutPath   = "/.zt/logout"
+	proxyLoginPath    = "/.zt/login"
+)
+
+const (
+	// headerAssertion carries a JWT signed with PROXY_HEADER_SECRET that
+	// upstreams verify instead of trusting the plain X-ZT-* headers.
+	headerAssertion = "X-ZT-Assertion"
+
+	assertionIssuer   = "zero-trust-access-platform"
+	assertionLifetime = time.Minute
+)
+
+// serveProxy runs the identity-aware reverse proxy on addr. Requests are
+// routed by Host to the upstream of the matching app.
+func (s *Server) serveProxy(addr string, trusted []netip.Prefix) error {
+	if s.cfg.ProxyHeaderSecret == "" {
+		return errors.New("PROXY_HEADER_SECRET is required when PROXY_ADDR is set")
+	}
+	if s.cfg.ProxyLoginURL == "" {
+		return errors.New("PROXY_LOGIN_URL is required when PROXY_ADDR is set")
+	}
+	p := &proxy{s: s, upstreams: map[string]*httputil.ReverseProxy{}}
+	log.Printf("identity-aware proxy listening on %s", addr)
+	return http.ListenAndServe(addr, middleware.RealIP(trusted, p))
+}
+
+type proxy struct {
+	s *Server
+
+	mu        sync.Mutex
+	upstreams map[string]*httputil.ReverseProxy
+}
+
+func (p *proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// There is no ServeMux in front of the proxy. Route and decide on the
+	// cleaned path, so /public/../admin cannot be decided as /public and
+	// then served as /admin. A path that cleaning leaves alone is
+	// forwarded as the client encoded it, so e.g. /api/repos/a%2Fb keeps
+	// its %2F; any other is forwarded cleaned.
+	cleaned, err := apps.CleanPath(r.URL.EscapedPath())
+	if err != nil {
+		http.Error(w, "invalid request path", http.StatusBadRequest)
+		return
+	}
+	if r.URL.Path != cleaned {
+		r.URL.Path, r.URL.RawPath = cleaned, ""
+	}
+
+	switch cleaned {
+	case proxyCallbackPath:
+		p.s.handleTicketCallback(w, r)
+		return
+	case proxyLogoutPath:
+		p.s.handleTicketLogout(w, r)
+		return
+	}
+
+	app, ok := p.s.apps.Load().Match(r.Host, cleaned)
+	if !ok || app.Upstream == "" {
+		http.Error(w, "unknown application", http.StatusNotFound)
+		return
+	}
+
+	var token string
+	if c, err := r.Cookie(middleware.SessionCookie); err == nil {
+		token = c.Value
+	}
+	res, err := p.s.forwardAuthorize(r.Context(), forwardRequest{
+		Host:          r.Host,
+		Path:          r.URL.EscapedPath(),
+		Method:        r.Method,
+		IP:            middleware.ClientIP(r),
+		Token:         token,
+		UserAgent:     r.UserAgent(),
+		Justification: r.Header.Get(headerJustification),
+		MFACode:       r.Header.Get(headerMFACode),
+		Proxy:         "proxy",
+	})
+	if err != nil {
+		http.Error(w, "failed to evaluate request", http.StatusInternalServerError)
+		return
+	}
+	switch res.Status {
+	case http.StatusOK:
+	case http.StatusUnauthorized:
+		p.s.redirectToLogin(w, r, r.URL.RequestURI(), res.Reason)
+		return
+	default:
+		http.Error(w, "access denied: "+res.Reason, res.Status)
+		return
+	}
+
+	assertion, err := p.assertion(app, res.Headers)
+	if err != nil {
+		http.Error(w, "failed to sign identity", http.StatusInternalServerError)
+		return
+	}
+
+	// Nothing identity-related from the client reaches the upstream: no
+	// forged X-ZT-* headers and no platform session cookie.
+	for k := range r.Header {
+		if strings.HasPrefix(k, "X-Zt-") {
+			r.Header.Del(k)
+		}
+	}
+	stripSessionCookie(r)
+	for k, v := range res.Headers {
+		r.Header.Set(k, v)
+	}
+	r.Header.Set(headerAssertion, assertion)
+
+	rp, err := p.upstream(app.Upstream)
+	if err != nil {
+		http.Error(w, "bad upstream", http.StatusBadGateway)
+		return
+	}
+	rp.ServeHTTP(w, r)
+}
+
+// redirectToLogin sends browsers to the platform login page, which comes
+// back through the callback to requestURI on this host; other clients,
+// and every client when no login page is configured, get a 401.
+func (s *Server) redirectToLogin(w http.ResponseWriter, r *http.Request, requestURI, reason string) {
+	u, ok := s.loginURL(middleware.Scheme(r), r.Host, requestURI, r.Method, r.Header.Get("Accept"))
+	if !ok {
+		http.Error(w, reason, http.StatusUnauthorized)
+		return
+	}
+	http.Redirect(w, r, u, http.StatusFound)
+}
+
+// loginURL is PROXY_LOGIN_URL?redirect=<original URL>, for browser page
+// loads only.
+func (s *Server) loginURL(scheme, host, requestURI, method, accept string) (string, bool) {
+	if s.cfg.ProxyLoginURL == "" || method != http.MethodGet || !strings.Contains(accept, "text/html") {
+		return "", false
+	}
+	u, err := url.Parse(s.cfg.ProxyLoginURL)
+	if err != nil {
+		return "", false
+	}
+	q := u.Query()
+	q.Set("redirect", scheme+"://"+host+requestURI)
+	u.RawQuery = q.Encode()
+	return u.String(), true
+}
+
+// GET /.zt/login  (nginx: error_page 401 = /.zt/login)
+// Redirects a browser that auth_request refused for lack of a session to
+// the login page; X-Original-URI is the page it asked for.
+func (s *Server) handleTicketLogin(w http.ResponseWriter, r *http.Request) {
+	uri := r.Header.Get("X-Original-URI")
+	if !strings.HasPrefix(uri, "/") || strings.HasPrefix(uri, "//") || strings.HasPrefix(uri, "/\\") {
+		uri = "/"
+	}
+	s.redirectToLogin(w, r, uri, "unauthorized")
+}
+
+// GET /.zt/logout
+// Revokes this host's session and clears its cookie.
+func (s *Server) handleTicketLogout(w http.ResponseWriter, r *http.Request) {
+	if c, err := r.Cookie(middleware.SessionCookie); err == nil {
+		id, err := middleware.ParseToken(r.Context(), s.jwtSecret, s.sessions, c.Value)
+		if err == nil && id.Host == apps.NormalizeHost(r.Host) {
+			_ = s.sessions.Revoke(r.Context(), id.UserID, id.SessionID)
+		}
+	}
+	http.SetCookie(w, &http.Cookie{Name: middleware.SessionCookie, Path: "/", MaxAge: -1})
+	if s.cfg.ProxyLoginURL == "" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	http.Redirect(w, r, s.cfg.ProxyLoginURL, http.StatusFound)
+}
+
+// GET /.zt/callback?ticket=...&rd=/path
+// Redeems a login ticket and stores a new session, scoped to this host, in
+// a cookie. The caller's own API token never leaves the login page.
+func (s *Server) handleTicketCallback(w http.ResponseWriter, r *http.Request) {
+	host := apps.NormalizeHost(r.Host)
+	parentID, err := s.sessions.RedeemTicket(r.Context(), r.URL.Query().Get("ticket"), host)
+	if errors.Is(err, sessions.ErrInvalid) {
+		http.Error(w, "invalid or used login ticket", http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		http.Error(w, "session store unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	tok, err := s.hostToken(r, parentID, host)
+	if errors.Is(err, sessions.ErrInvalid) {
+		http.Error(w, "invalid session", http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		http.Error(w, "failed to create session", http.StatusInternalServerError)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     middleware.SessionCookie,
+		Value:    tok,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   middleware.Scheme(r) == "https" || !s.cfg.Development(),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	rd := r.URL.Query().Get("rd")
+	if !strings.HasPrefix(rd, "/") || strings.HasPrefix(rd, "//") || strings.HasPrefix(rd, "/\\") {
+		rd = "/"
+	}
+	http.Redirect(w, r, rd, http.StatusFound)
+}
+
+// assertion signs the identity headers for the app's upstream.
+func (p *proxy) assertion(app apps.App, headers map[string]string) (string, error) {
+	now := time.Now()
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"iss":      assertionIssuer,
+		"aud":      app.Host,
+		"sub":      headers[headerUserID],
+		"email":    headers[headerUserEmail],
+		"role":     headers[headerUserRole],
+		"resource": headers[headerResource],
+		"policy":   headers[headerPolicy],
+		"iat":      now.Unix(),
+		"exp":      now.Add(assertionLifetime).Unix(),
+	}).SignedString([]byte(p.s.cfg.ProxyHeaderSecret))
+}
+
+// upstream returns the reverse proxy for target, creating it on first use.
+func (p *proxy) upstream(target string) (*httputil.ReverseProxy, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if rp, ok := p.upstreams[target]; ok {
+		return rp, nil
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+	rp := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(u)
+			pr.SetXForwarded()
+			pr.Out.Host = pr.In.Host
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			log.Printf("proxy %s: %v", target, err)
+			http.Error(w, "upstream unavailable", http.StatusBadGateway)
+		},
+	}
+	p.upstreams[target] = rp
+	return rp, nil
+}
+
+// stripSessionCookie removes the platform session cookie from r.
+func stripSessionCookie(r *http.Request) {
+	cookies := r.Cookies()
+	r.Header.Del("Cookie")
+	for _, c := range cookies {
+		if c.Name != middleware.SessionCookie {
+			r.AddCookie(c)
+		}
+	}
+}
+
+type proxyTicketRequest struct {
+	Redirect string `json:"redirect"`
+}
+
+// POST /auth/proxy-ticket {redirect}
+// Called by the login page after MFA to send the user back to a
+// registered app, proxied or behind forward auth: returns the app host's
+// callback URL with a one-time ticket for the session.
+func (s *Server) handleProxyTicket(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	tok, err := middleware.SessionToken(r)
+	if err != nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	ident, err := middleware.ParseToken(r.Context(), s.jwtSecret, s.sessions, tok)
+	if errors.Is(err, middleware.ErrSessionUnavailable) {
+		http.Error(w, "session store unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	// An app host session cannot be passed on to other hosts.
+	if err != nil || !ident.MFA || ident.Host != "" {
+		http.Error(w, "MFA has not been completed", http.StatusUnauthorized)
+		return
+	}
+
+	var req proxyTicketRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	// Only registered apps, so this is not an open redirect, and only
+	// over https outside development, so the ticket is not sent in clear.
+	target, err := url.Parse(req.Redirect)
+	if err != nil || (target.Scheme != "https" && (target.Scheme != "http" || !s.cfg.Development())) {
+		http.Error(w, "invalid redirect", http.StatusBadRequest)
+		return
+	}
+	targetPath, err := apps.CleanPath(target.EscapedPath())
+	if err != nil {
+		http.Error(w, "invalid redirect", http.StatusBadRequest)
+		return
+	}
+	_, ok := s.apps.Load().Match(target.Host, targetPath)
+	if !ok {
+		http.Error(w, "redirect is not a registered app", http.StatusBadRequest)
+		return
+	}
+
+	ticket, err := s.sessions.CreateTicket(r.Context(), ident.UserID, ident.SessionID, apps.NormalizeHost(target.Host))
+	if err != nil {
+		http.Error(w, "failed to create ticket", http.StatusInternalServerError)
+		return
+	}
+
+	rd := target.EscapedPath()
+	if target.RawQuery != "" {
+		rd += "?" + target.RawQuery
+	}
+	callback := url.URL{
+		Scheme:   target.Scheme,
+		Host:     target.Host,
+		Path:     proxyCallbackPath,
+		RawQuery: url.Values{"ticket": {ticket}, "rd": {rd}}.Encode(),
+	}
+	s.writeJSON(w, http.StatusOK, map[string]string{"url": callback.String()})
+}
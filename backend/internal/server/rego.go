@@ -0,0 +1,234 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"zero-trust-access-platform/backend/internal/middleware"
+	"zero-trust-access-platform/backend/internal/policy"
+	"zero-trust-access-platform/backend/internal/policy/rego"
+	"zero-trust-access-platform/backend/internal/signals"
+)
+
+type putRegoModuleRequest struct {
+	Source string `json:"source"`
+}
+
+type regoStatus struct {
+	Source   string   `json:"source"`
+	Query    string   `json:"query"`
+	Revision string   `json:"revision"`
+	Modules  []string `json:"modules"`
+	LoadedAt string   `json:"loaded_at"`
+}
+
+type engineStatus struct {
+	Engine  string                        `json:"engine"`
+	Compare bool                          `json:"compare"`
+	Rego    *regoStatus                   `json:"rego,omitempty"`
+	Timings map[string]policy.TimingStats `json:"timings"`
+	Cache   policy.CacheStats             `json:"cache"`
+	Signals []signals.ProviderStats       `json:"signals"`
+}
+
+// setupEngine installs the configured policy engine. The built-in engine
+// needs nothing more than loadPolicies; the Rego engine must load and
+// compile at startup, after which it is hot-reloaded.
+func (s *Server) setupEngine(ctx context.Context) error {
+	switch s.cfg.PolicyEngine {
+	case "", policy.BuiltinEngine:
+		return nil
+	case rego.Name:
+		if err := s.reloadRego(ctx); err != nil {
+			return fmt.Errorf("rego policy engine: %w", err)
+		}
+		go s.watchRego()
+		return nil
+	default:
+		return fmt.Errorf("POLICY_ENGINE must be %q or %q", policy.BuiltinEngine, rego.Name)
+	}
+}
+
+func (s *Server) usingRego() bool {
+	return s.cfg.PolicyEngine == rego.Name
+}
+
+func (s *Server) loadRegoBundle(ctx context.Context) (*rego.Bundle, string, error) {
+	if dir := s.cfg.RegoBundleDir; dir != "" {
+		b, err := rego.LoadDir(dir)
+		return b, "dir:" + dir, err
+	}
+	b, err := rego.NewRepository(s.db).Load(ctx)
+	return b, "db", err
+}
+
+// reloadRego recompiles the bundle if it changed and makes it live. A
+// bundle that fails to compile leaves the previous one in place.
+func (s *Server) reloadRego(ctx context.Context) error {
+	b, source, err := s.loadRegoBundle(ctx)
+	if err != nil {
+		return err
+	}
+	if cur := s.rego.Load(); cur != nil && cur.Revision == b.Revision() {
+		return nil
+	}
+
+	e, err := rego.Compile(ctx, s.cfg.RegoQuery, b, source)
+	if err != nil {
+		return err
+	}
+	s.rego.Store(e)
+	policy.SetEngine(e, s.cfg.PolicyEngineCompare)
+	log.Printf("rego policy bundle %s loaded from %s (%d modules)", e.Revision, e.Source, len(e.Modules))
+	return nil
+}
+
+func (s *Server) watchRego() {
+	interval := s.cfg.RegoReloadInterval
+	if interval <= 0 {
+		return
+	}
+	for range time.Tick(interval) {
+		if err := s.reloadRego(context.Background()); err != nil {
+			log.Printf("reload rego policy bundle: %v", err)
+		}
+	}
+}
+
+// GET /admin/policy/engine -> active engine, loaded Rego bundle and decision timings
+func (s *Server) handlePolicyEngine(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	st := engineStatus{
+		Engine:  policy.ActiveEngine(),
+		Compare: s.usingRego() && s.cfg.PolicyEngineCompare,
+		Timings: policy.Timings(),
+		Cache:   policy.Cache(),
+		Signals: s.signals.Stats(),
+	}
+	if e := s.rego.Load(); e != nil {
+		st.Rego = &regoStatus{
+			Source:   e.Source,
+			Query:    e.Query(),
+			Revision: e.Revision,
+			Modules:  e.Modules,
+			LoadedAt: e.LoadedAt.Format(time.RFC3339),
+		}
+	}
+	s.writeJSON(w, http.StatusOK, st)
+}
+
+// GET /admin/policy/rego -> Rego modules stored in the database
+func (s *Server) handleRegoModules(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	list, err := rego.NewRepository(s.db).List(r.Context())
+	if err != nil {
+		http.Error(w, "failed to list rego modules", http.StatusInternalServerError)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, list)
+}
+
+// PUT    /admin/policy/rego/{name}  {source}
+// DELETE /admin/policy/rego/{name}
+//
+// The resulting bundle is compiled before anything is saved. If the Rego
+// engine runs from the database, the change is live on this replica at once
+// and on the others as soon as the NOTIFY from the rego_modules trigger
+// (migration 014) reaches them; REGO_RELOAD_INTERVAL is only the fallback.
+func (s *Server) handleRegoModule(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) != 4 || parts[0] != "admin" || parts[1] != "policy" || parts[2] != "rego" {
+		http.NotFound(w, r)
+		return
+	}
+	name := parts[3]
+
+	userID, ok := middleware.UserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	repo := rego.NewRepository(s.db)
+	var bundle *rego.Bundle
+
+	switch r.Method {
+	case http.MethodPut:
+		var req putRegoModuleRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxPolicySourceBytes)).Decode(&req); err != nil || req.Source == "" {
+			http.Error(w, "invalid body", http.StatusBadRequest)
+			return
+		}
+		b, err := repo.WithModule(r.Context(), name, req.Source)
+		if err != nil {
+			http.Error(w, "failed to load rego modules", http.StatusInternalServerError)
+			return
+		}
+		if _, err := rego.Compile(r.Context(), s.cfg.RegoQuery, b, "db"); err != nil {
+			s.writeJSON(w, http.StatusBadRequest, map[string]string{
+				"error": err.Error(),
+			})
+			return
+		}
+		if err := repo.Put(r.Context(), name, req.Source, userID); err != nil {
+			if errors.Is(err, rego.ErrInvalidName) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			http.Error(w, "failed to save rego module", http.StatusInternalServerError)
+			return
+		}
+		bundle = b
+
+	case http.MethodDelete:
+		b, err := repo.Load(r.Context())
+		if err != nil {
+			http.Error(w, "failed to load rego modules", http.StatusInternalServerError)
+			return
+		}
+		if _, ok := b.Modules[name]; !ok {
+			http.Error(w, "rego module not found", http.StatusNotFound)
+			return
+		}
+		delete(b.Modules, name)
+		if s.usingRego() && s.cfg.RegoBundleDir == "" {
+			if _, err := rego.Compile(r.Context(), s.cfg.RegoQuery, b, "db"); err != nil {
+				http.Error(w, "the remaining bundle would not compile: "+err.Error(), http.StatusConflict)
+				return
+			}
+		}
+		if err := repo.Delete(r.Context(), name); err != nil && !errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "failed to delete rego module", http.StatusInternalServerError)
+			return
+		}
+		bundle = b
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.usingRego() && s.cfg.RegoBundleDir == "" {
+		if err := s.reloadRego(r.Context()); err != nil {
+			log.Printf("reload rego policy bundle: %v", err)
+		}
+	}
+
+	w.Header().Set("X-Rego-Revision", bundle.Revision())
+	w.WriteHeader(http.StatusNoContent)
+}
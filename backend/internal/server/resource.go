@@ -5,9 +5,14 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/lib/pq"
+
+	"zero-trust-access-platform/backend/internal/attributes"
 	"zero-trust-access-platform/backend/internal/middleware"
 	"zero-trust-access-platform/backend/internal/models"
+	"zero-trust-access-platform/backend/internal/obligations"
 	"zero-trust-access-platform/backend/internal/policy"
+	"zero-trust-access-platform/backend/internal/signals"
 )
 
 func (s *Server) handleListResources(w http.ResponseWriter, r *http.Request) {
@@ -31,21 +36,22 @@ func (s *Server) handleListResources(w http.ResponseWriter, r *http.Request) {
         JOIN access_policies p ON p.resource_id = r.id
         WHERE
             (
-                p.role = $1
+                p.role = ANY($1)
                 OR (p.user_id IS NOT NULL AND p.user_id = $2)
             )
             AND 'read' = ANY(p.allowed_actions)
         ORDER BY r.id;
     `
 
-	rows, err := s.db.Query(q, role, userID)
+	// Grants to inherited roles count too.
+	rows, err := s.db.Query(q, pq.Array(policy.EffectiveRoles(role)), userID)
 	if err != nil {
 		http.Error(w, "failed to query resources", http.StatusInternalServerError)
 		return
 	}
 	defer rows.Close()
 
-	var resources []models.Resource
+	var candidates []models.Resource
 
 	for rows.Next() {
 		var rsrc models.Resource
@@ -59,50 +65,76 @@ func (s *Server) handleListResources(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "failed to scan resource", http.StatusInternalServerError)
 			return
 		}
+		candidates = append(candidates, rsrc)
+	}
+
+	if err := rows.Err(); err != nil {
+		http.Error(w, "failed to read resources", http.StatusInternalServerError)
+		return
+	}
+
+	// ABAC attributes, loaded in bulk rather than per row.
+	attrRepo := attributes.NewRepository(s.db)
+	userAttrs, err := attrRepo.Get(r.Context(), attributes.User, userID)
+	if err != nil {
+		http.Error(w, "failed to load user attributes", http.StatusInternalServerError)
+		return
+	}
+	ids := make([]int64, len(candidates))
+	for i, rsrc := range candidates {
+		ids[i] = rsrc.ID
+	}
+	resourceAttrs, err := attrRepo.GetMany(r.Context(), attributes.Resource, ids)
+	if err != nil {
+		http.Error(w, "failed to load resource attributes", http.StatusInternalServerError)
+		return
+	}
+
+	// Optional, for resources whose policy requires a justification or a
+	// step-up MFA code.
+	justification := r.URL.Query().Get("justification")
+	mfaCode := r.Header.Get("X-MFA-Code")
+
+	// Signals, threat intel and risk describe the request, not the
+	// resource: enrich once and share the result across the listing.
+	base := policy.AccessContext{
+		UserID:         userID,
+		UserRole:       role,
+		MFAEnabled:     true, // enforced during login
+		Action:         "read",
+		ClientIP:       middleware.ClientIP(r),
+		Time:           time.Now(),
+		UserAttributes: userAttrs,
+	}
+	enrichErr := s.signals.Enrich(r.Context(), &base, signals.Request{UserAgent: r.UserAgent()})
 
+	var resources []models.Resource
+
+	for _, rsrc := range candidates {
 		// 🔐 Zero Trust policy evaluation
-		decision := policy.Evaluate(policy.AccessContext{
-			UserID:       userID,
-			UserRole:     role,
-			MFAEnabled:   true, // enforced during login
-			ResourceName: rsrc.Name,
-			ResourceType: rsrc.Type,
-			Sensitivity:  rsrc.Sensitivity,
-			Action:       "read",
-			Time:         time.Now(),
+		ac := base
+		ac.ResourceName = rsrc.Name
+		ac.ResourceType = rsrc.Type
+		ac.Sensitivity = rsrc.Sensitivity
+		ac.ResourceAttributes = resourceAttrs[rsrc.ID]
+		if enrichErr != nil {
+			s.logAccess(r, ac, signals.Denied(enrichErr))
+			continue
+		}
+		decision := policy.Evaluate(ac)
+		decision = obligations.Fulfil(r.Context(), s.notifier, ac, decision, obligations.Request{
+			Justification: justification,
+			MFACode:       mfaCode,
+			VerifyMFA:     obligations.VerifyTOTP(r.Context(), s.db, userID),
 		})
 
+		s.logAccess(r, ac, decision)
+
 		if !decision.Allowed {
-			s.logAccess(
-				r,
-				userID,
-				role,
-				rsrc.Name,
-				"read",
-				"deny",
-				decision.Policy,
-				decision.Reason,
-			)
 			continue
 		}
 
 		resources = append(resources, rsrc)
-
-		s.logAccess(
-			r,
-			userID,
-			role,
-			rsrc.Name,
-			"read",
-			"allow",
-			decision.Policy,
-			decision.Reason,
-		)
-	}
-
-	if err := rows.Err(); err != nil {
-		http.Error(w, "failed to read resources", http.StatusInternalServerError)
-		return
 	}
 
 	_ = json.NewEncoder(w).Encode(resources)
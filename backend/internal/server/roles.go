@@ -0,0 +1,142 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"zero-trust-access-platform/backend/internal/middleware"
+	"zero-trust-access-platform/backend/internal/policy"
+	"zero-trust-access-platform/backend/internal/roles"
+)
+
+type putRoleRequest struct {
+	Description string   `json:"description"`
+	Inherits    []string `json:"inherits"`
+	Permissions []string `json:"permissions"`
+}
+
+// loadRoles makes the role hierarchy available to policy evaluation and
+// to the admin check.
+func (s *Server) loadRoles(ctx context.Context) error {
+	list, err := roles.NewRepository(s.db).List(ctx)
+	if err != nil {
+		return err
+	}
+	policy.SetRoles(roles.Hierarchy(list))
+	return nil
+}
+
+// GET /admin/roles -> every role with its inherited roles and permissions
+func (s *Server) handleListRoles(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	list, err := roles.NewRepository(s.db).List(r.Context())
+	if err != nil {
+		http.Error(w, "failed to list roles", http.StatusInternalServerError)
+		return
+	}
+
+	type roleDTO struct {
+		roles.Role
+		EffectiveRoles       []string `json:"effective_roles"`
+		EffectivePermissions []string `json:"effective_permissions"`
+	}
+	out := make([]roleDTO, 0, len(list))
+	for _, role := range list {
+		out = append(out, roleDTO{
+			Role:                 role,
+			EffectiveRoles:       policy.EffectiveRoles(role.Name),
+			EffectivePermissions: policy.RolePermissions(role.Name),
+		})
+	}
+	s.writeJSON(w, http.StatusOK, out)
+}
+
+// PUT    /admin/roles/{name}  {description, inherits, permissions}
+// DELETE /admin/roles/{name}
+// Roles still assigned to users or inherited by other roles cannot be
+// deleted, nor can user, devops and admin.
+func (s *Server) handleRole(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) != 3 || parts[0] != "admin" || parts[1] != "roles" || parts[2] == "" {
+		http.NotFound(w, r)
+		return
+	}
+	name := parts[2]
+	repo := roles.NewRepository(s.db)
+
+	switch r.Method {
+	case http.MethodPut:
+		userID, ok := middleware.UserID(r)
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var req putRoleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid body", http.StatusBadRequest)
+			return
+		}
+		role := roles.Role{
+			Name:        name,
+			Description: req.Description,
+			Inherits:    req.Inherits,
+			Permissions: req.Permissions,
+		}
+		if role.Inherits == nil {
+			role.Inherits = []string{}
+		}
+		if role.Permissions == nil {
+			role.Permissions = []string{}
+		}
+
+		existing, err := repo.List(r.Context())
+		if err != nil {
+			http.Error(w, "failed to list roles", http.StatusInternalServerError)
+			return
+		}
+		if err := roles.Validate(role, existing); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := repo.Put(r.Context(), role, userID); err != nil {
+			http.Error(w, "failed to save role", http.StatusInternalServerError)
+			return
+		}
+
+	case http.MethodDelete:
+		err := repo.Delete(r.Context(), name)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			http.Error(w, "role not found", http.StatusNotFound)
+			return
+		case errors.Is(err, roles.ErrBuiltin):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case errors.Is(err, roles.ErrInUse):
+			http.Error(w, "role is assigned to users or inherited by another role", http.StatusConflict)
+			return
+		case err != nil:
+			http.Error(w, "failed to delete role", http.StatusInternalServerError)
+			return
+		}
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := s.loadRoles(r.Context()); err != nil {
+		http.Error(w, "failed to reload roles", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
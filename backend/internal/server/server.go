@@ -7,19 +7,48 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"sync/atomic"
 	"time"
 
+	"zero-trust-access-platform/backend/internal/apps"
+	"zero-trust-access-platform/backend/internal/audit"
 	"zero-trust-access-platform/backend/internal/awsroles"
 	"zero-trust-access-platform/backend/internal/awssts"
 	"zero-trust-access-platform/backend/internal/config"
 	awshandlers "zero-trust-access-platform/backend/internal/http/handlers"
 	"zero-trust-access-platform/backend/internal/middleware"
+	"zero-trust-access-platform/backend/internal/notify"
+	"zero-trust-access-platform/backend/internal/policy"
+	"zero-trust-access-platform/backend/internal/policy/rego"
+	"zero-trust-access-platform/backend/internal/roles"
+	"zero-trust-access-platform/backend/internal/serviceclients"
+	"zero-trust-access-platform/backend/internal/sessions"
+	"zero-trust-access-platform/backend/internal/signals"
+	"zero-trust-access-platform/backend/internal/threatintel"
 )
 
 type Server struct {
 	cfg       *config.Config
 	db        *sql.DB
 	jwtSecret []byte
+	sessions  *sessions.Repository
+	notifier  notify.Notifier
+
+	// rego is the loaded Rego bundle when POLICY_ENGINE=rego.
+	rego atomic.Pointer[rego.Engine]
+
+	// apps maps host/path to resources for forward auth and the proxy.
+	apps atomic.Pointer[apps.Table]
+
+	// decisions writes forward auth and proxy decisions in the background.
+	decisions *audit.Stream
+
+	// threats are the threat-intelligence blocklists.
+	threats *threatintel.Store
+
+	// signals enriches every request before it is evaluated: threat
+	// intelligence, signal providers, then the risk engine.
+	signals *signals.Registry
 }
 
 type healthResponse struct {
@@ -29,10 +58,21 @@ type healthResponse struct {
 }
 
 func New(cfg *config.Config, db *sql.DB, jwtSecret []byte) *Server {
+	threats := threatintel.NewStore(cfg.ThreatIntelFiles)
 	return &Server{
 		cfg:       cfg,
 		db:        db,
 		jwtSecret: jwtSecret,
+		sessions: sessions.NewRepository(db, sessions.Limits{
+			IdleTimeout:   cfg.SessionIdleTimeout,
+			MaxLifetime:   cfg.SessionMaxLifetime,
+			RoleLifetime:  map[string]time.Duration{"admin": cfg.AdminSessionMaxLifetime},
+			MaxConcurrent: cfg.SessionMaxConcurrent,
+		}),
+		notifier:  notify.NewBackground(notify.New(cfg.NotifyWebhookURL), cfg.NotifyDedupeWindow),
+		decisions: audit.NewStream(db, 1024),
+		threats:   threats,
+		signals:   signals.NewRegistry(),
 	}
 }
 
@@ -41,64 +81,243 @@ func (s *Server) routes(mux *http.ServeMux) {
 	mux.HandleFunc("/health", s.cors(s.handleHealth))
 	mux.HandleFunc("/auth/signup", s.cors(s.handleSignup))
 	mux.HandleFunc("/auth/login", s.cors(s.handleLogin))
+	// login hand-off to apps behind the identity-aware proxy
+	mux.HandleFunc("/auth/proxy-ticket", s.cors(s.handleProxyTicket))
+	// login hand-off on app hosts behind nginx or Envoy, which route /.zt/
+	// here with the original Host
+	mux.HandleFunc(proxyLoginPath, s.handleTicketLogin)
+	mux.HandleFunc(proxyCallbackPath, s.handleTicketCallback)
+	mux.HandleFunc(proxyLogoutPath, s.handleTicketLogout)
 
 	// protected: users
 	mux.HandleFunc("/users",
 		s.cors(
-			middleware.Auth(s.jwtSecret, s.handleListUsers),
+			middleware.Auth(s.jwtSecret, s.sessions, s.handleListUsers),
 		),
 	)
 
 	mux.HandleFunc("/users/",
 		s.cors(
-			middleware.Auth(s.jwtSecret, s.requireAdmin(s.handleUpdateUserRole)),
+			middleware.Auth(s.jwtSecret, s.sessions, s.requireAdmin(s.handleUpdateUserRole)),
 		),
 	)
 
 	// protected: resources
 	mux.HandleFunc("/resources",
 		s.cors(
-			middleware.Auth(s.jwtSecret, s.handleListResources),
+			middleware.Auth(s.jwtSecret, s.sessions, s.handleListResources),
 		),
 	)
 
 	// per-user activity feed
 	mux.HandleFunc("/me/activity",
 		s.cors(
-			middleware.Auth(s.jwtSecret, s.handleMyActivity),
+			middleware.Auth(s.jwtSecret, s.sessions, s.handleMyActivity),
+		),
+	)
+
+	// active sessions for the current user
+	mux.HandleFunc("/me/sessions",
+		s.cors(
+			middleware.Auth(s.jwtSecret, s.sessions, s.handleMySessions),
+		),
+	)
+
+	// Uses path parsing inside handleRevokeMySession to extract {id}.
+	mux.HandleFunc("/me/sessions/",
+		s.cors(
+			middleware.Auth(s.jwtSecret, s.sessions, s.handleRevokeMySession),
+		),
+	)
+
+	// admin per-user routes: /admin/users/{id}/sessions[/{sid}], /admin/users/{id}/attributes
+	mux.HandleFunc("/admin/users/",
+		s.cors(
+			middleware.Auth(s.jwtSecret, s.sessions, s.requireAdmin(s.handleAdminUser)),
+		),
+	)
+
+	// ABAC attributes on resources: /admin/resources/{id}/attributes
+	mux.HandleFunc("/admin/resources/",
+		s.cors(
+			middleware.Auth(s.jwtSecret, s.sessions, s.requireAdmin(s.handleResourceAttributes)),
 		),
 	)
 
 	// admin log viewer
 	mux.HandleFunc("/admin/logs",
 		s.cors(
-			middleware.Auth(s.jwtSecret, s.requireAdmin(s.handleListLogs)),
+			middleware.Auth(s.jwtSecret, s.sessions, s.requireAdmin(s.handleListLogs)),
 		),
 	)
 
 	// ---------- NEW: Admin Policies ----------
 	mux.HandleFunc("/admin/policies/aws-roles",
 		s.cors(
-			middleware.Auth(s.jwtSecret, s.requireAdmin(s.handleAwsRolePolicies)),
+			middleware.Auth(s.jwtSecret, s.sessions, s.requireAdmin(s.handleAwsRolePolicies)),
+		),
+	)
+
+	// declarative, versioned policy set used by policy.Evaluate
+	mux.HandleFunc("/admin/policy",
+		s.cors(
+			middleware.Auth(s.jwtSecret, s.sessions, s.requireAdmin(s.handleActivePolicy)),
+		),
+	)
+	mux.HandleFunc("/admin/policy/versions",
+		s.cors(
+			middleware.Auth(s.jwtSecret, s.sessions, s.requireAdmin(s.handlePolicyVersions)),
+		),
+	)
+
+	// dry-run a decision with a full evaluation trace (never logged)
+	mux.HandleFunc("/admin/policy/simulate",
+		s.cors(
+			middleware.Auth(s.jwtSecret, s.sessions, s.requireAdmin(s.handlePolicySimulate)),
+		),
+	)
+
+	// decisions that rely on an unconditional allow (deny_by_default migration)
+	mux.HandleFunc("/admin/policy/default-allow",
+		s.cors(
+			middleware.Auth(s.jwtSecret, s.sessions, s.requireAdmin(s.handleDefaultAllowReport)),
+		),
+	)
+
+	// threat-intelligence feeds and IP lookups
+	mux.HandleFunc("/admin/threat-intel",
+		s.cors(
+			middleware.Auth(s.jwtSecret, s.sessions, s.requireAdmin(s.handleThreatIntel)),
+		),
+	)
+
+	// static analysis of a draft policy set (shadowed rules, conflicts, ...)
+	mux.HandleFunc("/admin/policy/analyze",
+		s.cors(
+			middleware.Auth(s.jwtSecret, s.sessions, s.requireAdmin(s.handleAnalyzePolicy)),
+		),
+	)
+
+	// blast radius of a candidate policy set over recent access_logs
+	mux.HandleFunc("/admin/policy/replay",
+		s.cors(
+			middleware.Auth(s.jwtSecret, s.sessions, s.requireAdmin(s.handlePolicyReplay)),
+		),
+	)
+
+	// disagreements between shadow policies and enforced decisions
+	mux.HandleFunc("/admin/policy/shadow",
+		s.cors(
+			middleware.Auth(s.jwtSecret, s.sessions, s.requireAdmin(s.handleShadowReport)),
+		),
+	)
+
+	// Uses path parsing inside handlePolicyVersion to extract {id} and the action.
+	mux.HandleFunc("/admin/policy/versions/",
+		s.cors(
+			middleware.Auth(s.jwtSecret, s.sessions, s.requireAdmin(s.handlePolicyVersion)),
+		),
+	)
+
+	// policy engine status, decision timings and cache; Rego modules
+	mux.HandleFunc("/admin/policy/engine",
+		s.cors(
+			middleware.Auth(s.jwtSecret, s.sessions, s.requireAdmin(s.handlePolicyEngine)),
+		),
+	)
+	mux.HandleFunc("/admin/policy/cache",
+		s.cors(
+			middleware.Auth(s.jwtSecret, s.sessions, s.requireAdmin(s.handleDecisionCache)),
+		),
+	)
+	mux.HandleFunc("/admin/policy/rego",
+		s.cors(
+			middleware.Auth(s.jwtSecret, s.sessions, s.requireAdmin(s.handleRegoModules)),
+		),
+	)
+	mux.HandleFunc("/admin/policy/rego/",
+		s.cors(
+			middleware.Auth(s.jwtSecret, s.sessions, s.requireAdmin(s.handleRegoModule)),
+		),
+	)
+
+	// credentials for services using the AuthZEN API
+	mux.HandleFunc("/admin/service-clients",
+		s.cors(
+			middleware.Auth(s.jwtSecret, s.sessions, s.requireAdmin(s.handleServiceClients)),
+		),
+	)
+	mux.HandleFunc("/admin/service-clients/",
+		s.cors(
+			middleware.Auth(s.jwtSecret, s.sessions, s.requireAdmin(s.handleServiceClient)),
+		),
+	)
+
+	// AuthZEN policy decision point for other services (no CORS: not for browsers)
+	clients := serviceclients.NewRepository(s.db)
+	mux.HandleFunc("/.well-known/authzen-configuration", s.handleAuthzenConfiguration)
+	mux.HandleFunc("/access/v1/evaluation",
+		middleware.ServiceAuth(clients, s.handleAuthzenEvaluation),
+	)
+	mux.HandleFunc("/access/v1/evaluations",
+		middleware.ServiceAuth(clients, s.handleAuthzenEvaluations),
+	)
+
+	// host/path -> resource mappings for apps behind Envoy / nginx
+	mux.HandleFunc("/admin/apps",
+		s.cors(
+			middleware.Auth(s.jwtSecret, s.sessions, s.requireAdmin(s.handleApps)),
+		),
+	)
+	mux.HandleFunc("/admin/apps/",
+		s.cors(
+			middleware.Auth(s.jwtSecret, s.sessions, s.requireAdmin(s.handleApp)),
+		),
+	)
+
+	// nginx auth_request endpoint; authenticated by the user's own session
+	mux.HandleFunc("/ext-authz/nginx", s.handleNginxAuth)
+
+	// named network zones for in_zone policy conditions
+	mux.HandleFunc("/admin/network-zones",
+		s.cors(
+			middleware.Auth(s.jwtSecret, s.sessions, s.requireAdmin(s.handleListZones)),
+		),
+	)
+	mux.HandleFunc("/admin/network-zones/",
+		s.cors(
+			middleware.Auth(s.jwtSecret, s.sessions, s.requireAdmin(s.handleZone)),
+		),
+	)
+
+	// application roles, their inheritance and permission sets
+	mux.HandleFunc("/admin/roles",
+		s.cors(
+			middleware.Auth(s.jwtSecret, s.sessions, s.requireAdmin(s.handleListRoles)),
+		),
+	)
+	mux.HandleFunc("/admin/roles/",
+		s.cors(
+			middleware.Auth(s.jwtSecret, s.sessions, s.requireAdmin(s.handleRole)),
 		),
 	)
 
 	// ---------- NEW: Audit stats for chart ----------
 	mux.HandleFunc("/admin/audit/stats",
 		s.cors(
-			middleware.Auth(s.jwtSecret, s.requireAdmin(s.handleAuditStats)),
+			middleware.Auth(s.jwtSecret, s.sessions, s.requireAdmin(s.handleAuditStats)),
 		),
 	)
 
 	// MFA routes
 	mux.HandleFunc("/auth/mfa/enroll",
 		s.cors(
-			middleware.Auth(s.jwtSecret, s.handleMFAEnroll),
+			middleware.Auth(s.jwtSecret, s.sessions, s.handleMFAEnroll),
 		),
 	)
 	mux.HandleFunc("/auth/mfa/verify",
 		s.cors(
-			middleware.Auth(s.jwtSecret, s.handleMFAVerify),
+			middleware.Auth(s.jwtSecret, s.sessions, s.handleMFAVerify),
 		),
 	)
 
@@ -116,18 +335,18 @@ func (s *Server) routes(mux *http.ServeMux) {
 	// Handler that exposes:
 	//  - GET  /me/aws/roles
 	//  - POST /me/aws/roles/{id}/session
-	awsHandler := awshandlers.NewAwsRolesHandler(awsRepo, stsSvc, s.db)
+	awsHandler := awshandlers.NewAwsRolesHandler(awsRepo, stsSvc, s.db, s.notifier, s.signals)
 
 	mux.HandleFunc("/me/aws/roles",
 		s.cors(
-			middleware.Auth(s.jwtSecret, awsHandler.ListMyAwsRoles),
+			middleware.Auth(s.jwtSecret, s.sessions, awsHandler.ListMyAwsRoles),
 		),
 	)
 
 	// Uses path parsing inside CreateAwsSession to extract {id}.
 	mux.HandleFunc("/me/aws/roles/",
 		s.cors(
-			middleware.Auth(s.jwtSecret, awsHandler.CreateAwsSession),
+			middleware.Auth(s.jwtSecret, s.sessions, awsHandler.CreateAwsSession),
 		),
 	)
 }
@@ -138,8 +357,11 @@ func (s *Server) routes(mux *http.ServeMux) {
 func (s *Server) handleAwsRolePolicies(w http.ResponseWriter, r *http.Request) {
 	awsRepo := awsroles.NewRepository(s.db)
 
-	// Hardcoded app role mappings (for demo - matches your repo logic)
-	appRoles := []string{"admin", "user", "devops"}
+	appRoles, err := roles.NewRepository(s.db).List(r.Context())
+	if err != nil {
+		http.Error(w, "failed to list roles", http.StatusInternalServerError)
+		return
+	}
 
 	// Local response shape; no dependency on handler package
 	type roleDTO struct {
@@ -153,14 +375,14 @@ func (s *Server) handleAwsRolePolicies(w http.ResponseWriter, r *http.Request) {
 	policies := make(map[string][]roleDTO)
 
 	for _, appRole := range appRoles {
-		roles, err := awsRepo.ListForAppRole(r.Context(), appRole)
+		granted, err := awsRepo.ListForAppRole(r.Context(), appRole.Name)
 		if err != nil {
 			http.Error(w, "failed to list roles for app role", http.StatusInternalServerError)
 			return
 		}
 
-		out := make([]roleDTO, 0, len(roles))
-		for _, role := range roles {
+		out := make([]roleDTO, 0, len(granted))
+		for _, role := range granted {
 			out = append(out, roleDTO{
 				ID:          role.ID,
 				Name:        role.Name,
@@ -170,7 +392,7 @@ func (s *Server) handleAwsRolePolicies(w http.ResponseWriter, r *http.Request) {
 			})
 		}
 
-		policies[appRole] = out
+		policies[appRole.Name] = out
 	}
 
 	s.writeJSON(w, http.StatusOK, policies)
@@ -216,7 +438,7 @@ func (s *Server) handleAuditStats(w http.ResponseWriter, r *http.Request) {
 func (s *Server) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		role, _ := middleware.UserRole(r)
-		if role != "admin" {
+		if !policy.HasRole(role, "admin") {
 			http.Error(w, "forbidden", http.StatusForbidden)
 			return
 		}
@@ -227,7 +449,7 @@ func (s *Server) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
 func (s *Server) cors(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "http://localhost:5173")
-		w.Header().Set("Access-Control-Allow-Methods", "GET,POST,PATCH,OPTIONS")
+		w.Header().Set("Access-Control-Allow-Methods", "GET,POST,PUT,PATCH,DELETE,OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type,Authorization,X-Requested-With")
 
 		if r.Method == http.MethodOptions {
@@ -253,12 +475,56 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) Run() error {
+	if err := s.loadPolicies(context.Background()); err != nil {
+		return err
+	}
+	if err := s.setupEngine(context.Background()); err != nil {
+		return err
+	}
+	if err := s.loadZones(context.Background()); err != nil {
+		return fmt.Errorf("load network zones: %w", err)
+	}
+	go s.refreshZones()
+	if err := s.loadRoles(context.Background()); err != nil {
+		return fmt.Errorf("load roles: %w", err)
+	}
+	if err := s.loadApps(context.Background()); err != nil {
+		return fmt.Errorf("load protected apps: %w", err)
+	}
+	go s.refreshApps()
+	if err := s.setupDecisionCache(); err != nil {
+		return fmt.Errorf("decision cache: %w", err)
+	}
+	if err := s.setupSignals(); err != nil {
+		return fmt.Errorf("signal providers: %w", err)
+	}
+	if err := s.setupThreatIntel(); err != nil {
+		return fmt.Errorf("threat intel: %w", err)
+	}
+
+	if addr := s.cfg.ExtAuthzGRPCAddr; addr != "" {
+		go func() {
+			log.Fatalf("envoy ext_authz: %v", s.serveExtAuthz(addr))
+		}()
+	}
+
+	trusted, err := middleware.ParsePrefixes(s.cfg.TrustedProxies)
+	if err != nil {
+		return fmt.Errorf("TRUSTED_PROXIES: %w", err)
+	}
+
+	if addr := s.cfg.ProxyAddr; addr != "" {
+		go func() {
+			log.Fatalf("identity-aware proxy: %v", s.serveProxy(addr, trusted))
+		}()
+	}
+
 	mux := http.NewServeMux()
 	s.routes(mux)
 
 	addr := fmt.Sprintf(":%s", s.cfg.AppPort)
 	log.Printf("listening on %s", addr)
-	return http.ListenAndServe(addr, mux)
+	return http.ListenAndServe(addr, middleware.RealIP(trusted, mux))
 }
 
 func (s *Server) writeJSON(w http.ResponseWriter, status int, data any) {
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"zero-trust-access-platform/backend/internal/middleware"
+	"zero-trust-access-platform/backend/internal/serviceclients"
+)
+
+type createServiceClientRequest struct {
+	Name string `json:"name"`
+}
+
+type createServiceClientResponse struct {
+	serviceclients.Client
+	Secret string `json:"secret"`
+}
+
+// GET  /admin/service-clients
+// POST /admin/service-clients {name} -> the client and its secret (shown once)
+func (s *Server) handleServiceClients(w http.ResponseWriter, r *http.Request) {
+	repo := serviceclients.NewRepository(s.db)
+
+	switch r.Method {
+	case http.MethodGet:
+		list, err := repo.List(r.Context())
+		if err != nil {
+			http.Error(w, "failed to list service clients", http.StatusInternalServerError)
+			return
+		}
+		s.writeJSON(w, http.StatusOK, list)
+
+	case http.MethodPost:
+		userID, ok := middleware.UserID(r)
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var req createServiceClientRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Name) == "" {
+			http.Error(w, "invalid body", http.StatusBadRequest)
+			return
+		}
+
+		c, secret, err := repo.Create(r.Context(), strings.TrimSpace(req.Name), userID)
+		if err != nil {
+			http.Error(w, "failed to create service client", http.StatusInternalServerError)
+			return
+		}
+		s.writeJSON(w, http.StatusCreated, createServiceClientResponse{Client: *c, Secret: secret})
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// DELETE /admin/service-clients/{id}
+func (s *Server) handleServiceClient(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) != 3 || parts[0] != "admin" || parts[1] != "service-clients" {
+		http.NotFound(w, r)
+		return
+	}
+
+	err := serviceclients.NewRepository(s.db).Revoke(r.Context(), parts[2])
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "service client not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "failed to revoke service client", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
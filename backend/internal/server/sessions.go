@@ -0,0 +1,101 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+
+	"zero-trust-access-platform/backend/internal/middleware"
+	"zero-trust-access-platform/backend/internal/sessions"
+)
+
+// GET /me/sessions
+func (s *Server) handleMySessions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := middleware.UserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	list, err := s.sessions.ListActiveForUser(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "failed to list sessions", http.StatusInternalServerError)
+		return
+	}
+
+	current, _ := middleware.SessionID(r)
+	markCurrent(list, current)
+
+	s.writeJSON(w, http.StatusOK, list)
+}
+
+// DELETE /me/sessions/{id}
+func (s *Server) handleRevokeMySession(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, ok := middleware.UserID(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Expect /me/sessions/{id}
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) != 3 || parts[0] != "me" || parts[1] != "sessions" {
+		http.NotFound(w, r)
+		return
+	}
+
+	s.revokeSession(w, r, userID, parts[2])
+}
+
+// GET    /admin/users/{id}/sessions
+// DELETE /admin/users/{id}/sessions/{sid}
+func (s *Server) handleAdminUserSessions(w http.ResponseWriter, r *http.Request, userID int64, rest []string) {
+	switch {
+	case len(rest) == 0 && r.Method == http.MethodGet:
+		list, err := s.sessions.ListActiveForUser(r.Context(), userID)
+		if err != nil {
+			http.Error(w, "failed to list sessions", http.StatusInternalServerError)
+			return
+		}
+		current, _ := middleware.SessionID(r)
+		markCurrent(list, current)
+		s.writeJSON(w, http.StatusOK, list)
+
+	case len(rest) == 1 && r.Method == http.MethodDelete:
+		s.revokeSession(w, r, userID, rest[0])
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) revokeSession(w http.ResponseWriter, r *http.Request, userID int64, sessionID string) {
+	err := s.sessions.Revoke(r.Context(), userID, sessionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "failed to revoke session", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func markCurrent(list []sessions.Session, current string) {
+	for i := range list {
+		list[i].Current = list[i].ID == current
+	}
+}
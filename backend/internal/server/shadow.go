@@ -0,0 +1,148 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// shadowPolicyStats groups logged decisions by the policy that decided once
+// shadow policies were enforced. Disagreements can only come from shadow
+// policies; groups for enforced policies simply show agreement.
+type shadowPolicyStats struct {
+	Policy        string    `json:"policy"`
+	Evaluated     int       `json:"evaluated"`
+	Disagreements int       `json:"disagreements"`
+	AllowToDeny   int       `json:"allow_to_deny"`
+	DenyToAllow   int       `json:"deny_to_allow"`
+	FirstSeen     time.Time `json:"first_seen"`
+	LastSeen      time.Time `json:"last_seen"`
+}
+
+type shadowDisagreement struct {
+	ID             int64     `json:"id"`
+	UserID         *int64    `json:"user_id"`
+	ResourceName   string    `json:"resource_name"`
+	Action         string    `json:"action"`
+	Decision       string    `json:"decision"`
+	PolicyName     string    `json:"policy_name"`
+	ShadowDecision string    `json:"shadow_decision"`
+	ShadowPolicy   string    `json:"shadow_policy_name"`
+	ShadowReason   string    `json:"shadow_reason"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type shadowReport struct {
+	Since         time.Time            `json:"since"`
+	Policies      []shadowPolicyStats  `json:"policies"`
+	Disagreements []shadowDisagreement `json:"disagreements"`
+}
+
+// GET /admin/policy/shadow?days=7
+// Reports where shadow policies disagreed with the enforced decision, per
+// shadow policy, plus the most recent disagreements.
+func (s *Server) handleShadowReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	days := 7
+	if v := r.URL.Query().Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxReplayDays {
+			http.Error(w, "invalid days", http.StatusBadRequest)
+			return
+		}
+		days = n
+	}
+	since := time.Now().AddDate(0, 0, -days)
+
+	report := shadowReport{
+		Since:         since,
+		Policies:      []shadowPolicyStats{},
+		Disagreements: []shadowDisagreement{},
+	}
+
+	rows, err := s.db.QueryContext(r.Context(), `
+        SELECT shadow_policy_name,
+               COUNT(*),
+               COUNT(*) FILTER (WHERE shadow_decision <> decision),
+               COUNT(*) FILTER (WHERE decision = 'allow' AND shadow_decision = 'deny'),
+               COUNT(*) FILTER (WHERE decision = 'deny' AND shadow_decision = 'allow'),
+               MIN(created_at),
+               MAX(created_at)
+        FROM access_logs
+        WHERE shadow_decision IS NOT NULL AND created_at >= $1
+        GROUP BY shadow_policy_name
+        ORDER BY 3 DESC, 1
+    `, since)
+	if err != nil {
+		http.Error(w, "failed to query shadow stats", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var st shadowPolicyStats
+		if err := rows.Scan(
+			&st.Policy,
+			&st.Evaluated,
+			&st.Disagreements,
+			&st.AllowToDeny,
+			&st.DenyToAllow,
+			&st.FirstSeen,
+			&st.LastSeen,
+		); err != nil {
+			http.Error(w, "failed to scan shadow stats", http.StatusInternalServerError)
+			return
+		}
+		report.Policies = append(report.Policies, st)
+	}
+	if err := rows.Err(); err != nil {
+		http.Error(w, "failed to read shadow stats", http.StatusInternalServerError)
+		return
+	}
+
+	rows, err = s.db.QueryContext(r.Context(), `
+        SELECT id, user_id, resource_name, action, decision, COALESCE(policy_name, ''),
+               shadow_decision, shadow_policy_name, COALESCE(shadow_reason, ''), created_at
+        FROM access_logs
+        WHERE shadow_decision IS NOT NULL
+          AND shadow_decision <> decision
+          AND created_at >= $1
+        ORDER BY created_at DESC
+        LIMIT 100
+    `, since)
+	if err != nil {
+		http.Error(w, "failed to query shadow disagreements", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var d shadowDisagreement
+		if err := rows.Scan(
+			&d.ID,
+			&d.UserID,
+			&d.ResourceName,
+			&d.Action,
+			&d.Decision,
+			&d.PolicyName,
+			&d.ShadowDecision,
+			&d.ShadowPolicy,
+			&d.ShadowReason,
+			&d.CreatedAt,
+		); err != nil {
+			http.Error(w, "failed to scan shadow disagreements", http.StatusInternalServerError)
+			return
+		}
+		report.Disagreements = append(report.Disagreements, d)
+	}
+	if err := rows.Err(); err != nil {
+		http.Error(w, "failed to read shadow disagreements", http.StatusInternalServerError)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, report)
+}
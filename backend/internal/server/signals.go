@@ -0,0 +1,53 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"zero-trust-access-platform/backend/internal/risk"
+	"zero-trust-access-platform/backend/internal/signals"
+)
+
+// setupSignals registers the signal providers: threat intel, the risk
+// signals weighted by RISK_WEIGHTS and the webhooks configured by SIGNAL_*.
+func (s *Server) setupSignals() error {
+	providers := []signals.SignalProvider{s.threats}
+	for _, sig := range risk.Signals(s.cfg.RiskWeights, risk.Providers(s.db)...) {
+		providers = append(providers, sig)
+	}
+	for name, url := range s.cfg.SignalWebhooks {
+		providers = append(providers, signals.Webhook{
+			ProviderName: name,
+			URL:          url,
+			Client:       &http.Client{},
+		})
+	}
+	registered := map[string]bool{}
+	for _, p := range providers {
+		if err := s.signals.Register(p, s.signalOptions(p.Name())); err != nil {
+			return err
+		}
+		registered[p.Name()] = true
+	}
+
+	for name := range s.cfg.SignalTimeouts {
+		if !registered[name] {
+			return fmt.Errorf("SIGNAL_TIMEOUTS: no signal provider %q", name)
+		}
+	}
+	for _, name := range s.cfg.SignalFailClosed {
+		if !registered[name] {
+			return fmt.Errorf("SIGNAL_FAIL_CLOSED: no signal provider %q", name)
+		}
+	}
+	return nil
+}
+
+func (s *Server) signalOptions(name string) signals.Options {
+	return signals.Options{
+		Timeout:    time.Duration(s.cfg.SignalTimeouts[name]) * time.Millisecond,
+		FailClosed: slices.Contains(s.cfg.SignalFailClosed, name),
+	}
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"zero-trust-access-platform/backend/internal/policy"
+	"zero-trust-access-platform/backend/internal/signals"
+)
+
+// simulateRequest takes either a complete hypothetical Context, or a
+// UserID + Resource + Action that are resolved from the database and
+// scored by the risk engine as of now.
+type simulateRequest struct {
+	Context  *policy.AccessContext `json:"context"`
+	UserID   int64                 `json:"user_id"`
+	Resource string                `json:"resource"`
+	Action   string                `json:"action"`
+	ClientIP string                `json:"client_ip"`
+
+	// VersionID evaluates against a stored policy version instead of the
+	// active set, e.g. to check a proposed change before activating it.
+	VersionID int64 `json:"version_id"`
+}
+
+type simulateResponse struct {
+	Context policy.AccessContext `json:"context"`
+
+	// Engine is the engine that made the decision: the active one, or
+	// builtin for a stored version.
+	Engine string `json:"engine"`
+
+	// EnrichmentError is set when a fail-closed signal provider failed;
+	// the request would have been denied before evaluation.
+	EnrichmentError string `json:"enrichment_error,omitempty"`
+
+	policy.Trace
+}
+
+// POST /admin/policy/simulate
+// Returns the decision and full evaluation trace without logging anything
+// to access_logs.
+func (s *Server) handlePolicySimulate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req simulateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	var ac policy.AccessContext
+	var enrichErr string
+	switch {
+	case req.Context != nil:
+		ac = *req.Context
+		if ac.Time.IsZero() {
+			ac.Time = time.Now()
+		}
+
+	case req.UserID != 0 && req.Resource != "":
+		if req.Action == "" {
+			req.Action = "read"
+		}
+		var err error
+		ac, err = s.lookupAccessContext(r.Context(), req.UserID, req.Resource, req.Action)
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "user or resource not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "failed to build access context", http.StatusInternalServerError)
+			return
+		}
+		ac.ClientIP = req.ClientIP
+		if err := s.signals.Enrich(r.Context(), &ac, signals.Request{}); err != nil {
+			enrichErr = err.Error()
+		}
+
+	default:
+		http.Error(w, "context or user_id and resource required", http.StatusBadRequest)
+		return
+	}
+
+	policy.ResolveRoles(&ac)
+
+	var trace policy.Trace
+	engine := policy.BuiltinEngine
+	if req.VersionID != 0 {
+		v, err := policy.NewRepository(s.db).Get(r.Context(), req.VersionID)
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "policy version not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "failed to load policy version", http.StatusInternalServerError)
+			return
+		}
+		set, err := policy.CompileVersion(v)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		trace = set.Explain(ac)
+	} else {
+		engine = policy.ActiveEngine()
+		trace = policy.Explain(ac)
+	}
+
+	s.writeJSON(w, http.StatusOK, simulateResponse{
+		Context:         ac,
+		Engine:          engine,
+		EnrichmentError: enrichErr,
+		Trace:           trace,
+	})
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"zero-trust-access-platform/backend/internal/middleware"
+	"zero-trust-access-platform/backend/internal/models"
+	"zero-trust-access-platform/backend/internal/policy"
+	"zero-trust-access-platform/backend/internal/threatintel"
+)
+
+// THREAT_INTEL_LOGIN modes.
+const (
+	threatLoginDeny   = "deny"
+	threatLoginStepUp = "step_up"
+	threatLoginOff    = "off"
+)
+
+// setupThreatIntel loads the configured blocklists; a file that cannot be
+// loaded at startup is a configuration error.
+func (s *Server) setupThreatIntel() error {
+	switch s.cfg.ThreatIntelLogin {
+	case threatLoginDeny, threatLoginStepUp, threatLoginOff:
+	default:
+		return fmt.Errorf("THREAT_INTEL_LOGIN must be %s, %s or %s", threatLoginDeny, threatLoginStepUp, threatLoginOff)
+	}
+	if len(s.cfg.ThreatIntelFiles) == 0 {
+		return nil
+	}
+	if err := s.threats.Reload(); err != nil {
+		return err
+	}
+	for _, f := range s.threats.Feeds() {
+		log.Printf("threat intel feed %s loaded from %s (%d entries)", f.Name, f.Path, f.Entries)
+	}
+	go s.watchThreatIntel()
+	return nil
+}
+
+func (s *Server) watchThreatIntel() {
+	interval := s.cfg.ThreatIntelReloadInterval
+	if interval <= 0 {
+		return
+	}
+	for range time.Tick(interval) {
+		if err := s.threats.Reload(); err != nil {
+			log.Printf("reload threat intel feeds: %v", err)
+		}
+	}
+}
+
+type threatIntelStatus struct {
+	Feeds []threatintel.Feed  `json:"feeds"`
+	Login string              `json:"login"`
+	Match *policy.ThreatIntel `json:"match,omitempty"`
+	IP    string              `json:"ip,omitempty"`
+}
+
+// GET /admin/threat-intel[?ip=203.0.113.7]
+// Loaded feeds, and the match for ip if given.
+func (s *Server) handleThreatIntel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	st := threatIntelStatus{
+		Feeds: s.threats.Feeds(),
+		Login: s.cfg.ThreatIntelLogin,
+		IP:    r.URL.Query().Get("ip"),
+	}
+	if st.IP != "" {
+		st.Match, _ = s.threats.Lookup(st.IP)
+	}
+	s.writeJSON(w, http.StatusOK, st)
+}
+
+// loginThreat returns the match for the client IP of a sign-in, or nil
+// if it is not listed or THREAT_INTEL_LOGIN is off.
+func (s *Server) loginThreat(r *http.Request) *policy.ThreatIntel {
+	if s.cfg.ThreatIntelLogin == threatLoginOff {
+		return nil
+	}
+	ti, _ := s.threats.Lookup(middleware.ClientIP(r))
+	return ti
+}
+
+// logLoginThreat records the outcome of a sign-in from a listed IP,
+// tagged with the feed.
+func (s *Server) logLoginThreat(r *http.Request, ti *policy.ThreatIntel, u models.User, allowed bool) {
+	listing := fmt.Sprintf("%s is listed in %s", ti.Prefix, ti.Feed)
+	if ti.Category != "" {
+		listing += " (" + ti.Category + ")"
+	}
+	d := policy.Decision{
+		Allowed:  allowed,
+		Policy:   "threat-intel-login",
+		Policies: []string{"threat-intel-login"},
+	}
+	switch {
+	case allowed:
+		d.Reason = listing + "; continuing with the enrolled MFA"
+	case s.cfg.ThreatIntelLogin == threatLoginDeny:
+		d.Reason = "sign-in blocked: " + listing
+	default:
+		d.Reason = "MFA enrollment blocked: " + listing
+	}
+
+	s.logAccess(r, policy.AccessContext{
+		UserID:       u.ID,
+		UserRole:     u.Role,
+		ResourceName: "platform",
+		ResourceType: "platform",
+		Action:       "login",
+		ClientIP:     middleware.ClientIP(r),
+		Time:         time.Now(),
+		ThreatIntel:  ti,
+	}, d)
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"zero-trust-access-platform/backend/internal/models"
+	"zero-trust-access-platform/backend/internal/roles"
 )
 
 // GET /users
@@ -76,16 +77,29 @@ func (s *Server) handleUpdateUserRole(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "invalid body", http.StatusBadRequest)
 		return
 	}
-	if req.Role != "user" && req.Role != "admin" {
+	exists, err := roles.NewRepository(s.db).Exists(r.Context(), req.Role)
+	if err != nil {
+		http.Error(w, "failed to check role", http.StatusInternalServerError)
+		return
+	}
+	if !exists {
 		http.Error(w, "invalid role", http.StatusBadRequest)
 		return
 	}
 
-	_, err = s.db.Exec(`UPDATE users SET role = $1 WHERE id = $2`, req.Role, id)
+	res, err := s.db.Exec(`UPDATE users SET role = $1 WHERE id = $2 AND role <> $1`, req.Role, id)
 	if err != nil {
 		http.Error(w, "failed to update role", http.StatusInternalServerError)
 		return
 	}
+	// Tokens carry the role and requireAdmin trusts it, so a demoted admin
+	// would keep admin rights until their token expired. Sign them out.
+	if n, err := res.RowsAffected(); err != nil || n > 0 {
+		if err := s.sessions.RevokeAll(r.Context(), id); err != nil {
+			http.Error(w, "failed to revoke sessions", http.StatusInternalServerError)
+			return
+		}
+	}
 
 	var u models.User
 	var createdAt time.Time
@@ -104,3 +118,27 @@ func (s *Server) handleUpdateUserRole(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(u)
 }
+
+// Admin routes under /admin/users/{id}/...
+func (s *Server) handleAdminUser(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) < 4 || parts[0] != "admin" || parts[1] != "users" {
+		http.NotFound(w, r)
+		return
+	}
+
+	userID, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		http.Error(w, "invalid user id", http.StatusBadRequest)
+		return
+	}
+
+	switch {
+	case parts[3] == "sessions" && len(parts) <= 5:
+		s.handleAdminUserSessions(w, r, userID, parts[4:])
+	case parts[3] == "attributes" && len(parts) == 4:
+		s.handleUserAttributes(w, r, userID)
+	default:
+		http.NotFound(w, r)
+	}
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"zero-trust-access-platform/backend/internal/middleware"
+	"zero-trust-access-platform/backend/internal/policy"
+	"zero-trust-access-platform/backend/internal/zones"
+)
+
+// zoneRefreshInterval is how often zones are re-read so that edits made on
+// another replica take effect without a restart.
+const zoneRefreshInterval = time.Minute
+
+type putZoneRequest struct {
+	CIDRs       []string `json:"cidrs"`
+	Description string   `json:"description"`
+}
+
+// loadZones makes the stored network zones available to policy conditions.
+func (s *Server) loadZones(ctx context.Context) error {
+	z, err := zones.NewRepository(s.db).Prefixes(ctx)
+	if err != nil {
+		return err
+	}
+	policy.SetZones(z)
+	return nil
+}
+
+func (s *Server) refreshZones() {
+	for range time.Tick(zoneRefreshInterval) {
+		if err := s.loadZones(context.Background()); err != nil {
+			log.Printf("refresh network zones: %v", err)
+		}
+	}
+}
+
+// GET /admin/network-zones
+func (s *Server) handleListZones(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	list, err := zones.NewRepository(s.db).List(r.Context())
+	if err != nil {
+		http.Error(w, "failed to list network zones", http.StatusInternalServerError)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, list)
+}
+
+// PUT    /admin/network-zones/{name}  {cidrs, description}
+// DELETE /admin/network-zones/{name}
+// Changes take effect immediately on this replica and within a minute on others.
+func (s *Server) handleZone(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) != 3 || parts[0] != "admin" || parts[1] != "network-zones" || parts[2] == "" {
+		http.NotFound(w, r)
+		return
+	}
+	name := parts[2]
+	repo := zones.NewRepository(s.db)
+
+	switch r.Method {
+	case http.MethodPut:
+		userID, ok := middleware.UserID(r)
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var req putZoneRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.CIDRs) == 0 {
+			http.Error(w, "invalid body", http.StatusBadRequest)
+			return
+		}
+		prefixes, err := middleware.ParsePrefixes(req.CIDRs)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		z := zones.Zone{Name: name, Description: req.Description}
+		for _, p := range prefixes {
+			z.CIDRs = append(z.CIDRs, p.String())
+		}
+		if err := repo.Put(r.Context(), z, userID); err != nil {
+			http.Error(w, "failed to save network zone", http.StatusInternalServerError)
+			return
+		}
+
+	case http.MethodDelete:
+		err := repo.Delete(r.Context(), name)
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "network zone not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "failed to delete network zone", http.StatusInternalServerError)
+			return
+		}
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := s.loadZones(r.Context()); err != nil {
+		http.Error(w, "failed to reload network zones", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,205 @@
+package sessions
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// activeClause selects sessions that are not revoked, not past their
+// absolute expiry and, when $2 > 0, used within the last $2 seconds. An app
+// host session also ends when the session it was minted from is revoked.
+const activeClause = `revoked_at IS NULL
+  AND expires_at > NOW()
+  AND ($2 = 0 OR last_used_at > NOW() - $2 * INTERVAL '1 second')
+  AND (parent_id IS NULL OR EXISTS (
+      SELECT 1 FROM user_sessions parent
+      WHERE parent.id = user_sessions.parent_id AND parent.revoked_at IS NULL))`
+
+// evictQuery revokes the user's ($1) active MFA sessions beyond the newest
+// $3. Host sessions and sessions still waiting for MFA are not counted.
+const evictQuery = `
+UPDATE user_sessions
+SET revoked_at = NOW()
+WHERE id IN (
+    SELECT id FROM user_sessions
+    WHERE user_id = $1 AND mfa AND host IS NULL AND ` + activeClause + `
+    ORDER BY created_at DESC, id
+    OFFSET $3
+);
+`
+
+// Repository provides DB access for user_sessions.
+type Repository struct {
+	DB     *sql.DB
+	Limits Limits
+}
+
+func NewRepository(db *sql.DB, limits Limits) *Repository {
+	return &Repository{DB: db, Limits: limits}
+}
+
+// Create stores a newly issued session for a user with the given app role.
+// A session that has completed MFA gets the role's lifetime, and if the
+// role has a concurrent session limit the oldest active MFA sessions beyond
+// it are revoked. A session issued before MFA lives for PendingLifetime and
+// neither counts toward nor triggers the limit, so knowing a password is
+// not enough to sign a user out elsewhere.
+func (r *Repository) Create(ctx context.Context, s *Session, role string) error {
+	if !s.MFA {
+		s.ExpiresAt = time.Now().Add(PendingLifetime)
+	} else {
+		s.ExpiresAt = time.Now().Add(r.Limits.Lifetime(role))
+	}
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := tx.QueryRowContext(ctx, `
+INSERT INTO user_sessions (id, user_id, expires_at, ip, user_agent, mfa)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING created_at, last_used_at;
+`,
+		s.ID,
+		s.UserID,
+		s.ExpiresAt,
+		s.IP,
+		s.UserAgent,
+		s.MFA,
+	).Scan(&s.CreatedAt, &s.LastUsedAt); err != nil {
+		return err
+	}
+
+	if max := r.Limits.Concurrent(role); s.MFA && max > 0 {
+		if _, err := tx.ExecContext(ctx, evictQuery, s.UserID, r.idleSeconds(), max); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// CreateForHost stores a session scoped to host, minted from the active
+// session parentID when a login ticket is redeemed. It expires with its
+// parent and does not count toward the concurrent session limit. It
+// returns ErrInvalid if the parent is no longer active.
+func (r *Repository) CreateForHost(ctx context.Context, s *Session, parentID, host string) error {
+	err := r.DB.QueryRowContext(ctx, `
+INSERT INTO user_sessions (id, user_id, expires_at, ip, user_agent, host, parent_id, mfa)
+SELECT $3, user_id, expires_at, $4, $5, $6, id, mfa
+FROM user_sessions
+WHERE id = $1 AND `+activeClause+`
+RETURNING user_id, created_at, last_used_at, expires_at;
+`, parentID, r.idleSeconds(), s.ID, s.IP, s.UserAgent, host).Scan(&s.UserID, &s.CreatedAt, &s.LastUsedAt, &s.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrInvalid
+	}
+	if err != nil {
+		return err
+	}
+	s.Host = host
+	return nil
+}
+
+// ListActiveForUser returns the user's sessions that are neither revoked nor
+// expired, newest first.
+func (r *Repository) ListActiveForUser(ctx context.Context, userID int64) ([]Session, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+SELECT id, user_id, created_at, last_used_at, expires_at, ip, user_agent, COALESCE(host, ''), mfa, revoked_at
+FROM user_sessions
+WHERE user_id = $1 AND `+activeClause+`
+ORDER BY created_at DESC;
+`, userID, r.idleSeconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Session{}
+	for rows.Next() {
+		var s Session
+		if err := rows.Scan(
+			&s.ID,
+			&s.UserID,
+			&s.CreatedAt,
+			&s.LastUsedAt,
+			&s.ExpiresAt,
+			&s.IP,
+			&s.UserAgent,
+			&s.Host,
+			&s.MFA,
+			&s.RevokedAt,
+		); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Revoke marks one of the user's sessions as revoked. It returns
+// sql.ErrNoRows if the session does not belong to the user or is already
+// revoked, so callers cannot probe other users' session IDs.
+func (r *Repository) Revoke(ctx context.Context, userID int64, id string) error {
+	res, err := r.DB.ExecContext(ctx, `
+UPDATE user_sessions
+SET revoked_at = NOW()
+WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL;
+`, id, userID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// RevokeAll revokes every active session of the user, including host
+// sessions, e.g. after their role changed: tokens carry the role, so
+// existing ones would keep the old rights until they expire.
+func (r *Repository) RevokeAll(ctx context.Context, userID int64) error {
+	_, err := r.DB.ExecContext(ctx, `
+UPDATE user_sessions
+SET revoked_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL;
+`, userID)
+	return err
+}
+
+// Touch validates a session on use and bumps its last_used_at, which
+// slides the idle timeout. It returns ErrInvalid if the session is unknown,
+// revoked, past its absolute lifetime or has been idle for too long.
+func (r *Repository) Touch(ctx context.Context, id string) error {
+	res, err := r.DB.ExecContext(ctx, `
+UPDATE user_sessions
+SET last_used_at = NOW()
+WHERE id = $1 AND `+activeClause+`;
+`, id, r.idleSeconds())
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrInvalid
+	}
+	return nil
+}
+
+func (r *Repository) idleSeconds() int64 {
+	return int64(r.Limits.IdleTimeout / time.Second)
+}
@@ -0,0 +1,207 @@
+package sessions
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder is a database/sql driver that records every statement. Queries
+// return one row of two timestamps, which is what the INSERT ... RETURNING
+// in Create scans.
+type recorder struct {
+	mu    sync.Mutex
+	stmts []recorded
+}
+
+type recorded struct {
+	query string
+	args  []driver.Value
+}
+
+func (r *recorder) Open(string) (driver.Conn, error) { return &recConn{r}, nil }
+
+func (r *recorder) record(query string, args []driver.Value) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stmts = append(r.stmts, recorded{query, args})
+}
+
+type recConn struct{ r *recorder }
+
+func (c *recConn) Prepare(query string) (driver.Stmt, error) { return &recStmt{c.r, query}, nil }
+func (c *recConn) Close() error                              { return nil }
+func (c *recConn) Begin() (driver.Tx, error)                 { return recTx{}, nil }
+
+type recTx struct{}
+
+func (recTx) Commit() error   { return nil }
+func (recTx) Rollback() error { return nil }
+
+type recStmt struct {
+	r     *recorder
+	query string
+}
+
+func (s *recStmt) Close() error  { return nil }
+func (s *recStmt) NumInput() int { return -1 }
+
+func (s *recStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.r.record(s.query, args)
+	return driver.RowsAffected(1), nil
+}
+
+func (s *recStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.r.record(s.query, args)
+	return &recRows{}, nil
+}
+
+type recRows struct{ done bool }
+
+func (r *recRows) Columns() []string { return []string{"created_at", "last_used_at"} }
+func (r *recRows) Close() error      { return nil }
+func (r *recRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0], dest[1] = time.Now(), time.Now()
+	return nil
+}
+
+// Connect and Driver make the recorder a driver.Connector for sql.OpenDB.
+func (r *recorder) Connect(context.Context) (driver.Conn, error) { return &recConn{r}, nil }
+func (r *recorder) Driver() driver.Driver                        { return r }
+
+func newRecordingRepo(t *testing.T, limits Limits) (*Repository, *recorder) {
+	t.Helper()
+	rec := &recorder{}
+	db := sql.OpenDB(rec)
+	t.Cleanup(func() { db.Close() })
+	return NewRepository(db, limits), rec
+}
+
+func TestCreateEviction(t *testing.T) {
+	limits := Limits{
+		IdleTimeout:   30 * time.Minute,
+		MaxLifetime:   24 * time.Hour,
+		RoleLifetime:  map[string]time.Duration{"admin": 8 * time.Hour},
+		MaxConcurrent: map[string]int{"admin": 2, "user": 5},
+	}
+	tests := []struct {
+		name         string
+		role         string
+		mfa          bool
+		wantLifetime time.Duration
+		wantEvict    int // OFFSET; 0 means no eviction
+	}{
+		{name: "admin after MFA", role: "admin", mfa: true, wantLifetime: 8 * time.Hour, wantEvict: 2},
+		{name: "user after MFA", role: "user", mfa: true, wantLifetime: 24 * time.Hour, wantEvict: 5},
+		{name: "admin before MFA", role: "admin", wantLifetime: PendingLifetime},
+		{name: "role without a limit", role: "auditor", mfa: true, wantLifetime: 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, rec := newRecordingRepo(t, limits)
+			s := &Session{ID: "s1", UserID: 7, MFA: tt.mfa}
+			start := time.Now()
+			if err := repo.Create(context.Background(), s, tt.role); err != nil {
+				t.Fatal(err)
+			}
+			if got := s.ExpiresAt.Sub(start); got < tt.wantLifetime || got > tt.wantLifetime+time.Minute {
+				t.Errorf("lifetime = %s, want %s", got, tt.wantLifetime)
+			}
+
+			if len(rec.stmts) == 0 || !strings.Contains(rec.stmts[0].query, "INSERT INTO user_sessions") {
+				t.Fatalf("statements = %v, want the INSERT first", rec.stmts)
+			}
+			if mfa := rec.stmts[0].args[5]; mfa != tt.mfa {
+				t.Errorf("stored mfa = %v, want %v", mfa, tt.mfa)
+			}
+
+			var evictions []recorded
+			for _, st := range rec.stmts[1:] {
+				if st.query == evictQuery {
+					evictions = append(evictions, st)
+				}
+			}
+			if tt.wantEvict == 0 {
+				if len(evictions) != 0 {
+					t.Fatalf("evicted sessions: %v", evictions)
+				}
+				return
+			}
+			if len(evictions) != 1 {
+				t.Fatalf("ran the eviction %d times, want once", len(evictions))
+			}
+			args := evictions[0].args
+			if args[0] != int64(7) || args[1] != int64(1800) || args[2] != int64(tt.wantEvict) {
+				t.Errorf("eviction args = %v, want [7 1800 %d]", args, tt.wantEvict)
+			}
+		})
+	}
+}
+
+func TestEvictQueryCountsOnlyMFASessions(t *testing.T) {
+	for _, want := range []string{
+		"user_id = $1",
+		"AND mfa",
+		"host IS NULL",
+		"revoked_at IS NULL",
+		"ORDER BY created_at DESC, id",
+		"OFFSET $3",
+	} {
+		if !strings.Contains(evictQuery, want) {
+			t.Errorf("evictQuery lacks %q", want)
+		}
+	}
+}
+
+func TestRevokeAll(t *testing.T) {
+	repo, rec := newRecordingRepo(t, Limits{})
+	if err := repo.RevokeAll(context.Background(), 7); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.stmts) != 1 {
+		t.Fatalf("ran %d statements, want 1", len(rec.stmts))
+	}
+	st := rec.stmts[0]
+	if !strings.Contains(st.query, "WHERE user_id = $1 AND revoked_at IS NULL") || strings.Contains(st.query, "host") {
+		t.Errorf("query = %q, want every active session of the user", st.query)
+	}
+	if len(st.args) != 1 || st.args[0] != int64(7) {
+		t.Errorf("args = %v, want [7]", st.args)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	l := Limits{
+		MaxLifetime:   24 * time.Hour,
+		RoleLifetime:  map[string]time.Duration{"admin": 8 * time.Hour},
+		MaxConcurrent: map[string]int{"admin": 2, "user": 5},
+	}
+	tests := []struct {
+		role           string
+		wantLifetime   time.Duration
+		wantConcurrent int
+	}{
+		{role: "admin", wantLifetime: 8 * time.Hour, wantConcurrent: 2},
+		{role: "user", wantLifetime: 24 * time.Hour, wantConcurrent: 5},
+		// devops inherits user in the built-in hierarchy.
+		{role: "devops", wantLifetime: 24 * time.Hour, wantConcurrent: 5},
+		{role: "unknown", wantLifetime: 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		if got := l.Lifetime(tt.role); got != tt.wantLifetime {
+			t.Errorf("Lifetime(%s) = %s, want %s", tt.role, got, tt.wantLifetime)
+		}
+		if got := l.Concurrent(tt.role); got != tt.wantConcurrent {
+			t.Errorf("Concurrent(%s) = %d, want %d", tt.role, got, tt.wantConcurrent)
+		}
+	}
+}
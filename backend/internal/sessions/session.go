@@ -0,0 +1,87 @@
+package sessions
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"zero-trust-access-platform/backend/internal/middleware"
+	"zero-trust-access-platform/backend/internal/policy"
+)
+
+// ErrInvalid is returned when a session does not exist, was revoked or has
+// expired. Callers should treat it as "not authenticated".
+var ErrInvalid = middleware.ErrSessionInvalid
+
+// Session is a single issued API token. The ID doubles as the JWT "jti"
+// claim, so middleware.Auth can reject a token as soon as its row is revoked.
+type Session struct {
+	ID         string     `json:"id"`
+	UserID     int64      `json:"user_id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt time.Time  `json:"last_used_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	IP         string     `json:"ip"`
+	UserAgent  string     `json:"user_agent"`
+	Host       string     `json:"host,omitempty"` // app host the session is scoped to, for ticket logins
+	MFA        bool       `json:"mfa"`            // false for the temporary session issued before MFA
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+
+	// Current is not stored; handlers set it for the session making the request.
+	Current bool `json:"current"`
+}
+
+// PendingLifetime is the fixed lifetime of a session issued before MFA,
+// which only serves the enroll and verify calls.
+const PendingLifetime = 10 * time.Minute
+
+// Limits are the server-side session controls. A zero IdleTimeout or
+// MaxConcurrent entry disables that check. Per-role entries also apply to
+// roles that inherit the role (see forRole).
+type Limits struct {
+	IdleTimeout   time.Duration            // sliding; reset on every request
+	MaxLifetime   time.Duration            // absolute, from creation
+	RoleLifetime  map[string]time.Duration // per-role override of MaxLifetime
+	MaxConcurrent map[string]int           // per-role; oldest sessions are evicted
+}
+
+// Lifetime returns the absolute session lifetime for the given app role.
+func (l Limits) Lifetime(role string) time.Duration {
+	if d, ok := forRole(l.RoleLifetime, role); ok {
+		return d
+	}
+	return l.MaxLifetime
+}
+
+// Concurrent returns how many active sessions a user with the given app
+// role may have; 0 means unlimited.
+func (l Limits) Concurrent(role string) int {
+	n, _ := forRole(l.MaxConcurrent, role)
+	return n
+}
+
+// forRole returns the positive value set for role itself or, failing that,
+// the smallest one set for a role it inherits, so that a custom role based
+// on admin gets the admin limits.
+func forRole[T int | time.Duration](m map[string]T, role string) (T, bool) {
+	if v := m[role]; v > 0 {
+		return v, true
+	}
+	var best T
+	found := false
+	for _, r := range policy.EffectiveRoles(role) {
+		if v := m[r]; v > 0 && (!found || v < best) {
+			best, found = v, true
+		}
+	}
+	return best, found
+}
+
+// NewID returns a random, URL-safe session identifier.
+func NewID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,56 @@
+package sessions
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+// TicketLifetime bounds how long a login ticket can be redeemed.
+const TicketLifetime = time.Minute
+
+// CreateTicket stores a one-time login code that hands the session
+// sessionID to host, and returns the code. Only its hash is stored.
+func (r *Repository) CreateTicket(ctx context.Context, userID int64, sessionID, host string) (string, error) {
+	code, err := NewID()
+	if err != nil {
+		return "", err
+	}
+	// Expired tickets are never redeemed; clear them out on the way.
+	if _, err := r.DB.ExecContext(ctx, `DELETE FROM login_tickets WHERE expires_at < NOW();`); err != nil {
+		return "", err
+	}
+	_, err = r.DB.ExecContext(ctx, `
+INSERT INTO login_tickets (code_hash, host, user_id, session_id, expires_at)
+VALUES ($1, $2, $3, $4, $5);
+`, hashCode(code), host, userID, sessionID, time.Now().Add(TicketLifetime))
+	if err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+// RedeemTicket consumes the code for host and returns the session it was
+// issued from. The row is deleted in the same statement, so a code works
+// once across all replicas. It returns ErrInvalid for an unknown, used or
+// expired code, or one issued for another host.
+func (r *Repository) RedeemTicket(ctx context.Context, code, host string) (string, error) {
+	var sessionID string
+	err := r.DB.QueryRowContext(ctx, `
+DELETE FROM login_tickets
+WHERE code_hash = $1 AND host = $2 AND expires_at > NOW()
+RETURNING session_id;
+`, hashCode(code), host).Scan(&sessionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrInvalid
+	}
+	return sessionID, err
+}
+
+func hashCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
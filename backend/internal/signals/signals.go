@@ -0,0 +1,265 @@
+// Package signals enriches the policy input before evaluation. Signal
+// providers (device posture, geo, threat intel, HR status, ...) register
+// with a Registry; Enrich runs them and fills AccessContext.Signals, so a
+// new signal needs no change to the handlers that build the context.
+package signals
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"zero-trust-access-platform/backend/internal/policy"
+)
+
+// DefaultTimeout bounds a provider registered without a timeout.
+const DefaultTimeout = 200 * time.Millisecond
+
+// Request is what a provider may look at besides the access context.
+type Request struct {
+	UserAgent string // empty when the caller does not know it
+}
+
+// Attributes are a provider's signals by name, e.g. {"status": "active",
+// "on_leave": false}. Values must be strings, booleans or integers.
+type Attributes map[string]any
+
+// SignalProvider computes signals for a request. ac is the context built
+// so far, without other providers' signals; providers must not modify it.
+// They should honour ctx, which carries the provider's timeout.
+type SignalProvider interface {
+	Name() string
+	Signals(ctx context.Context, ac policy.AccessContext, req Request) (Attributes, error)
+}
+
+// ContextProvider is a SignalProvider whose result also fills typed fields
+// of the access context, such as the threat-intel match or the risk score.
+// Once every provider has run, Enrich calls Apply with the signals of each
+// one that succeeded, in registration order.
+type ContextProvider interface {
+	SignalProvider
+	Apply(ac *policy.AccessContext, attrs Attributes)
+}
+
+// Options control how a provider is run.
+type Options struct {
+	Timeout time.Duration // 0 means DefaultTimeout
+
+	// FailClosed denies the request when the provider fails or times out.
+	// By default the request is evaluated without its signals.
+	FailClosed bool
+}
+
+// ProviderError is returned by Enrich when a fail-closed provider fails.
+type ProviderError struct {
+	Provider string
+	Err      error
+}
+
+func (e *ProviderError) Error() string {
+	return fmt.Sprintf("signal provider %s failed: %v", e.Provider, e.Err)
+}
+
+func (e *ProviderError) Unwrap() error { return e.Err }
+
+// Denied is the decision for a request whose enrichment failed closed.
+func Denied(err error) policy.Decision {
+	return policy.Decision{
+		Allowed:  false,
+		Policy:   "signal-provider-failed",
+		Policies: []string{},
+		Reason:   err.Error(),
+	}
+}
+
+var providerName = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+type entry struct {
+	p    SignalProvider
+	opts Options
+
+	calls, failures, timeouts, nanos atomic.Int64
+}
+
+// Registry holds the signal providers and runs them before every
+// evaluation.
+type Registry struct {
+	mu      sync.RWMutex
+	entries []*entry
+}
+
+// NewRegistry returns a registry without providers.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds a provider. Names must be lower_snake_case and unique;
+// they prefix the provider's signals.
+func (r *Registry) Register(p SignalProvider, opts Options) error {
+	name := p.Name()
+	if !providerName.MatchString(name) {
+		return fmt.Errorf("signal provider name %q must be lower_snake_case", name)
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range r.entries {
+		if e.p.Name() == name {
+			return fmt.Errorf("signal provider %q is already registered", name)
+		}
+	}
+	r.entries = append(r.entries, &entry{p: p, opts: opts})
+	return nil
+}
+
+// Enrich is the enrichment step: it runs every provider concurrently,
+// stores their signals in ac.Signals as <provider>.<name> and lets context
+// providers fill their fields. It returns a *ProviderError if a
+// fail-closed provider fails; callers then deny with Denied.
+func (r *Registry) Enrich(ctx context.Context, ac *policy.AccessContext, req Request) error {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.RLock()
+	entries := r.entries
+	r.mu.RUnlock()
+
+	results := make([]Attributes, len(entries))
+	errs := make([]error, len(entries))
+	var wg sync.WaitGroup
+	for i, e := range entries {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = e.run(ctx, *ac, req)
+		}()
+	}
+	wg.Wait()
+
+	var failed error
+	for i, e := range entries {
+		name := e.p.Name()
+		if errs[i] != nil {
+			if e.opts.FailClosed {
+				if failed == nil {
+					failed = &ProviderError{Provider: name, Err: errs[i]}
+				}
+				continue
+			}
+			log.Printf("signals: %s: %v (ignored, fail-open)", name, errs[i])
+			continue
+		}
+		for k, v := range results[i] {
+			if ac.Signals == nil {
+				ac.Signals = map[string]any{}
+			}
+			ac.Signals[name+"."+k] = v
+		}
+		if cp, ok := e.p.(ContextProvider); ok {
+			cp.Apply(ac, results[i])
+		}
+	}
+	return failed
+}
+
+func (e *entry) run(ctx context.Context, ac policy.AccessContext, req Request) (Attributes, error) {
+	ctx, cancel := context.WithTimeout(ctx, e.opts.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	type result struct {
+		attrs Attributes
+		err   error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		attrs, err := e.p.Signals(ctx, ac, req)
+		ch <- result{attrs, err}
+	}()
+
+	// A provider that ignores ctx cannot hold up the request.
+	var res result
+	select {
+	case res = <-ch:
+	case <-ctx.Done():
+		res.err = ctx.Err()
+	}
+	e.calls.Add(1)
+	e.nanos.Add(int64(time.Since(start)))
+
+	if res.err == nil {
+		res.err = validate(res.attrs)
+	}
+	if res.err != nil {
+		e.failures.Add(1)
+		if errors.Is(res.err, context.DeadlineExceeded) {
+			e.timeouts.Add(1)
+			res.err = fmt.Errorf("timed out after %s", e.opts.Timeout)
+		}
+		return nil, res.err
+	}
+	return res.attrs, nil
+}
+
+var signalName = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// validate checks names and value types, so that policies can rely on
+// signal.<provider>.<name> being a string, boolean or integer.
+func validate(attrs Attributes) error {
+	for k, v := range attrs {
+		if !signalName.MatchString(k) {
+			return fmt.Errorf("signal name %q must be lower_snake_case", k)
+		}
+		switch v.(type) {
+		case string, bool, int, int64:
+		default:
+			return fmt.Errorf("signal %q: %T is not a string, boolean or integer", k, v)
+		}
+	}
+	return nil
+}
+
+// ProviderStats reports how a provider has been doing.
+type ProviderStats struct {
+	Name           string  `json:"name"`
+	TimeoutMS      int64   `json:"timeout_ms"`
+	FailClosed     bool    `json:"fail_closed"`
+	Calls          int64   `json:"calls"`
+	Failures       int64   `json:"failures"`
+	Timeouts       int64   `json:"timeouts"`
+	AverageLatency float64 `json:"average_latency_ms"`
+}
+
+// Stats lists the registered providers by name.
+func (r *Registry) Stats() []ProviderStats {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make([]ProviderStats, 0, len(r.entries))
+	for _, e := range r.entries {
+		st := ProviderStats{
+			Name:       e.p.Name(),
+			TimeoutMS:  e.opts.Timeout.Milliseconds(),
+			FailClosed: e.opts.FailClosed,
+			Calls:      e.calls.Load(),
+			Failures:   e.failures.Load(),
+			Timeouts:   e.timeouts.Load(),
+		}
+		if st.Calls > 0 {
+			st.AverageLatency = float64(e.nanos.Load()) / float64(st.Calls) / 1e6
+		}
+		out = append(out, st)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
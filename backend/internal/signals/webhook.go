@@ -0,0 +1,73 @@
+package signals
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"zero-trust-access-platform/backend/internal/policy"
+)
+
+// Webhook is a provider implemented by another service: it POSTs
+//
+//	{"context": <AccessContext>, "user_agent": "..."}
+//
+// to URL and expects a JSON object of signals back, e.g.
+// {"status": "active", "on_leave": false}.
+type Webhook struct {
+	ProviderName string
+	URL          string
+	Client       *http.Client
+}
+
+func (w Webhook) Name() string { return w.ProviderName }
+
+func (w Webhook) Signals(ctx context.Context, ac policy.AccessContext, req Request) (Attributes, error) {
+	body, err := json.Marshal(map[string]any{
+		"context":    ac,
+		"user_agent": req.UserAgent,
+	})
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	client := w.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", w.URL, resp.Status)
+	}
+
+	dec := json.NewDecoder(io.LimitReader(resp.Body, 64<<10))
+	dec.UseNumber()
+	var raw map[string]any
+	if err := dec.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("invalid response: %w", err)
+	}
+	attrs := make(Attributes, len(raw))
+	for k, v := range raw {
+		if n, ok := v.(json.Number); ok {
+			i, err := n.Int64()
+			if err != nil {
+				return nil, fmt.Errorf("signal %q: %s is not an integer", k, n)
+			}
+			v = i
+		}
+		attrs[k] = v
+	}
+	return attrs, nil
+}
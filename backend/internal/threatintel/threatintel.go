@@ -0,0 +1,315 @@
+// Package threatintel matches client IPs against local IP reputation feeds:
+// plain lists of IPs and CIDRs (one per line, "#" or ";" starts a
+// comment), or CSV files with the IP or CIDR in the first column and a
+// category in the second, e.g.
+//
+//	ip,category
+//	203.0.113.0/24,botnet
+//	198.51.100.7,tor_exit
+//
+// Files are re-read when their content changes; a file that fails to
+// parse keeps its previous entries.
+package threatintel
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"zero-trust-access-platform/backend/internal/policy"
+	"zero-trust-access-platform/backend/internal/signals"
+)
+
+// Feed is one loaded file.
+type Feed struct {
+	Name       string         `json:"name"` // file name without extension
+	Path       string         `json:"path"`
+	Format     string         `json:"format"` // "cidr" or "csv"
+	Entries    int            `json:"entries"`
+	Categories map[string]int `json:"categories,omitempty"`
+	Revision   string         `json:"revision"`
+	LoadedAt   time.Time      `json:"loaded_at"`
+	Error      string         `json:"error,omitempty"` // last reload error
+}
+
+type entry struct {
+	feed     string
+	category string
+	prefix   netip.Prefix
+}
+
+// table is an immutable snapshot of every feed's entries, indexed by
+// prefix length so a lookup is one map probe per length in use.
+type table struct {
+	byLen map[int]map[netip.Prefix]entry
+	lens  []int // longest first
+}
+
+// Store holds the feeds loaded from a set of files.
+type Store struct {
+	paths []string
+
+	mu      sync.Mutex // serialises Reload
+	feeds   map[string]*Feed
+	entries map[string][]entry // by path
+
+	table atomic.Pointer[table]
+}
+
+// NewStore returns a store for paths; call Reload to load them.
+func NewStore(paths []string) *Store {
+	s := &Store{
+		paths:   paths,
+		feeds:   map[string]*Feed{},
+		entries: map[string][]entry{},
+	}
+	s.table.Store(&table{byLen: map[int]map[netip.Prefix]entry{}})
+	return s
+}
+
+// Reload re-reads the files whose content changed. It returns the errors
+// of the files that could not be loaded; their previous entries stay.
+func (s *Store) Reload() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var errs []error
+	changed := false
+	for _, path := range s.paths {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			errs = append(errs, s.failed(path, err))
+			continue
+		}
+		sum := sha256.Sum256(raw)
+		rev := hex.EncodeToString(sum[:8])
+		if f := s.feeds[path]; f != nil && f.Revision == rev {
+			f.Error = ""
+			continue
+		}
+
+		f := &Feed{Name: feedName(path), Path: path, Format: "cidr", Revision: rev, LoadedAt: time.Now()}
+		var list []entry
+		if strings.EqualFold(filepath.Ext(path), ".csv") {
+			f.Format = "csv"
+			list, err = parseCSV(f.Name, raw)
+		} else {
+			list, err = parseCIDRs(f.Name, raw)
+		}
+		if err != nil {
+			errs = append(errs, s.failed(path, err))
+			continue
+		}
+		f.Entries = len(list)
+		for _, e := range list {
+			if e.category != "" {
+				if f.Categories == nil {
+					f.Categories = map[string]int{}
+				}
+				f.Categories[e.category]++
+			}
+		}
+		s.feeds[path] = f
+		s.entries[path] = list
+		changed = true
+	}
+
+	if changed {
+		s.table.Store(s.build())
+	}
+	return errors.Join(errs...)
+}
+
+func (s *Store) failed(path string, err error) error {
+	err = fmt.Errorf("%s: %w", path, err)
+	if f := s.feeds[path]; f != nil {
+		f.Error = err.Error()
+	} else {
+		s.feeds[path] = &Feed{Name: feedName(path), Path: path, Error: err.Error()}
+	}
+	return err
+}
+
+// build merges the feeds in configuration order; for a prefix listed by
+// several feeds the first one wins.
+func (s *Store) build() *table {
+	t := &table{byLen: map[int]map[netip.Prefix]entry{}}
+	for _, path := range s.paths {
+		for _, e := range s.entries[path] {
+			m := t.byLen[e.prefix.Bits()]
+			if m == nil {
+				m = map[netip.Prefix]entry{}
+				t.byLen[e.prefix.Bits()] = m
+				t.lens = append(t.lens, e.prefix.Bits())
+			}
+			if _, dup := m[e.prefix]; !dup {
+				m[e.prefix] = e
+			}
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(t.lens)))
+	return t
+}
+
+// Lookup returns the most specific entry containing ip, if any.
+func (s *Store) Lookup(ip string) (*policy.ThreatIntel, bool) {
+	if s == nil {
+		return nil, false
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return nil, false
+	}
+	addr = addr.Unmap()
+
+	t := s.table.Load()
+	for _, bits := range t.lens {
+		if bits > addr.BitLen() {
+			continue
+		}
+		p, err := addr.Prefix(bits)
+		if err != nil {
+			continue
+		}
+		if e, ok := t.byLen[bits][p]; ok {
+			return &policy.ThreatIntel{Feed: e.feed, Category: e.category, Prefix: e.prefix.String()}, true
+		}
+	}
+	return nil, false
+}
+
+// The store is the signal provider threat_intel: its signals are listed,
+// and feed, category and prefix for a match. Apply sets
+// AccessContext.ThreatIntel.
+func (s *Store) Name() string { return "threat_intel" }
+
+func (s *Store) Signals(_ context.Context, ac policy.AccessContext, _ signals.Request) (signals.Attributes, error) {
+	ti, ok := s.Lookup(ac.ClientIP)
+	if !ok {
+		return signals.Attributes{"listed": false}, nil
+	}
+	return signals.Attributes{"listed": true, "feed": ti.Feed, "category": ti.Category, "prefix": ti.Prefix}, nil
+}
+
+func (s *Store) Apply(ac *policy.AccessContext, attrs signals.Attributes) {
+	if listed, _ := attrs["listed"].(bool); !listed {
+		return
+	}
+	ti := &policy.ThreatIntel{}
+	ti.Feed, _ = attrs["feed"].(string)
+	ti.Category, _ = attrs["category"].(string)
+	ti.Prefix, _ = attrs["prefix"].(string)
+	ac.ThreatIntel = ti
+}
+
+// Feeds lists the configured files and how they loaded.
+func (s *Store) Feeds() []Feed {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]Feed, 0, len(s.paths))
+	for _, path := range s.paths {
+		if f := s.feeds[path]; f != nil {
+			out = append(out, *f)
+		} else {
+			out = append(out, Feed{Name: feedName(path), Path: path})
+		}
+	}
+	return out
+}
+
+func feedName(path string) string {
+	base := filepath.Base(path)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// parsePrefix accepts "203.0.113.0/24" or a single address.
+func parsePrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			return p, err
+		}
+		if p.Addr().Is4In6() && p.Bits() >= 96 {
+			p = netip.PrefixFrom(p.Addr().Unmap(), p.Bits()-96)
+		}
+		return p.Masked(), nil
+	}
+	a, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	a = a.Unmap()
+	return netip.PrefixFrom(a, a.BitLen()), nil
+}
+
+func parseCIDRs(feed string, raw []byte) ([]entry, error) {
+	var out []entry
+	for i, line := range strings.Split(string(raw), "\n") {
+		if j := strings.IndexAny(line, "#;"); j >= 0 {
+			line = line[:j]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		// Some lists carry extra columns after the address.
+		if f := strings.Fields(line); len(f) > 1 {
+			line = f[0]
+		}
+		p, err := parsePrefix(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		out = append(out, entry{feed: feed, prefix: p})
+	}
+	return out, nil
+}
+
+func parseCSV(feed string, raw []byte) ([]entry, error) {
+	r := csv.NewReader(bytes.NewReader(raw))
+	r.Comment = '#'
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	var out []entry
+	for n := 1; ; n++ {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(rec) == 0 || strings.TrimSpace(rec[0]) == "" {
+			continue
+		}
+		p, err := parsePrefix(strings.TrimSpace(rec[0]))
+		if err != nil {
+			if n == 1 {
+				continue // header
+			}
+			row, _ := r.FieldPos(0)
+			return nil, fmt.Errorf("line %d: %w", row, err)
+		}
+		e := entry{feed: feed, prefix: p}
+		if len(rec) > 1 {
+			e.category = strings.ToLower(strings.TrimSpace(rec[1]))
+		}
+		out = append(out, e)
+	}
+	return out, nil
+}
@@ -0,0 +1,87 @@
+package threatintel
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"zero-trust-access-platform/backend/internal/policy"
+)
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestStoreLookup(t *testing.T) {
+	dir := t.TempDir()
+	drop := writeFile(t, dir, "drop.txt", `
+# Spamhaus-style list
+203.0.113.0/24 ; SBL123
+198.51.100.7
+2001:db8::/32
+::ffff:192.0.2.0/120
+`)
+	rep := writeFile(t, dir, "reputation.csv", "ip,category\n203.0.113.128/25,Botnet\n192.0.2.9,tor_exit\n")
+
+	s := NewStore([]string{drop, rep})
+	if err := s.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		ip   string
+		want *policy.ThreatIntel
+	}{
+		{ip: "203.0.113.9", want: &policy.ThreatIntel{Feed: "drop", Prefix: "203.0.113.0/24"}},
+		{ip: "203.0.113.200", want: &policy.ThreatIntel{Feed: "reputation", Category: "botnet", Prefix: "203.0.113.128/25"}},
+		{ip: "198.51.100.7", want: &policy.ThreatIntel{Feed: "drop", Prefix: "198.51.100.7/32"}},
+		{ip: "::ffff:198.51.100.7", want: &policy.ThreatIntel{Feed: "drop", Prefix: "198.51.100.7/32"}},
+		{ip: "192.0.2.9", want: &policy.ThreatIntel{Feed: "reputation", Category: "tor_exit", Prefix: "192.0.2.9/32"}},
+		{ip: "192.0.2.10", want: &policy.ThreatIntel{Feed: "drop", Prefix: "192.0.2.0/24"}},
+		{ip: "2001:db8::1", want: &policy.ThreatIntel{Feed: "drop", Prefix: "2001:db8::/32"}},
+		{ip: "198.51.100.8"},
+		{ip: "not an ip"},
+	}
+	for _, tt := range tests {
+		got, ok := s.Lookup(tt.ip)
+		if ok != (tt.want != nil) || (ok && *got != *tt.want) {
+			t.Errorf("Lookup(%s) = %+v, %v; want %+v", tt.ip, got, ok, tt.want)
+		}
+	}
+}
+
+func TestStoreReloadKeepsEntriesOnError(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "drop.txt", "203.0.113.0/24\n")
+	s := NewStore([]string{path})
+	if err := s.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	writeFile(t, dir, "drop.txt", "203.0.113.0/24\nnot-a-cidr\n")
+	if err := s.Reload(); err == nil {
+		t.Fatal("want a parse error")
+	}
+	if _, ok := s.Lookup("203.0.113.9"); !ok {
+		t.Fatal("a broken file dropped the previous entries")
+	}
+	if feeds := s.Feeds(); len(feeds) != 1 || feeds[0].Error == "" {
+		t.Fatalf("Feeds() = %+v, want the error reported", feeds)
+	}
+
+	writeFile(t, dir, "drop.txt", "198.51.100.0/24\n")
+	if err := s.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.Lookup("203.0.113.9"); ok {
+		t.Error("old entry still listed after a successful reload")
+	}
+	if _, ok := s.Lookup("198.51.100.1"); !ok {
+		t.Error("new entry not listed")
+	}
+}
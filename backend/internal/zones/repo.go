@@ -0,0 +1,109 @@
+package zones
+
+import (
+	"context"
+	"database/sql"
+	"net/netip"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Zone is a named set of CIDRs, e.g. "corp-vpn".
+type Zone struct {
+	Name        string    `json:"name"`
+	CIDRs       []string  `json:"cidrs"`
+	Description string    `json:"description"`
+	UpdatedBy   *int64    `json:"updated_by"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Repository provides DB access for network_zones.
+type Repository struct {
+	DB *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{DB: db}
+}
+
+// List returns all zones ordered by name.
+func (r *Repository) List(ctx context.Context) ([]Zone, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+SELECT name, cidrs, description, updated_by, updated_at
+FROM network_zones
+ORDER BY name;
+`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Zone{}
+	for rows.Next() {
+		var z Zone
+		if err := rows.Scan(
+			&z.Name,
+			pq.Array(&z.CIDRs),
+			&z.Description,
+			&z.UpdatedBy,
+			&z.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		out = append(out, z)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Put creates or replaces a zone. CIDRs must already be validated.
+func (r *Repository) Put(ctx context.Context, z Zone, updatedBy int64) error {
+	_, err := r.DB.ExecContext(ctx, `
+INSERT INTO network_zones (name, cidrs, description, updated_by, updated_at)
+VALUES ($1, $2, $3, $4, NOW())
+ON CONFLICT (name) DO UPDATE
+SET cidrs = EXCLUDED.cidrs,
+    description = EXCLUDED.description,
+    updated_by = EXCLUDED.updated_by,
+    updated_at = EXCLUDED.updated_at;
+`, z.Name, pq.Array(z.CIDRs), z.Description, updatedBy)
+	return err
+}
+
+// Delete removes a zone, returning sql.ErrNoRows if it does not exist.
+func (r *Repository) Delete(ctx context.Context, name string) error {
+	res, err := r.DB.ExecContext(ctx, `DELETE FROM network_zones WHERE name = $1;`, name)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Prefixes loads every zone as parsed prefixes, skipping entries that no
+// longer parse (they were validated on write).
+func (r *Repository) Prefixes(ctx context.Context) (map[string][]netip.Prefix, error) {
+	list, err := r.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string][]netip.Prefix, len(list))
+	for _, z := range list {
+		for _, c := range z.CIDRs {
+			if p, err := netip.ParsePrefix(c); err == nil {
+				out[z.Name] = append(out[z.Name], p.Masked())
+			}
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,16 @@
+-- Tracks every token issued by the API so users and admins can see where an
+-- account is signed in and revoke individual sessions.
+CREATE TABLE IF NOT EXISTS user_sessions (
+    id           TEXT PRIMARY KEY,          -- JWT "jti" claim
+    user_id      BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at   TIMESTAMPTZ NOT NULL,
+    ip           TEXT NOT NULL DEFAULT '',
+    user_agent   TEXT NOT NULL DEFAULT '',
+    revoked_at   TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS user_sessions_active_idx
+    ON user_sessions (user_id, created_at)
+    WHERE revoked_at IS NULL;
@@ -0,0 +1,8 @@
+-- Declarative policy set evaluated by policy.Evaluate. The source is kept as
+-- written (YAML or JSON) so comments and formatting survive edits.
+CREATE TABLE IF NOT EXISTS policy_sets (
+    name       TEXT PRIMARY KEY,
+    source     TEXT NOT NULL,
+    updated_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,40 @@
+-- Immutable history of policy documents. Rows are never updated; which
+-- version is live is recorded separately in policy_activations.
+CREATE TABLE IF NOT EXISTS policy_versions (
+    id         BIGSERIAL PRIMARY KEY,
+    parent_id  BIGINT REFERENCES policy_versions(id),
+    source     TEXT NOT NULL,
+    comment    TEXT NOT NULL DEFAULT '',
+    diff       TEXT NOT NULL DEFAULT '',
+    author_id  BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Append-only activation log; the newest row is the active version.
+CREATE TABLE IF NOT EXISTS policy_activations (
+    id           BIGSERIAL PRIMARY KEY,
+    version_id   BIGINT NOT NULL REFERENCES policy_versions(id),
+    kind         TEXT NOT NULL CHECK (kind IN ('activate', 'rollback')),
+    activated_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    activated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Carry over a policy set saved before versioning existed.
+INSERT INTO policy_versions (source, comment, author_id, created_at)
+SELECT source, 'imported from policy_sets', updated_by, updated_at
+FROM policy_sets
+WHERE name = 'default'
+  AND NOT EXISTS (SELECT 1 FROM policy_versions);
+
+INSERT INTO policy_activations (version_id, kind, activated_by, activated_at)
+SELECT id, 'activate', author_id, created_at
+FROM policy_versions
+WHERE NOT EXISTS (SELECT 1 FROM policy_activations)
+ORDER BY id
+LIMIT 1;
+
+DROP TABLE IF EXISTS policy_sets;
+
+-- Which policy version produced each logged decision.
+ALTER TABLE access_logs
+    ADD COLUMN IF NOT EXISTS policy_version_id BIGINT REFERENCES policy_versions(id);
@@ -0,0 +1,7 @@
+-- Full policy.AccessContext that was evaluated, so past decisions can be
+-- replayed against a candidate policy set.
+ALTER TABLE access_logs
+    ADD COLUMN IF NOT EXISTS context JSONB;
+
+CREATE INDEX IF NOT EXISTS access_logs_created_at_idx
+    ON access_logs (created_at);
@@ -0,0 +1,5 @@
+-- Would-be decision of shadow policies, recorded next to the enforced one.
+ALTER TABLE access_logs
+    ADD COLUMN IF NOT EXISTS shadow_decision    TEXT,
+    ADD COLUMN IF NOT EXISTS shadow_policy_name TEXT,
+    ADD COLUMN IF NOT EXISTS shadow_reason      TEXT;
@@ -0,0 +1,8 @@
+-- Named network zones (e.g. corp-vpn) referenced by in_zone policy conditions.
+CREATE TABLE IF NOT EXISTS network_zones (
+    name        TEXT PRIMARY KEY,
+    cidrs       TEXT[] NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    updated_by  BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
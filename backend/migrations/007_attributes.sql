@@ -0,0 +1,15 @@
+-- Free-form ABAC attributes, exposed to policies as user.<key> and
+-- resource.<key>. List values (e.g. tags) are stored comma-separated.
+CREATE TABLE IF NOT EXISTS user_attributes (
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    key     TEXT NOT NULL,
+    value   TEXT NOT NULL,
+    PRIMARY KEY (user_id, key)
+);
+
+CREATE TABLE IF NOT EXISTS resource_attributes (
+    resource_id BIGINT NOT NULL REFERENCES resources(id) ON DELETE CASCADE,
+    key         TEXT NOT NULL,
+    value       TEXT NOT NULL,
+    PRIMARY KEY (resource_id, key)
+);
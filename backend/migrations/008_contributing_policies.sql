@@ -0,0 +1,4 @@
+-- Every policy that contributed to a decision under a combining algorithm.
+-- policy_name keeps the first of them.
+ALTER TABLE access_logs
+    ADD COLUMN IF NOT EXISTS contributing_policies TEXT[];
@@ -0,0 +1,4 @@
+-- Obligations and advice fulfilled for an allow, e.g. the session cap
+-- applied, the justification given or the owner notified.
+ALTER TABLE access_logs
+    ADD COLUMN IF NOT EXISTS obligations JSONB;
@@ -0,0 +1,8 @@
+-- Rego modules for the OPA policy engine (POLICY_ENGINE=rego without
+-- REGO_BUNDLE_DIR).
+CREATE TABLE IF NOT EXISTS rego_modules (
+    name       TEXT PRIMARY KEY,
+    source     TEXT NOT NULL,
+    updated_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,15 @@
+-- Credentials for other services calling the platform, e.g. the AuthZEN
+-- evaluation API. Secrets are stored as SHA-256 hex.
+CREATE TABLE IF NOT EXISTS service_clients (
+    id           TEXT PRIMARY KEY,
+    name         TEXT NOT NULL,
+    secret_hash  TEXT NOT NULL,
+    created_by   BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_used_at TIMESTAMPTZ,
+    revoked_at   TIMESTAMPTZ
+);
+
+-- Which service client asked for a decision, if any.
+ALTER TABLE access_logs
+    ADD COLUMN IF NOT EXISTS service_client TEXT;
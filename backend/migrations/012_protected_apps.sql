@@ -0,0 +1,11 @@
+-- Internal web apps behind Envoy ext_authz / nginx auth_request: which
+-- registered resource a host and path prefix belong to.
+CREATE TABLE IF NOT EXISTS protected_apps (
+    id          BIGSERIAL PRIMARY KEY,
+    host        TEXT NOT NULL,
+    path_prefix TEXT NOT NULL DEFAULT '/',
+    resource_id BIGINT NOT NULL REFERENCES resources(id) ON DELETE CASCADE,
+    updated_by  BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (host, path_prefix)
+);
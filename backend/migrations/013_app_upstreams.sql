@@ -0,0 +1,4 @@
+-- Upstream URL for apps served by the built-in identity-aware proxy
+-- (PROXY_ADDR). Apps without one are only used for forward auth.
+ALTER TABLE protected_apps
+    ADD COLUMN IF NOT EXISTS upstream TEXT;
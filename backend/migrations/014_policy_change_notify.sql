@@ -0,0 +1,44 @@
+-- Every replica LISTENs on zt_policy_changed and drops its decision cache
+-- (and reloads policies, zones or Rego modules) when any input to policy
+-- decisions changes. The payload is the table that changed.
+CREATE OR REPLACE FUNCTION notify_policy_changed() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('zt_policy_changed', TG_TABLE_NAME);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS policy_changed ON policy_activations;
+CREATE TRIGGER policy_changed
+    AFTER INSERT OR UPDATE OR DELETE ON policy_activations
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_policy_changed();
+
+DROP TRIGGER IF EXISTS policy_changed ON rego_modules;
+CREATE TRIGGER policy_changed
+    AFTER INSERT OR UPDATE OR DELETE ON rego_modules
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_policy_changed();
+
+DROP TRIGGER IF EXISTS policy_changed ON network_zones;
+CREATE TRIGGER policy_changed
+    AFTER INSERT OR UPDATE OR DELETE ON network_zones
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_policy_changed();
+
+DROP TRIGGER IF EXISTS policy_changed ON users;
+CREATE TRIGGER policy_changed
+    AFTER UPDATE OF role ON users
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_policy_changed();
+
+DROP TRIGGER IF EXISTS policy_changed ON user_attributes;
+CREATE TRIGGER policy_changed
+    AFTER INSERT OR UPDATE OR DELETE ON user_attributes
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_policy_changed();
+
+DROP TRIGGER IF EXISTS policy_changed ON resource_attributes;
+CREATE TRIGGER policy_changed
+    AFTER INSERT OR UPDATE OR DELETE ON resource_attributes
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_policy_changed();
+
+DROP TRIGGER IF EXISTS policy_changed ON resources;
+CREATE TRIGGER policy_changed
+    AFTER UPDATE OR DELETE ON resources
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_policy_changed();
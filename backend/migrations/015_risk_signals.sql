@@ -0,0 +1,27 @@
+-- Password and MFA checks, for the failed_logins and ip_reputation risk
+-- signals.
+CREATE TABLE IF NOT EXISTS login_attempts (
+    id         BIGSERIAL PRIMARY KEY,
+    email      TEXT NOT NULL DEFAULT '',
+    user_id    BIGINT REFERENCES users(id) ON DELETE CASCADE,
+    ip         TEXT NOT NULL DEFAULT '',
+    stage      TEXT NOT NULL CHECK (stage IN ('password', 'mfa')),
+    succeeded  BOOLEAN NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS login_attempts_user_idx
+    ON login_attempts (user_id, created_at);
+CREATE INDEX IF NOT EXISTS login_attempts_ip_idx
+    ON login_attempts (ip, created_at);
+
+-- Risk score the decision was made with.
+ALTER TABLE access_logs
+    ADD COLUMN IF NOT EXISTS risk_score SMALLINT;
+
+-- The unusual_hour, ip_reputation and deny_burst signals look at recent
+-- decisions per user and per IP.
+CREATE INDEX IF NOT EXISTS access_logs_user_created_at_idx
+    ON access_logs (user_id, created_at);
+CREATE INDEX IF NOT EXISTS access_logs_ip_created_at_idx
+    ON access_logs (ip, created_at);
@@ -0,0 +1,7 @@
+-- Threat-intelligence feed the client IP was listed in, if any.
+ALTER TABLE access_logs
+    ADD COLUMN IF NOT EXISTS threat_feed TEXT;
+
+CREATE INDEX IF NOT EXISTS access_logs_threat_feed_idx
+    ON access_logs (threat_feed, created_at)
+    WHERE threat_feed IS NOT NULL;
@@ -0,0 +1,39 @@
+-- Application roles. A role has every permission of the roles it inherits
+-- (transitively), and users with it count as having those roles too.
+-- Permissions are "<kind>:<name>", e.g. aws_role:ZTPortalReadOnlyLogs.
+CREATE TABLE IF NOT EXISTS roles (
+    name        TEXT PRIMARY KEY CHECK (name ~ '^[a-z][a-z0-9_-]*$'),
+    description TEXT NOT NULL DEFAULT '',
+    inherits    TEXT[] NOT NULL DEFAULT '{}',
+    permissions TEXT[] NOT NULL DEFAULT '{}',
+    builtin     BOOLEAN NOT NULL DEFAULT false,
+    updated_by  BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- admin ⊇ devops ⊇ user. The AWS role admins had before roles were stored
+-- here moves to admin's permission set.
+INSERT INTO roles (name, description, inherits, permissions, builtin) VALUES
+    ('user',   'Regular users',                  '{}',       '{}',                              true),
+    ('devops', 'Operators with AWS access',      '{user}',   '{}',                              true),
+    ('admin',  'Platform administrators',        '{devops}', '{aws_role:ZTPortalReadOnlyLogs}', true)
+ON CONFLICT (name) DO NOTHING;
+
+-- Keep any role already assigned to a user so the foreign key below holds.
+INSERT INTO roles (name)
+SELECT DISTINCT role FROM users
+WHERE role ~ '^[a-z][a-z0-9_-]*$'
+ON CONFLICT (name) DO NOTHING;
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'users_role_fkey') THEN
+        ALTER TABLE users
+            ADD CONSTRAINT users_role_fkey FOREIGN KEY (role) REFERENCES roles(name);
+    END IF;
+END $$;
+
+DROP TRIGGER IF EXISTS policy_changed ON roles;
+CREATE TRIGGER policy_changed
+    AFTER INSERT OR UPDATE OR DELETE ON roles
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_policy_changed();
@@ -0,0 +1,17 @@
+-- Writes and AWS role sessions need the permissions resources:write and
+-- aws:assume (see user-read-only and aws-non-privileged-deny in the
+-- default policies). devops has both, and admin inherits them.
+UPDATE roles
+SET permissions = permissions || ARRAY['resources:write', 'aws:assume']
+WHERE name = 'devops'
+  AND NOT permissions @> ARRAY['resources:write', 'aws:assume'];
+
+-- Roles kept from users.role by 017 could write through default-allow;
+-- they keep that. Roles created by admins must inherit devops or be
+-- granted the permissions.
+UPDATE roles
+SET permissions = permissions || ARRAY['resources:write', 'aws:assume']
+WHERE NOT builtin
+  AND updated_by IS NULL
+  AND inherits = '{}'
+  AND NOT permissions @> ARRAY['resources:write', 'aws:assume'];
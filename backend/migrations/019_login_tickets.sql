@@ -0,0 +1,16 @@
+-- One-time codes that hand a platform session to an app host behind the
+-- proxy or forward auth. Only a hash of the code is stored; redeeming
+-- deletes the row.
+CREATE TABLE IF NOT EXISTS login_tickets (
+    code_hash  TEXT PRIMARY KEY,
+    host       TEXT NOT NULL,
+    user_id    BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    session_id TEXT NOT NULL REFERENCES user_sessions(id) ON DELETE CASCADE,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+-- A redeemed ticket mints a session for the app host's cookie: scoped to
+-- that host and ending with the session the ticket came from.
+ALTER TABLE user_sessions
+    ADD COLUMN IF NOT EXISTS host TEXT,
+    ADD COLUMN IF NOT EXISTS parent_id TEXT REFERENCES user_sessions(id) ON DELETE CASCADE;
@@ -0,0 +1,11 @@
+-- Sessions issued before MFA only serve the enroll/verify calls. They are
+-- short-lived and do not count toward the concurrent session limit.
+--
+-- Existing sessions cannot tell whether they completed MFA. They are
+-- marked as if they had, so nobody is signed out or loses a slot: their
+-- tokens still carry the mfa claim, which is what grants access. Only new
+-- sessions default to pre-MFA.
+ALTER TABLE user_sessions
+    ADD COLUMN IF NOT EXISTS mfa BOOLEAN NOT NULL DEFAULT TRUE;
+ALTER TABLE user_sessions
+    ALTER COLUMN mfa SET DEFAULT FALSE;
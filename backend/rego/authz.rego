@@ -0,0 +1,41 @@
+# Example bundle for POLICY_ENGINE=rego (REGO_BUNDLE_DIR=./rego). It mirrors
+# the built-in default_policies.yaml so the two engines can be compared
+# with POLICY_ENGINE_COMPARE=true.
+#
+# input is the AccessContext (user_id, user_role, user_roles,
+# user_permissions, mfa_enabled, resource_name, resource_type, sensitivity,
+# action, client_ip, user_attributes, resource_attributes, risk_score,
+# risk_signals, threat_intel, time) plus risk: {score, signals}, the risk
+# score and signal names. threat_intel is only present when the client IP
+# is listed.
+package zerotrust.authz
+
+default decision := {
+	"allow": true,
+	"policy": "default-allow",
+	"reason": "policy conditions satisfied",
+}
+
+decision := deny("threat-intel-deny", "the client IP is listed in a threat-intelligence feed") if {
+	input.threat_intel
+} else := deny("high-risk-deny", "the request's risk score is too high") if {
+	input.risk.score > 70
+} else := deny("high-sensitivity-admin-only", "only admins may access high sensitivity resources") if {
+	input.sensitivity == "high"
+	not has_role("admin")
+} else := deny("high-sensitivity-mfa-required", "MFA is required for high sensitivity access") if {
+	input.sensitivity == "high"
+	not input.mfa_enabled
+} else := deny("aws-non-privileged-deny", "regular users cannot assume AWS roles") if {
+	input.resource_type == "aws_role"
+	not has_permission("aws:assume")
+} else := deny("user-read-only", "users are limited to read-only access") if {
+	not has_permission("resources:write")
+	input.action != "read"
+}
+
+deny(name, reason) := {"allow": false, "policy": name, "reason": reason}
+
+has_role(r) if input.user_roles[_] == r
+
+has_permission(p) if input.user_permissions[_] == p
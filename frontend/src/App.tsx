@@ -17,8 +17,10 @@ import {
   type AwsRole,
   fetchAwsRoles,
   createAwsSession,
+  createProxyTicket,
+  isAdmin,
 } from "./lib/api";
-import { fetchUsers, updateUserRole } from "./lib/users";
+import { fetchRoles, fetchUsers, updateUserRole } from "./lib/users";
 import type { User } from "./lib/users";
 
 import { LoginForm } from "./features/auth/LoginForm";
@@ -124,7 +126,7 @@ function AppShell({
             label="Users"
             current={location.pathname.startsWith("/users")}
           />
-        {auth.user?.role === "admin" && (                    // ← ADD
+        {isAdmin(auth.user) && (                             // ← ADD
           <NavItem                                        // ← ADD
             to="/admin/policies"                          // ← ADD
             label="Policies"                              // ← ADD
@@ -194,7 +196,7 @@ function AdminOnly({
   auth: AuthState;
   children: React.ReactNode;
 }) {
-  if (!auth.user || auth.user.role !== "admin") {
+  if (!isAdmin(auth.user)) {
     return (
       <p style={{ fontSize: "0.9rem" }}>
         You must be an admin to view this page.
@@ -385,13 +387,15 @@ function MyResourcesPage({ auth }: { auth: AuthState }) {
 function UsersPage({
   auth,
   users,
+  roles,
   onRoleChange,
 }: {
   auth: AuthState;
   users: User[];
-  onRoleChange: (id: number, role: "user" | "admin") => void;
+  roles: string[];
+  onRoleChange: (id: number, role: string) => void;
 }) {
-  if (!auth.user || auth.user.role !== "admin") {
+  if (!isAdmin(auth.user)) {
     return <p>You must be an admin to manage users.</p>;
   }
 
@@ -420,15 +424,15 @@ function UsersPage({
               <td>
                 <select
                   value={u.role}
-                  onChange={(e) =>
-                    onRoleChange(
-                      u.id,
-                      e.target.value === "admin" ? "admin" : "user",
-                    )
-                  }
+                  onChange={(e) => onRoleChange(u.id, e.target.value)}
                 >
-                  <option value="user">user</option>
-                  <option value="admin">admin</option>
+                  {(roles.includes(u.role) ? roles : [u.role, ...roles]).map(
+                    (name) => (
+                      <option key={name} value={name}>
+                        {name}
+                      </option>
+                    ),
+                  )}
                 </select>
               </td>
             </tr>
@@ -636,6 +640,7 @@ function SystemStatusPage({ health }: { health: Health | null }) {
 const App: React.FC = () => {
   const [health, setHealth] = useState<Health | null>(null);
   const [users, setUsers] = useState<User[]>([]);
+  const [roles, setRoles] = useState<string[]>([]);
   const [auth, setAuth] = useState<AuthState>({ token: null, user: null });
   const [error, setError] = useState<string | null>(null);
 
@@ -668,16 +673,33 @@ const App: React.FC = () => {
     }
   }, []);
 
+  // Sent here by the identity-aware proxy: once signed in, hand the
+  // session to the app and go back to it.
+  useEffect(() => {
+    const redirect = new URLSearchParams(window.location.search).get(
+      "redirect",
+    );
+    if (!auth.token || !redirect) return;
+
+    createProxyTicket(auth.token, redirect)
+      .then(({ url }) => window.location.assign(url))
+      .catch((err) => setError(err.message));
+  }, [auth.token]);
+
   /* 🔐 FIXED: admin-only users fetch */
   useEffect(() => {
-    if (!auth.token || auth.user?.role !== "admin") {
+    if (!auth.token || !isAdmin(auth.user)) {
       setUsers([]);
+      setRoles([]);
       return;
     }
 
     fetchUsers(auth.token)
       .then(setUsers)
       .catch((err) => setError(err.message));
+    fetchRoles(auth.token)
+      .then((list) => setRoles(list.map((r) => r.name)))
+      .catch((err) => setError(err.message));
   }, [auth.token, auth.user]);
 
   const handleFinalAuth = (res: AuthResponse) => {
@@ -742,7 +764,7 @@ const App: React.FC = () => {
     setEnrollData(null);
   };
 
-  const handleUserRoleChange = async (id: number, role: "user" | "admin") => {
+  const handleUserRoleChange = async (id: number, role: string) => {
     if (!auth.token) return;
     try {
       const updated = await updateUserRole(auth.token, id, role);
@@ -954,6 +976,7 @@ const App: React.FC = () => {
             <UsersPage
               auth={auth}
               users={users}
+              roles={roles}
               onRoleChange={handleUserRoleChange}
             />
           }
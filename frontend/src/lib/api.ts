@@ -11,8 +11,17 @@ export type AuthUser = {
   email: string;
   full_name: string;
   role: string;
+  // Effective roles: role plus every role it inherits.
+  roles?: string[];
 };
 
+// Admin UI is for any role that is or inherits admin. Users saved before
+// logins returned effective roles only have role.
+export function isAdmin(user: AuthUser | null | undefined): boolean {
+  if (!user) return false;
+  return (user.roles ?? [user.role]).includes("admin");
+}
+
 export type AuthResponse = {
   token?: string;
   user?: AuthUser;
@@ -74,6 +83,12 @@ export async function createAwsSession(
       Authorization: `Bearer ${token}`,
     },
   });
+  if (res.status === 403) {
+    const body = await res.json().catch(() => null);
+    throw new Error(
+      body?.reason ? `Access denied: ${body.reason}` : "Access denied",
+    );
+  }
   if (!res.ok) {
     throw new Error(`Failed to create AWS session: ${res.status}`);
   }
@@ -150,3 +165,22 @@ export async function signup(
   }
   return res.json();
 }
+
+// ---------- Identity-aware proxy login hand-off ----------
+export async function createProxyTicket(
+  token: string,
+  redirect: string,
+): Promise<{ url: string }> {
+  const res = await fetch(`${API_BASE_URL}/auth/proxy-ticket`, {
+    method: "POST",
+    headers: {
+      Authorization: `Bearer ${token}`,
+      "Content-Type": "application/json",
+    },
+    body: JSON.stringify({ redirect }),
+  });
+  if (!res.ok) {
+    throw new Error(`Failed to return to application: ${res.status}`);
+  }
+  return res.json();
+}
@@ -21,10 +21,32 @@ export async function fetchUsers(token: string): Promise<User[]> {
   return res.json();
 }
 
+export type Role = {
+  name: string;
+  description: string;
+  inherits: string[];
+  permissions: string[];
+  builtin: boolean;
+  effective_roles: string[];
+  effective_permissions: string[];
+};
+
+export async function fetchRoles(token: string): Promise<Role[]> {
+  const res = await fetch(`${API_BASE_URL}/admin/roles`, {
+    headers: {
+      Authorization: `Bearer ${token}`,
+    },
+  });
+  if (!res.ok) {
+    throw new Error(`Failed to load roles: ${res.status}`);
+  }
+  return res.json();
+}
+
 export async function updateUserRole(
   token: string,
   userId: number,
-  role: "user" | "admin",
+  role: string,
 ): Promise<User> {
   const res = await fetch(`${API_BASE_URL}/users/${userId}/role`, {
     method: "PATCH",
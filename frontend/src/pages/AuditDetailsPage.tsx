@@ -1,4 +1,5 @@
 import { useEffect, useState } from "react";
+import { isAdmin } from "../lib/api";
 
 type AuditActivity = {
   id: number;
@@ -27,7 +28,7 @@ export function AuditDetailPage({ auth }: AuditDetailProps) {
   useEffect(() => {
     if (!auth.token || !auth.user) return;
 
-    if (auth.user.role !== "admin") {
+    if (!isAdmin(auth.user)) {
       setError("You must be an admin to view the audit trail.");
       return;
     }
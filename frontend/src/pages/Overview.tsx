@@ -1,7 +1,12 @@
 // frontend/src/pages/Overview.tsx
 import { useEffect, useState } from "react";
 import { Link } from "react-router-dom";
-import { fetchAuditStats, type AuditStat, type Health } from "../lib/api";
+import {
+  fetchAuditStats,
+  isAdmin,
+  type AuditStat,
+  type Health,
+} from "../lib/api";
 import type { User } from "../lib/users";
 
 type AuthState = {
@@ -41,7 +46,7 @@ export function OverviewPage({ health, users, error, auth }: OverviewProps) {
   const safeUsers: User[] = Array.isArray(users) ? users : [];
   const safeActivity: ActivityItem[] = Array.isArray(activity) ? activity : [];
 
-  const isAdmin = auth.user?.role === "admin";
+  const showAdmin = isAdmin(auth.user);
 
   // Load recent activity
   useEffect(() => {
@@ -74,7 +79,7 @@ export function OverviewPage({ health, users, error, auth }: OverviewProps) {
 
   // Load audit stats for chart
   useEffect(() => {
-    if (!auth.token || !isAdmin) {
+    if (!auth.token || !showAdmin) {
       setStatsLoading(false);
       return;
     }
@@ -93,7 +98,7 @@ export function OverviewPage({ health, users, error, auth }: OverviewProps) {
     }
 
     loadStats();
-  }, [auth.token, isAdmin]);
+  }, [auth.token, showAdmin]);
 
   // Small bar chart for allow/deny
   const AuditChart = () => {
@@ -221,7 +226,7 @@ export function OverviewPage({ health, users, error, auth }: OverviewProps) {
 
   return (
     <div style={{ display: "flex", flexDirection: "column", gap: "1.5rem" }}>
-      {isAdmin && (
+      {showAdmin && (
         <section>
           <h2 style={{ fontSize: "1.2rem", marginBottom: "0.5rem" }}>
             Overview
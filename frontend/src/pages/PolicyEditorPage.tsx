@@ -1,7 +1,7 @@
 // frontend/src/pages/PolicyEditorPage.tsx
 import { useEffect, useState } from "react";
 
-import { fetchAwsRolePolicies, type AwsRole } from "../lib/api";
+import { fetchAwsRolePolicies, isAdmin, type AwsRole } from "../lib/api";
 
 type AuthState = {
   token: string | null;
@@ -59,7 +59,7 @@ export function PolicyEditorPage({ auth }: PolicyEditorPageProps) {
     loadPolicies();
   }, [auth.token]);
 
-  if (!auth.user || auth.user.role !== "admin") {
+  if (!isAdmin(auth.user)) {
     return <p style={{ fontSize: "0.9rem" }}>Admin access only.</p>;
   }
 